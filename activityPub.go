@@ -291,7 +291,12 @@ func (a *goBlog) apOnCreateUpdate(blog *configBlog, requestActor *ap.Actor, acti
 		visible = false
 	}
 	if inReplyTo := object.InReplyTo; inReplyTo != nil {
-		if replyTarget := inReplyTo.GetLink().String(); replyTarget != "" && strings.HasPrefix(replyTarget, a.cfg.Server.PublicAddress) {
+		replyTarget := inReplyTo.GetLink().String()
+		if exists, id, err := a.db.commentIdByOriginal(replyTarget); replyTarget != "" && err == nil && exists {
+			// It's a reply to another reply, thread it below the comment
+			replyTarget = a.getFullAddress(blog.getRelativePath(fmt.Sprintf("%s/%d", commentPath, id)))
+		}
+		if replyTarget != "" && strings.HasPrefix(replyTarget, a.cfg.Server.PublicAddress) {
 			// It's a reply
 			original := object.GetLink().String()
 			name := requestActor.Name.First().Value.String()
@@ -302,9 +307,9 @@ func (a *goBlog) apOnCreateUpdate(blog *configBlog, requestActor *ap.Actor, acti
 			if actorUrl := requestActor.URL.GetLink(); actorUrl != "" {
 				website = actorUrl.String()
 			}
-			content := object.Content.First().Value.String()
+			content := cleanHTMLText(object.Content.First().Value.String())
 			if visible {
-				_, _, _ = a.createComment(blog, replyTarget, content, name, website, original)
+				_, _, _ = a.createComment(blog, replyTarget, content, name, website, "", original)
 				return
 			} else {
 				buf := bufferpool.Get()
@@ -573,6 +578,8 @@ func apRequestIsSuccess(code int) bool {
 	return code == http.StatusOK || code == http.StatusCreated || code == http.StatusAccepted || code == http.StatusNoContent
 }
 
+const activityPubKeyCacheKey = "activitypub_key"
+
 // Load or generate key for ActivityPub communication
 func (a *goBlog) loadActivityPubPrivateKey() error {
 	// Check if already loaded
@@ -580,7 +587,7 @@ func (a *goBlog) loadActivityPubPrivateKey() error {
 		return nil
 	}
 	// Check if already generated
-	if keyData, err := a.db.retrievePersistentCache("activitypub_key"); err == nil && keyData != nil {
+	if keyData, err := a.db.retrievePersistentCache(activityPubKeyCacheKey); err == nil && keyData != nil {
 		privateKeyDecoded, _ := pem.Decode(keyData)
 		if privateKeyDecoded == nil {
 			log.Println("failed to decode cached private key")
@@ -611,7 +618,7 @@ func (a *goBlog) loadActivityPubPrivateKey() error {
 	a.apPrivateKey = key
 	a.apPubKeyBytes = pubKeyBytes
 	return a.db.cachePersistently(
-		"activitypub_key",
+		activityPubKeyCacheKey,
 		pem.EncodeToMemory(&pem.Block{
 			Type:  "PRIVATE KEY",
 			Bytes: x509.MarshalPKCS1PrivateKey(a.apPrivateKey),
@@ -4,6 +4,7 @@ import (
 	"crypto/rsa"
 	"net/http"
 	"sync"
+	"time"
 
 	shutdowner "git.jlel.se/jlelse/go-shutdowner"
 	ts "git.jlel.se/jlelse/template-strings"
@@ -43,6 +44,8 @@ type goBlog struct {
 	blogStatsCacheGroup singleflight.Group
 	// Cache
 	cache *cache
+	// Comments
+	commentEditSecretMutex sync.Mutex
 	// Config
 	cfg *config
 	// Database
@@ -69,7 +72,7 @@ type goBlog struct {
 	// Logs
 	logf *rotatelogs.RotateLogs
 	// Markdown
-	md, absoluteMd, titleMd goldmark.Markdown
+	md, absoluteMd, titleMd, commentMd goldmark.Markdown
 	// Media
 	compressorsInit  sync.Once
 	compressors      []mediaCompression
@@ -100,6 +103,9 @@ type goBlog struct {
 	// Tor
 	torAddress  string
 	torHostname string
+	// WebSub
+	webSubHostRequests map[string][]time.Time
+	webSubMutex        sync.Mutex
 	// GetBlogURL
 	getBlogURL string
 	// Syndication Targets
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/samber/lo"
+)
+
+const (
+	// Scope to allow access to everything that requires a login (editor, settings etc.)
+	appPasswordScopeAdmin = "admin"
+
+	appPasswordLength = 32
+	// Only update the last used time every minute
+	appPasswordTouchInterval = time.Minute
+)
+
+// Micropub scopes and the admin scope
+var appPasswordScopes = []string{"create", "update", "delete", "undelete", "media", appPasswordScopeAdmin}
+
+type appPassword struct {
+	ID       int
+	Username string
+	Scopes   []string
+	Created  time.Time
+	LastUsed time.Time
+}
+
+func hashAppPassword(password string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(password)))
+}
+
+// Check if app passwords are correct and return the granted scopes
+func (a *goBlog) checkAppPasswords(username, password string) ([]string, bool) {
+	// Passwords from the config have all scopes
+	for _, apw := range a.cfg.User.AppPasswords {
+		if apw.Username == username && apw.Password == password {
+			return appPasswordScopes, true
+		}
+	}
+	// Passwords from the database
+	apw, err := a.db.getAppPasswordByHash(hashAppPassword(password))
+	if err != nil || subtle.ConstantTimeCompare([]byte(apw.Username), []byte(username)) != 1 {
+		return nil, false
+	}
+	if time.Since(apw.LastUsed) > appPasswordTouchInterval {
+		if err := a.db.touchAppPassword(apw.ID); err != nil {
+			log.Println("Failed to update app password:", err.Error())
+		}
+	}
+	return apw.Scopes, true
+}
+
+// Create a new app password, returns the password in plain text
+func (db *database) createAppPassword(username string, scopes []string) (string, error) {
+	if username == "" {
+		return "", errors.New("username is required")
+	}
+	scopes = lo.Intersect(appPasswordScopes, scopes)
+	if len(scopes) == 0 {
+		return "", errors.New("at least one scope is required")
+	}
+	password, err := secureRandomString(appPasswordLength, []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")...)
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(
+		"insert into apppasswords (username, hash, scopes, created) values (@username, @hash, @scopes, @created)",
+		sql.Named("username", username),
+		sql.Named("hash", hashAppPassword(password)),
+		sql.Named("scopes", strings.Join(scopes, " ")),
+		sql.Named("created", utcNowString()),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return "", errors.New("app password with this username already exists")
+		}
+		return "", err
+	}
+	return password, nil
+}
+
+func (db *database) deleteAppPassword(id int) error {
+	_, err := db.Exec("delete from apppasswords where id = @id", sql.Named("id", id))
+	return err
+}
+
+func (db *database) touchAppPassword(id int) error {
+	_, err := db.Exec("update apppasswords set lastused = @now where id = @id", sql.Named("now", utcNowString()), sql.Named("id", id))
+	return err
+}
+
+const appPasswordsQuery = "select id, username, scopes, created, lastused from apppasswords"
+
+func scanAppPassword(scanner interface{ Scan(...any) error }) (*appPassword, error) {
+	apw := &appPassword{}
+	var scopes, created, lastUsed string
+	if err := scanner.Scan(&apw.ID, &apw.Username, &scopes, &created, &lastUsed); err != nil {
+		return nil, err
+	}
+	apw.Scopes = strings.Fields(scopes)
+	apw.Created = noError(dateparse.ParseIn(created, time.UTC))
+	if lastUsed != "" {
+		apw.LastUsed = noError(dateparse.ParseIn(lastUsed, time.UTC))
+	}
+	return apw, nil
+}
+
+func (db *database) getAppPasswordByHash(hash string) (*appPassword, error) {
+	row, err := db.QueryRow(appPasswordsQuery+" where hash = @hash", sql.Named("hash", hash))
+	if err != nil {
+		return nil, err
+	}
+	return scanAppPassword(row)
+}
+
+func (db *database) getAppPasswords() ([]*appPassword, error) {
+	rows, err := db.Query(appPasswordsQuery + " order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	apws := []*appPassword{}
+	for rows.Next() {
+		apw, err := scanAppPassword(rows)
+		if err != nil {
+			return nil, err
+		}
+		apws = append(apws, apw)
+	}
+	return apws, rows.Err()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goblog.app/app/pkgs/contenttype"
+)
+
+func Test_appPasswords(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.User = &configUser{
+		Nick:     "test",
+		Password: "pass",
+		AppPasswords: []*configAppPassword{
+			{
+				Username: "app1",
+				Password: "pass1",
+			},
+		},
+	}
+
+	_ = app.initConfig(false)
+	app.initMarkdown()
+	app.initSessions()
+	_ = app.initTemplateStrings()
+
+	// Create
+	_, err := app.db.createAppPassword("uploader", []string{"invalid"})
+	assert.Error(t, err)
+
+	password, err := app.db.createAppPassword("uploader", []string{"create", "media", "invalid"})
+	require.NoError(t, err)
+	assert.Len(t, password, appPasswordLength)
+
+	_, err = app.db.createAppPassword("uploader", []string{"create"})
+	assert.Error(t, err)
+
+	apws, err := app.db.getAppPasswords()
+	require.NoError(t, err)
+	require.Len(t, apws, 1)
+	assert.Equal(t, "uploader", apws[0].Username)
+	assert.Equal(t, []string{"create", "media"}, apws[0].Scopes)
+	assert.True(t, apws[0].LastUsed.IsZero())
+
+	// Check
+	scopes, ok := app.checkAppPasswords("uploader", password)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"create", "media"}, scopes)
+
+	_, ok = app.checkAppPasswords("other", password)
+	assert.False(t, ok)
+
+	_, ok = app.checkAppPasswords("uploader", "wrong")
+	assert.False(t, ok)
+
+	scopes, ok = app.checkAppPasswords("app1", "pass1")
+	assert.True(t, ok)
+	assert.Equal(t, appPasswordScopes, scopes)
+
+	apws, err = app.db.getAppPasswords()
+	require.NoError(t, err)
+	assert.False(t, apws[0].LastUsed.IsZero())
+
+	// Scoped password can't log in
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("uploader", password)
+	assert.False(t, app.isLoggedIn(req))
+
+	// But can use Micropub with the scopes
+	var gotScope string
+	h := app.checkIndieAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotScope = r.Context().Value(indieAuthScope).(string)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "create media", gotScope)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("uploader", "wrong")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Scopes are compared as whole words
+	require.NoError(t, app.createPost(&post{Path: "/test/delete", Content: "Test"}))
+	undeletePassword, err := app.db.createAppPassword("undeleter", []string{"undelete"})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, micropubPath, strings.NewReader(url.Values{"action": {"delete"}, "url": {app.cfg.Server.PublicAddress + "/test/delete"}}.Encode()))
+	req.Header.Set(contentType, contenttype.WWWForm)
+	req.SetBasicAuth("undeleter", undeletePassword)
+	rec = httptest.NewRecorder()
+	app.buildRouter().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	p, err := app.getPost("/test/delete")
+	require.NoError(t, err)
+	assert.Equal(t, statusPublished, p.Status)
+
+	// Delete
+	require.NoError(t, app.db.deleteAppPassword(apws[0].ID))
+	_, ok = app.checkAppPasswords("uploader", password)
+	assert.False(t, ok)
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/pquerna/otp/totp"
+	"github.com/samber/lo"
 	"go.goblog.app/app/pkgs/bodylimit"
 	"go.goblog.app/app/pkgs/bufferpool"
 	"go.goblog.app/app/pkgs/contenttype"
@@ -23,14 +24,12 @@ func (a *goBlog) checkCredentials(username, password, totpPasscode string) bool
 		(a.cfg.User.TOTP == "" || totp.Validate(totpPasscode, a.cfg.User.TOTP))
 }
 
-// Check if app passwords are correct
-func (a *goBlog) checkAppPasswords(username, password string) bool {
-	for _, apw := range a.cfg.User.AppPasswords {
-		if apw.Username == username && apw.Password == password {
-			return true
-		}
-	}
-	return false
+// Check if the TOTP passcode is a valid recovery code, the code gets invalidated
+func (a *goBlog) checkTOTPRecoveryCode(username, password, recoveryCode string) bool {
+	return a.cfg.User.TOTP != "" &&
+		username == a.cfg.User.Nick &&
+		password == a.cfg.User.Password &&
+		a.db.useTOTPRecoveryCode(recoveryCode)
 }
 
 // Check if cookie is known and logged in
@@ -38,6 +37,7 @@ func (a *goBlog) checkLoginCookie(r *http.Request) bool {
 	ses, err := a.loginSessions.Get(r, "l")
 	if err == nil && ses != nil {
 		if login, ok := ses.Values["login"]; ok && login.(bool) {
+			a.loginSessions.touch(ses)
 			return true
 		}
 	}
@@ -109,11 +109,27 @@ func (a *goBlog) checkLogin(w http.ResponseWriter, r *http.Request) bool {
 	if r.FormValue("loginaction") != "login" {
 		return false
 	}
-	// Check credential
-	if !a.checkCredentials(r.FormValue("username"), r.FormValue("password"), r.FormValue("token")) {
-		a.serveError(w, r, "Incorrect credentials", http.StatusUnauthorized)
+	username := r.FormValue("username")
+	// Check if there were too many failed attempts
+	locked, err := a.db.isLoginLocked(a.remoteIP(r), username)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+	if locked {
+		a.logLoginEvent(r, loginEventLocked, username)
+		a.serveError(w, r, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
 		return true
 	}
+	// Check credential
+	if !a.checkCredentials(username, r.FormValue("password"), r.FormValue("token")) {
+		if !a.checkTOTPRecoveryCode(username, r.FormValue("password"), r.FormValue("token")) {
+			a.logLoginEvent(r, loginEventFailure, username)
+			a.serveError(w, r, "Incorrect credentials", http.StatusUnauthorized)
+			return true
+		}
+		a.logLoginEvent(r, loginEventRecoveryCode, username)
+	}
 	// Prepare original request
 	bodyDecoder := base64.NewDecoder(base64.StdEncoding, strings.NewReader(r.FormValue("loginbody")))
 	origReq, _ := http.NewRequestWithContext(r.Context(), r.FormValue("loginmethod"), r.URL.RequestURI(), bodyDecoder)
@@ -126,11 +142,13 @@ func (a *goBlog) checkLogin(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	}
 	ses.Values["login"] = true
+	ses.Values[sessionUserAgent] = r.UserAgent()
 	err = a.loginSessions.Save(r, w, ses)
 	if err != nil {
 		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
 		return true
 	}
+	a.logLoginEvent(r, loginEventSuccess, username)
 	// Serve original request
 	setLoggedIn(origReq, true)
 	a.d.ServeHTTP(w, origReq)
@@ -143,15 +161,22 @@ func (a *goBlog) isLoggedIn(r *http.Request) bool {
 		return loggedIn
 	}
 	// Check app passwords
-	if username, password, ok := r.BasicAuth(); ok && a.checkAppPasswords(username, password) {
-		setLoggedIn(r, true)
-		return true
+	username, password, basicAuth := r.BasicAuth()
+	if basicAuth {
+		if scopes, err := a.checkBasicAuth(r, username, password); err == nil && lo.Contains(scopes, appPasswordScopeAdmin) {
+			setLoggedIn(r, true)
+			return true
+		}
 	}
 	// Check session cookie
 	if a.checkLoginCookie(r) {
 		setLoggedIn(r, true)
 		return true
 	}
+	if basicAuth {
+		// Remember the result, so failed attempts are only checked and logged once per request
+		setLoggedIn(r, false)
+	}
 	// Not logged in
 	return false
 }
@@ -171,6 +196,9 @@ func serveLogin(w http.ResponseWriter, r *http.Request) {
 // HandlerFunc to delete login session and cookie
 func (a *goBlog) serveLogout(w http.ResponseWriter, r *http.Request) {
 	if ses, err := a.loginSessions.Get(r, "l"); err == nil && ses != nil {
+		if login, ok := ses.Values["login"].(bool); ok && login {
+			a.logLoginEvent(r, loginEventLogout, a.cfg.User.Nick)
+		}
 		_ = a.loginSessions.Delete(r, w, ses)
 	}
 	http.Redirect(w, r, "/", http.StatusFound)
@@ -184,3 +184,237 @@ func Test_setLoggedIn(t *testing.T) {
 	assert.True(t, ok)
 	assert.False(t, loggedIn)
 }
+
+func Test_loginThrottling(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.User = &configUser{
+		Nick:     "test",
+		Password: "pass",
+	}
+
+	_ = app.initConfig(false)
+	app.initMarkdown()
+	app.initSessions()
+	_ = app.initTemplateStrings()
+
+	app.d = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte("ABC Test"))
+	})
+
+	h := alice.New(app.checkIsLogin, app.authMiddleware).Then(app.d)
+
+	login := func(ip, password string) int {
+		data := url.Values{}
+		data.Add("loginaction", "login")
+		data.Add("loginmethod", "GET")
+		data.Add("username", "test")
+		data.Add("password", password)
+
+		req := httptest.NewRequest(http.MethodPost, "/abc", strings.NewReader(data.Encode()))
+		req.Header.Add(contentType, contenttype.WWWForm)
+		req.RemoteAddr = ip + ":1234"
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < loginMaxFailuresPerIP; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login("192.0.2.1", "wrong"))
+	}
+
+	// Locked, even with the correct password
+	assert.Equal(t, http.StatusTooManyRequests, login("192.0.2.1", "pass"))
+
+	// Other IP still works
+	assert.Equal(t, http.StatusOK, login("192.0.2.2", "pass"))
+
+	// Events are logged
+	events, err := app.db.getLoginEvents(100)
+	require.NoError(t, err)
+	require.Len(t, events, loginMaxFailuresPerIP+2)
+	assert.Equal(t, loginEventSuccess, events[0].Event)
+	assert.Equal(t, "192.0.2.2", events[0].IP)
+	assert.Equal(t, loginEventLocked, events[1].Event)
+	assert.Equal(t, loginEventFailure, events[2].Event)
+	assert.Equal(t, "test", events[2].Username)
+}
+
+func Test_basicAuthThrottling(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.User = &configUser{
+		Nick:         "test",
+		Password:     "pass",
+		AppPasswords: []*configAppPassword{{Username: "app", Password: "secret"}},
+	}
+
+	_ = app.initConfig(false)
+	app.initMarkdown()
+	app.initSessions()
+	_ = app.initTemplateStrings()
+
+	h := app.checkIndieAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte("ABC Test"))
+	}))
+
+	request := func(ip, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/micropub", nil)
+		req.SetBasicAuth("app", password)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < loginMaxFailuresPerIP; i++ {
+		assert.Equal(t, http.StatusUnauthorized, request("192.0.2.1", "wrong"))
+	}
+
+	// Locked, even with the correct password
+	assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.1", "secret"))
+
+	// Also when checking the login
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.SetBasicAuth("app", "secret")
+	req.RemoteAddr = "192.0.2.1:1234"
+	assert.False(t, app.isLoggedIn(req))
+
+	// Other IP still works
+	assert.Equal(t, http.StatusOK, request("192.0.2.2", "secret"))
+
+	events, err := app.db.getLoginEvents(100)
+	require.NoError(t, err)
+	require.Len(t, events, loginMaxFailuresPerIP+2)
+	assert.Equal(t, loginEventLocked, events[0].Event)
+	assert.Equal(t, loginEventBasicAuthFailed, events[2].Event)
+	assert.Equal(t, "app", events[2].Username)
+}
+
+func Test_remoteIP(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Add("X-Forwarded-For", "198.51.100.1, 192.0.2.1")
+
+	// Proxy headers are ignored by default
+	assert.Equal(t, "10.0.0.1", app.remoteIP(req))
+
+	// The address added by the trusted proxy is used
+	app.cfg.Server.TrustProxy = true
+	assert.Equal(t, "192.0.2.1", app.remoteIP(req))
+
+	req.Header.Del("X-Forwarded-For")
+	req.Header.Set("X-Real-IP", "192.0.2.2")
+	assert.Equal(t, "192.0.2.2", app.remoteIP(req))
+}
+
+func Test_totpRecoveryCodes(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.User = &configUser{
+		Nick:     "test",
+		Password: "pass",
+		TOTP:     "JBSWY3DPEHPK3PXP",
+	}
+
+	_ = app.initConfig(false)
+
+	codes, err := app.db.generateTOTPRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, totpRecoveryCodesCount)
+	assert.Regexp(t, `^\d{5}-\d{5}-\d{5}$`, codes[0])
+
+	count, err := app.db.countTOTPRecoveryCodes()
+	require.NoError(t, err)
+	assert.Equal(t, totpRecoveryCodesCount, count)
+
+	assert.False(t, app.checkTOTPRecoveryCode("test", "wrong", codes[0]))
+	assert.False(t, app.checkTOTPRecoveryCode("test", "pass", "123456"))
+	// Code can be entered without separators, but only once
+	assert.True(t, app.checkTOTPRecoveryCode("test", "pass", strings.ReplaceAll(codes[0], "-", "")))
+	assert.False(t, app.checkTOTPRecoveryCode("test", "pass", codes[0]))
+
+	count, err = app.db.countTOTPRecoveryCodes()
+	require.NoError(t, err)
+	assert.Equal(t, totpRecoveryCodesCount-1, count)
+
+	// Generating new codes invalidates the old ones
+	_, err = app.db.generateTOTPRecoveryCodes()
+	require.NoError(t, err)
+	assert.False(t, app.checkTOTPRecoveryCode("test", "pass", codes[1]))
+}
+
+func Test_loginSessions(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.User = &configUser{
+		Nick:     "test",
+		Password: "pass",
+	}
+
+	_ = app.initConfig(false)
+	app.initMarkdown()
+	app.initSessions()
+	_ = app.initTemplateStrings()
+
+	app.d = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte("ABC Test"))
+		if app.isLoggedIn(r) {
+			_, _ = rw.Write([]byte("Logged in"))
+		}
+	})
+
+	h := alice.New(app.checkIsLogin, app.authMiddleware).Then(app.d)
+
+	login := func(ua string) *http.Cookie {
+		data := url.Values{}
+		data.Add("loginaction", "login")
+		data.Add("loginmethod", "GET")
+		data.Add("username", "test")
+		data.Add("password", "pass")
+
+		req := httptest.NewRequest(http.MethodPost, "/abc", strings.NewReader(data.Encode()))
+		req.Header.Add(contentType, contenttype.WWWForm)
+		req.Header.Set(userAgent, ua)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		res := rec.Result()
+		_ = res.Body.Close()
+		require.Len(t, res.Cookies(), 1)
+		return res.Cookies()[0]
+	}
+
+	cookie1 := login("Browser 1")
+	_ = login("Browser 2")
+
+	sessions, err := app.loginSessions.getAll("l", "login")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.ElementsMatch(t, []string{"Browser 1", "Browser 2"}, []string{sessions[0].UserAgent, sessions[1].UserAgent})
+
+	// Revoke first session
+	require.NoError(t, app.loginSessions.deleteByID("l", cookie1.Value))
+	assert.Error(t, app.loginSessions.deleteByID("l", "c-invalid"))
+
+	sessions, err = app.loginSessions.getAll("l", "login")
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Browser 2", sessions[0].UserAgent)
+
+	// Revoked session isn't logged in anymore
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.AddCookie(cookie1)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.NotContains(t, rec.Body.String(), "Logged in")
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/ristretto"
@@ -79,7 +80,7 @@ func (a *goBlog) cacheMiddleware(next http.Handler) http.Handler {
 		// copy and set headers
 		a.setCacheHeaders(w, ci)
 		// check conditional request
-		if ifNoneMatchHeader := r.Header.Get("If-None-Match"); ifNoneMatchHeader != "" && ifNoneMatchHeader == ci.eTag {
+		if notModified(r, ci.eTag) {
 			// send 304
 			w.WriteHeader(http.StatusNotModified)
 			return
@@ -156,6 +157,23 @@ type cacheItem struct {
 	body       []byte
 }
 
+// Check the If-None-Match header of a conditional request, responses only use content-hash ETags
+// because the content can change without a reliable modification time (e.g. when a post is deleted)
+func notModified(r *http.Request, eTag string) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || eTag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		// Weak comparison, ignore the weak prefix and quotes
+		tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+		if tag == "*" || tag == strings.Trim(strings.TrimPrefix(eTag, "W/"), `"`) {
+			return true
+		}
+	}
+	return false
+}
+
 // Calculate byte size of cache item using size of header, body and etag
 func (ci *cacheItem) cost() int {
 	headerBuf := bufferpool.Get()
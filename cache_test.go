@@ -67,3 +67,17 @@ func Benchmark_cache_getCache(b *testing.B) {
 		c.getCache(strconv.Itoa(i), handler, req)
 	}
 }
+
+func Test_notModified(t *testing.T) {
+	check := func(header, value string) bool {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(header, value)
+		return notModified(req, "abc")
+	}
+	assert.True(t, check("If-None-Match", "abc"))
+	assert.True(t, check("If-None-Match", `"def", W/"abc"`))
+	assert.True(t, check("If-None-Match", "*"))
+	assert.False(t, check("If-None-Match", `"def"`))
+	assert.False(t, check("If-Modified-Since", "Wed, 01 Jan 2030 00:00:00 GMT"))
+	assert.False(t, notModified(httptest.NewRequest(http.MethodGet, "/", nil), "abc"))
+}
@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"html"
+	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"path"
@@ -11,10 +14,15 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/samber/lo"
 	"go.goblog.app/app/pkgs/builderpool"
+	"go.goblog.app/app/pkgs/contenttype"
 )
 
-const commentPath = "/comment"
+const (
+	commentPath           = "/comment"
+	commentPreviewSubPath = "/preview"
+)
 
 type comment struct {
 	ID       int
@@ -23,6 +31,12 @@ type comment struct {
 	Website  string
 	Comment  string
 	Original string
+	Parent   int
+	Status   commentStatus
+	Email    string // Private, never rendered
+	Created  string
+	// Comments created before Markdown support are plain text
+	PlainText bool
 }
 
 func (a *goBlog) serveComment(w http.ResponseWriter, r *http.Request) {
@@ -41,6 +55,11 @@ func (a *goBlog) serveComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	comment := comments[0]
+	if comment.Status != commentStatusApproved && !a.isLoggedIn(r) {
+		// Only show comments awaiting moderation to logged in users
+		a.serve404(w, r)
+		return
+	}
 	_, bc := a.getBlog(r)
 	canonical := a.getFullAddress(bc.getRelativePath(path.Join(commentPath, strconv.Itoa(id))))
 	a.render(w, r, a.renderComment, &renderData{
@@ -55,30 +74,92 @@ func (a *goBlog) createCommentFromRequest(w http.ResponseWriter, r *http.Request
 	name := r.FormValue("name")
 	website := r.FormValue("website")
 	_, bc := a.getBlog(r)
+	email := ""
+	if bc.commentEmailsEnabled() {
+		email = r.FormValue("email")
+	}
 	// Create comment
-	result, errStatus, err := a.createComment(bc, target, comment, name, website, "")
+	result, status, err := a.createComment(bc, target, comment, name, website, email, "")
 	if err != nil {
-		a.serveError(w, r, err.Error(), errStatus)
+		a.serveError(w, r, err.Error(), status)
+		return
+	}
+	// Subscribe to new comments
+	if r.FormValue("subscribe") == "on" {
+		if email, _ = cleanCommentEmail(email); email != "" {
+			go func() {
+				if err := a.subscribeToComments(bc, target, email); err != nil {
+					log.Println("Failed to subscribe to comments:", err.Error())
+				}
+			}()
+		}
+	}
+	// Allow the commenter to edit or delete the comment for a while
+	editPath := ""
+	if id, err := strconv.Atoi(path.Base(result)); err == nil {
+		if token := a.issueCommentEditToken(w, bc, id); token != "" {
+			editPath = bc.commentAuthorEditPath(id, token)
+		}
+	}
+	if status == http.StatusAccepted || editPath != "" {
+		// Comment is awaiting moderation or the edit link has to be shown
+		a.renderWithStatusCode(w, r, lo.If(status == http.StatusAccepted, http.StatusAccepted).Else(http.StatusCreated), a.renderCommentCreated, &renderData{
+			Data: &commentCreatedRenderData{
+				target:   target,
+				address:  result,
+				pending:  status == http.StatusAccepted,
+				editPath: editPath,
+			},
+		})
 		return
 	}
 	// Redirect to comment
 	http.Redirect(w, r, result, http.StatusFound)
 }
 
-func (a *goBlog) createComment(bc *configBlog, target, comment, name, website, original string) (string, int, error) {
+// Render the Markdown of the comment form for the live preview
+func (a *goBlog) serveCommentPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(contentType, contenttype.HTMLUTF8)
+	_, _ = io.WriteString(w, a.renderCommentMarkdown(cleanCommentMarkdown(r.FormValue("comment"))))
+}
+
+// Render the content of a comment as safe HTML, old plain text comments keep their formatting
+func (a *goBlog) renderCommentContent(c *comment) string {
+	if !c.PlainText {
+		return a.renderCommentMarkdown(c.Comment)
+	}
+	return "<p>" + strings.ReplaceAll(html.EscapeString(c.Comment), "\n", "<br>") + "</p>"
+}
+
+// Comments are saved as Markdown, raw HTML isn't rendered
+func cleanCommentMarkdown(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+// Returns the path of the comment, the status is http.StatusAccepted if the comment is held for moderation
+func (a *goBlog) createComment(bc *configBlog, target, comment, name, website, email, original string) (string, int, error) {
 	updateId := -1
 	// Check target
 	target, status, err := a.checkCommentTarget(target)
 	if err != nil {
 		return "", status, err
 	}
+	// Check if it's a reply to another comment
+	parent, target, status, err := a.checkCommentParent(bc, target)
+	if err != nil {
+		return "", status, err
+	}
 	// Check and clean comment
-	comment = cleanHTMLText(comment)
+	comment = cleanCommentMarkdown(comment)
 	if comment == "" {
 		return "", http.StatusBadRequest, errors.New("comment is empty")
 	}
 	name = defaultIfEmpty(cleanHTMLText(name), "Anonymous")
 	website = cleanHTMLText(website)
+	email, err = cleanCommentEmail(email)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
 	original = cleanHTMLText(original)
 	if original != "" {
 		// Check if comment already exists
@@ -92,9 +173,16 @@ func (a *goBlog) createComment(bc *configBlog, target, comment, name, website, o
 	}
 	// Insert
 	if updateId == -1 {
+		newStatus := a.newCommentStatus(bc, comment, name, website, original)
+		switch a.checkSpam(comment, name, website) {
+		case spamVerdictReject:
+			return "", http.StatusBadRequest, errors.New("comment rejected as spam")
+		case spamVerdictHold:
+			newStatus = commentStatusPending
+		}
 		result, err := a.db.Exec(
-			"insert into comments (target, comment, name, website, original) values (@target, @comment, @name, @website, @original)",
-			sql.Named("target", target), sql.Named("comment", comment), sql.Named("name", name), sql.Named("website", website), sql.Named("original", original),
+			"insert into comments (target, comment, name, website, email, original, parent, status, created) values (@target, @comment, @name, @website, @email, @original, @parent, @status, @created)",
+			sql.Named("target", target), sql.Named("comment", comment), sql.Named("name", name), sql.Named("website", website), sql.Named("email", email), sql.Named("original", original), sql.Named("parent", parent), sql.Named("status", newStatus), sql.Named("created", utcNowString()),
 		)
 		if err != nil {
 			return "", http.StatusInternalServerError, errors.New("failed to save comment to database")
@@ -103,18 +191,32 @@ func (a *goBlog) createComment(bc *configBlog, target, comment, name, website, o
 			return "", http.StatusInternalServerError, errors.New("failed to save comment to database")
 		} else {
 			commentAddress := bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, commentID))
+			if newStatus != commentStatusApproved {
+				// Notify about the comment awaiting moderation
+				a.sendNotification(fmt.Sprintf("New comment awaiting moderation: %s", a.getFullAddress(commentAddress)))
+				return commentAddress, http.StatusAccepted, nil
+			}
 			// Send webmention
-			_ = a.createWebmention(a.getFullAddress(commentAddress), a.getFullAddress(target))
+			_ = a.createWebmention(a.getFullAddress(commentAddress), a.commentReplyTarget(bc, target, parent))
+			// Notify subscribers
+			go a.notifyCommentSubscribers(bc, int(commentID))
 			// Return comment path
 			return commentAddress, 0, nil
 		}
 	} else {
-		if err := a.db.updateComment(updateId, comment, name, website); err != nil {
+		existing, err := a.db.getComment(updateId)
+		if err != nil || existing == nil {
+			return "", http.StatusInternalServerError, errors.New("failed to check the database")
+		}
+		if err := a.db.updateComment(updateId, comment, name, website, existing.Status); err != nil {
 			return "", http.StatusInternalServerError, errors.New("failed to update comment in database")
 		}
 		commentAddress := bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, updateId))
+		if existing.Status != commentStatusApproved {
+			return commentAddress, http.StatusAccepted, nil
+		}
 		// Send webmention
-		_ = a.createWebmention(a.getFullAddress(commentAddress), a.getFullAddress(target))
+		_ = a.createWebmention(a.getFullAddress(commentAddress), a.commentReplyTarget(bc, target, parent))
 		// Return comment path
 		return commentAddress, 0, nil
 	}
@@ -133,18 +235,74 @@ func (a *goBlog) checkCommentTarget(target string) (string, int, error) {
 	return targetURL.Path, 0, nil
 }
 
+// If the target is a comment, return its ID as parent and the target of the parent comment
+func (a *goBlog) checkCommentParent(bc *configBlog, target string) (int, string, int, error) {
+	idString, isComment := strings.CutPrefix(target, bc.getRelativePath(commentPath)+"/")
+	if !isComment {
+		return 0, target, 0, nil
+	}
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, "", http.StatusBadRequest, errors.New("bad target")
+	}
+	parent, err := a.db.getComment(id)
+	if err != nil {
+		return 0, "", http.StatusInternalServerError, errors.New("failed to check the database")
+	}
+	if parent == nil || parent.Status != commentStatusApproved {
+		return 0, "", http.StatusBadRequest, errors.New("parent comment not found")
+	}
+	return parent.ID, parent.Target, 0, nil
+}
+
+// The address a comment replies to, for replies to other comments that's the parent comment
+func (a *goBlog) commentReplyTarget(bc *configBlog, target string, parent int) string {
+	if parent != 0 {
+		return a.getFullAddress(bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, parent)))
+	}
+	return a.getFullAddress(target)
+}
+
 type commentsRequestConfig struct {
 	id, offset, limit int
+	ids               []int
+	status            commentStatus
+	target            string // path of the commented post
+	blog              string // only comments of published public posts of this blog
 }
 
 func buildCommentsQuery(config *commentsRequestConfig) (query string, args []any) {
 	queryBuilder := builderpool.Get()
 	defer builderpool.Put(queryBuilder)
-	queryBuilder.WriteString("select id, target, name, website, comment, original from comments")
+	queryBuilder.WriteString("select id, target, name, website, comment, original, parent, status, email, created, plaintext from comments where 1")
 	if config.id != 0 {
-		queryBuilder.WriteString(" where id = @id")
+		queryBuilder.WriteString(" and id = @id")
 		args = append(args, sql.Named("id", config.id))
 	}
+	if len(config.ids) > 0 {
+		queryBuilder.WriteString(" and id in (")
+		for i, id := range config.ids {
+			if i > 0 {
+				queryBuilder.WriteString(", ")
+			}
+			named := "id" + strconv.Itoa(i)
+			queryBuilder.WriteString("@" + named)
+			args = append(args, sql.Named(named, id))
+		}
+		queryBuilder.WriteString(")")
+	}
+	if config.status != "" {
+		queryBuilder.WriteString(" and status = @status")
+		args = append(args, sql.Named("status", config.status))
+	}
+	if config.target != "" {
+		queryBuilder.WriteString(" and target = @target")
+		args = append(args, sql.Named("target", config.target))
+	}
+	if config.blog != "" {
+		queryBuilder.WriteString(" and target in (select path from posts where blog = @blog and status = @published and visibility = @public)")
+		args = append(args, sql.Named("blog", config.blog), sql.Named("published", statusPublished), sql.Named("public", visibilityPublic))
+	}
 	queryBuilder.WriteString(" order by id desc")
 	if config.limit != 0 || config.offset != 0 {
 		queryBuilder.WriteString(" limit @limit offset @offset")
@@ -162,7 +320,7 @@ func (db *database) getComments(config *commentsRequestConfig) ([]*comment, erro
 	}
 	for rows.Next() {
 		c := &comment{}
-		err = rows.Scan(&c.ID, &c.Target, &c.Name, &c.Website, &c.Comment, &c.Original)
+		err = rows.Scan(&c.ID, &c.Target, &c.Name, &c.Website, &c.Comment, &c.Original, &c.Parent, &c.Status, &c.Email, &c.Created, &c.PlainText)
 		if err != nil {
 			return nil, err
 		}
@@ -171,6 +329,14 @@ func (db *database) getComments(config *commentsRequestConfig) ([]*comment, erro
 	return comments, nil
 }
 
+func (db *database) getComment(id int) (*comment, error) {
+	comments, err := db.getComments(&commentsRequestConfig{id: id})
+	if err != nil || len(comments) < 1 {
+		return nil, err
+	}
+	return comments[0], nil
+}
+
 func (db *database) countComments(config *commentsRequestConfig) (count int, err error) {
 	query, params := buildCommentsQuery(config)
 	query = "select count(*) from (" + query + ")"
@@ -182,16 +348,20 @@ func (db *database) countComments(config *commentsRequestConfig) (count int, err
 	return
 }
 
-func (db *database) updateComment(id int, comment, name, website string) error {
+func (db *database) updateComment(id int, comment, name, website string, status commentStatus) error {
 	_, err := db.Exec(
-		"update comments set comment = @comment, name = @name, website = @website where id = @id",
-		sql.Named("comment", comment), sql.Named("name", name), sql.Named("website", website), sql.Named("id", id),
+		"update comments set comment = @comment, name = @name, website = @website, status = @status, plaintext = false where id = @id",
+		sql.Named("comment", comment), sql.Named("name", name), sql.Named("website", website), sql.Named("status", status), sql.Named("id", id),
 	)
 	return err
 }
 
+// Delete the comment, replies to it are moved to the parent of the deleted comment
 func (db *database) deleteComment(id int) error {
-	_, err := db.Exec("delete from comments where id = @id", sql.Named("id", id))
+	_, err := db.Exec(
+		"begin; update comments set parent = (select parent from comments where id = ?) where parent = ?; delete from comments where id = ?; commit;",
+		dbNoCache, id, id, id,
+	)
 	return err
 }
 
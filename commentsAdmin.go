@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"sync"
@@ -37,8 +38,15 @@ func (p *commentsPaginationAdapter) Slice(offset, length int, data any) error {
 
 func (a *goBlog) commentsAdmin(w http.ResponseWriter, r *http.Request) {
 	commentsPath := r.Context().Value(pathKey).(string)
+	var status commentStatus = ""
+	switch commentStatus(r.URL.Query().Get("status")) {
+	case commentStatusApproved:
+		status = commentStatusApproved
+	case commentStatusPending:
+		status = commentStatusPending
+	}
 	// Adapter
-	p := paginator.New(&commentsPaginationAdapter{config: &commentsRequestConfig{}, db: a.db}, 5)
+	p := paginator.New(&commentsPaginationAdapter{config: &commentsRequestConfig{status: status}, db: a.db}, 5)
 	p.SetPage(stringToInt(chi.URLParam(r, "page")))
 	var comments []*comment
 	err := p.Results(&comments)
@@ -68,14 +76,20 @@ func (a *goBlog) commentsAdmin(w http.ResponseWriter, r *http.Request) {
 		nextPage, _ = p.Page()
 	}
 	nextPath = fmt.Sprintf("%s/page/%d", commentsPath, nextPage)
+	// Query
+	query := ""
+	if status != "" {
+		query = "?" + url.Values{"status": []string{string(status)}}.Encode()
+	}
 	// Render
 	a.render(w, r, a.renderCommentsAdmin, &renderData{
 		Data: &commentsRenderData{
 			comments: comments,
 			hasPrev:  hasPrev,
 			hasNext:  hasNext,
-			prev:     prevPath,
-			next:     nextPath,
+			prev:     prevPath + query,
+			next:     nextPath + query,
+			pending:  noError(a.db.countComments(&commentsRequestConfig{status: commentStatusPending})),
 		},
 	})
 }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	commentAuthorEditSubPath   = "/author/edit"
+	commentAuthorDeleteSubPath = "/author/delete"
+
+	commentEditTokenKey contextKey = "commentEditToken"
+
+	commentEditSecretCacheKey = "comment_edit_secret"
+	commentEditSecretLength   = 64
+)
+
+// Time window in which commenters can edit or delete their own comments, 0 if disabled
+func (bc *configBlog) commentEditWindow() time.Duration {
+	if !bc.commentsEnabled() || bc.Comments.EditWindow <= 0 {
+		return 0
+	}
+	return time.Duration(bc.Comments.EditWindow) * time.Minute
+}
+
+// Get the secret to sign edit tokens, it's generated on first use and saved in the database
+func (a *goBlog) commentEditSecret() ([]byte, error) {
+	a.commentEditSecretMutex.Lock()
+	defer a.commentEditSecretMutex.Unlock()
+	secret, err := a.db.retrievePersistentCache(commentEditSecretCacheKey)
+	if err != nil || secret != nil {
+		return secret, err
+	}
+	newSecret, err := secureRandomString(commentEditSecretLength)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.db.cachePersistently(commentEditSecretCacheKey, []byte(newSecret)); err != nil {
+		return nil, err
+	}
+	return []byte(newSecret), nil
+}
+
+func (a *goBlog) signCommentEditToken(payload string) (string, error) {
+	secret, err := a.commentEditSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Create a signed token that allows to edit or delete the comment until it expires
+func (a *goBlog) createCommentEditToken(id int, expires time.Time) (string, error) {
+	payload := fmt.Sprintf("%d.%d", id, expires.Unix())
+	signature, err := a.signCommentEditToken(payload)
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + signature, nil
+}
+
+func (a *goBlog) checkCommentEditToken(id int, token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != strconv.Itoa(id) {
+		return false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	signature, err := a.signCommentEditToken(parts[0] + "." + parts[1])
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(parts[2]))
+}
+
+func commentEditTokenCookieName(id int) string {
+	return fmt.Sprintf("comment_edit_%d", id)
+}
+
+// Issue an edit token for a new comment, it's saved in a cookie and returned to show it once
+func (a *goBlog) issueCommentEditToken(w http.ResponseWriter, bc *configBlog, id int) string {
+	window := bc.commentEditWindow()
+	if window == 0 {
+		return ""
+	}
+	token, err := a.createCommentEditToken(id, time.Now().Add(window))
+	if err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     commentEditTokenCookieName(id),
+		Value:    token,
+		Path:     bc.getRelativePath(commentPath),
+		MaxAge:   int(window.Seconds()),
+		Secure:   a.useSecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// Path to edit the comment with the token
+func (bc *configBlog) commentAuthorEditPath(id int, token string) string {
+	return bc.getRelativePath(commentPath+commentAuthorEditSubPath) + "?id=" + strconv.Itoa(id) + "&token=" + token
+}
+
+// Check the edit token from the form or the cookie
+func (a *goBlog) commentAuthorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.FormValue("id"))
+		if err != nil {
+			a.serveError(w, r, "id missing or wrong format", http.StatusBadRequest)
+			return
+		}
+		token := r.FormValue("token")
+		if cookie, err := r.Cookie(commentEditTokenCookieName(id)); token == "" && err == nil {
+			token = cookie.Value
+		}
+		if !a.checkCommentEditToken(id, token) {
+			a.serveError(w, r, "invalid or expired token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), commentEditTokenKey, token)))
+	})
+}
+
+func (a *goBlog) commentAuthorDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := a.db.getComment(id)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		a.serve404(w, r)
+		return
+	}
+	if err = a.db.deleteComment(id); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.cache.purge()
+	_, bc := a.getBlog(r)
+	// Resend webmention, so the mention gets deleted
+	if c.Status == commentStatusApproved {
+		commentAddress := bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, id))
+		_ = a.createWebmention(a.getFullAddress(commentAddress), a.commentReplyTarget(bc, c.Target, c.Parent))
+	}
+	// Remove cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:   commentEditTokenCookieName(id),
+		Path:   bc.getRelativePath(commentPath),
+		MaxAge: -1,
+	})
+	http.Redirect(w, r, c.Target, http.StatusFound)
+}
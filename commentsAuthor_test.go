@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goblog.app/app/pkgs/contenttype"
+)
+
+func Test_commentEditToken(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+
+	token, err := app.createCommentEditToken(1, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, app.checkCommentEditToken(1, token))
+
+	// Wrong comment
+	assert.False(t, app.checkCommentEditToken(2, token))
+
+	// Tampered expiry
+	parts := strings.Split(token, ".")
+	assert.False(t, app.checkCommentEditToken(1, parts[0]+".9999999999."+parts[2]))
+
+	// Expired
+	token, err = app.createCommentEditToken(1, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, app.checkCommentEditToken(1, token))
+
+	assert.False(t, app.checkCommentEditToken(1, ""))
+}
+
+func Test_commentAuthorEdit(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Comments = &configComments{Enabled: true, EditWindow: 15}
+
+	mux := chi.NewMux()
+	mux.Use(middleware.WithValue(blogKey, app.cfg.DefaultBlog))
+	mux.Post("/comment", app.createCommentFromRequest)
+	mux.Group(func(r chi.Router) {
+		r.Use(app.commentAuthorMiddleware)
+		r.Get("/comment/author/edit", app.serveCommentsEditor)
+		r.Post("/comment/author/edit", app.serveCommentsEditor)
+		r.Post("/comment/author/delete", app.commentAuthorDelete)
+	})
+
+	// Create comment, the edit link is shown and saved in a cookie
+	data := url.Values{}
+	data.Add("target", "https://example.com/abc")
+	data.Add("comment", "Tpyo")
+	req := httptest.NewRequest(http.MethodPost, "/comment", strings.NewReader(data.Encode()))
+	req.Header.Add(contentType, contenttype.WWWForm)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/comment/author/edit?id=1&amp;token=")
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "comment_edit_1", cookies[0].Name)
+
+	// Without token
+	req = httptest.NewRequest(http.MethodGet, "/comment/author/edit?id=1", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// With token from cookie
+	req = httptest.NewRequest(http.MethodGet, "/comment/author/edit?id=1", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Tpyo")
+
+	// Token of another comment
+	req = httptest.NewRequest(http.MethodGet, "/comment/author/edit?id=2&token="+url.QueryEscape(cookies[0].Value), nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Edit
+	data = url.Values{}
+	data.Add("id", "1")
+	data.Add("token", cookies[0].Value)
+	data.Add("comment", "Typo")
+	req = httptest.NewRequest(http.MethodPost, "/comment/author/edit", strings.NewReader(data.Encode()))
+	req.Header.Add(contentType, contenttype.WWWForm)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/comment/1", rec.Header().Get("Location"))
+
+	c, err := app.db.getComment(1)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "Typo", c.Comment)
+	assert.Equal(t, commentStatusApproved, c.Status)
+
+	// Edits are moderated again
+	bc.Comments.ModerationKeywords = []string{"casino"}
+	data = url.Values{}
+	data.Add("id", "1")
+	data.Add("token", cookies[0].Value)
+	data.Add("comment", "Visit my casino")
+	req = httptest.NewRequest(http.MethodPost, "/comment/author/edit", strings.NewReader(data.Encode()))
+	req.Header.Add(contentType, contenttype.WWWForm)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/abc", rec.Header().Get("Location"))
+
+	c, err = app.db.getComment(1)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, commentStatusPending, c.Status)
+
+	// Held comments aren't approved by edits
+	bc.Comments.ModerationKeywords = nil
+	data.Set("comment", "Typo")
+	req = httptest.NewRequest(http.MethodPost, "/comment/author/edit", strings.NewReader(data.Encode()))
+	req.Header.Add(contentType, contenttype.WWWForm)
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	c, err = app.db.getComment(1)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, commentStatusPending, c.Status)
+
+	// Delete
+	data = url.Values{}
+	data.Add("id", "1")
+	data.Add("token", cookies[0].Value)
+	req = httptest.NewRequest(http.MethodPost, "/comment/author/delete", strings.NewReader(data.Encode()))
+	req.Header.Add(contentType, contenttype.WWWForm)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+
+	c, err = app.db.getComment(1)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"path"
 	"strconv"
+
+	"github.com/samber/lo"
 )
 
 const commentEditSubPath = "/edit"
@@ -26,17 +29,47 @@ func (a *goBlog) serveCommentsEditor(w http.ResponseWriter, r *http.Request) {
 	comment := comments[0]
 	blog, bc := a.getBlog(r)
 	if r.Method == http.MethodPost {
-		name := r.FormValue("name")
-		website := r.FormValue("website")
-		commentText := r.FormValue("comment")
-		if err := a.db.updateComment(id, commentText, name, website); err != nil {
+		name := defaultIfEmpty(cleanHTMLText(r.FormValue("name")), comment.Name)
+		website := cleanHTMLText(r.FormValue("website"))
+		commentText := cleanCommentMarkdown(r.FormValue("comment"))
+		if commentText == "" {
+			a.serveError(w, r, "comment is empty", http.StatusBadRequest)
+			return
+		}
+		status := comment.Status
+		if _, authorEdit := r.Context().Value(commentEditTokenKey).(string); authorEdit {
+			// Edits by the commenter are checked like new comments, so approved comments can't be changed to spam
+			status = a.newCommentStatus(bc, commentText, name, website, comment.Original)
+			switch a.checkSpam(commentText, name, website) {
+			case spamVerdictReject:
+				a.serveError(w, r, "comment rejected as spam", http.StatusBadRequest)
+				return
+			case spamVerdictHold:
+				status = commentStatusPending
+			}
+			if comment.Status != commentStatusApproved {
+				// Only admins approve held comments
+				status = comment.Status
+			}
+		}
+		if err := a.db.updateComment(id, commentText, name, website, status); err != nil {
 			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		a.cache.purge()
-		// Resend webmention
 		commentAddress := bc.getRelativePath(path.Join(commentPath, strconv.Itoa(id)))
-		_ = a.createWebmention(a.getFullAddress(commentAddress), a.getFullAddress(comment.Target))
+		if status != commentStatusApproved {
+			if comment.Status == commentStatusApproved {
+				// Resend webmention, so the mention gets deleted until the comment is approved again
+				_ = a.createWebmention(a.getFullAddress(commentAddress), a.commentReplyTarget(bc, comment.Target, comment.Parent))
+				a.sendNotification(fmt.Sprintf("Edited comment awaiting moderation: %s", a.getFullAddress(commentAddress)))
+			}
+			// Comments awaiting moderation are only visible to logged in users
+			http.Redirect(w, r, lo.If(a.isLoggedIn(r), commentAddress).Else(comment.Target), http.StatusFound)
+			return
+		}
+		// Resend webmention
+		_ = a.createWebmention(a.getFullAddress(commentAddress), a.commentReplyTarget(bc, comment.Target, comment.Parent))
 		// Redirect to comment
 		http.Redirect(w, r, commentAddress, http.StatusFound)
 		return
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/samber/lo"
+)
+
+// Comment from an export of another comment system
+type importedComment struct {
+	id, parent string
+	threadURL  string
+	name       string
+	website    string
+	content    string
+	created    time.Time
+	noDate     bool // The export had no valid date, created is the import time
+}
+
+type disqusExport struct {
+	Threads []struct {
+		ID   string `xml:"id,attr"`
+		Link string `xml:"link"`
+	} `xml:"thread"`
+	Posts []struct {
+		ID        string `xml:"id,attr"`
+		Message   string `xml:"message"`
+		CreatedAt string `xml:"createdAt"`
+		IsDeleted bool   `xml:"isDeleted"`
+		IsSpam    bool   `xml:"isSpam"`
+		Author    struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Thread struct {
+			ID string `xml:"id,attr"`
+		} `xml:"thread"`
+		Parent struct {
+			ID string `xml:"id,attr"`
+		} `xml:"parent"`
+	} `xml:"post"`
+}
+
+type wxrExport struct {
+	Items []struct {
+		Link     string `xml:"link"`
+		Comments []struct {
+			ID        string `xml:"comment_id"`
+			Author    string `xml:"comment_author"`
+			AuthorURL string `xml:"comment_author_url"`
+			Date      string `xml:"comment_date"`
+			DateGMT   string `xml:"comment_date_gmt"`
+			Content   string `xml:"comment_content"`
+			Approved  string `xml:"comment_approved"`
+			Type      string `xml:"comment_type"`
+			Parent    string `xml:"comment_parent"`
+		} `xml:"comment"`
+	} `xml:"channel>item"`
+}
+
+// Import comments from a Disqus XML or WordPress WXR export file
+func (a *goBlog) importCommentsFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	comments, err := parseCommentsExport(f)
+	if err != nil {
+		return err
+	}
+	return a.importComments(comments)
+}
+
+func parseCommentsExport(r io.ReadSeeker) ([]*importedComment, error) {
+	// Detect format using the root element
+	root := ""
+	decoder := xml.NewDecoder(r)
+	for root == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := token.(xml.StartElement); ok {
+			root = se.Name.Local
+		}
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch root {
+	case "disqus":
+		return parseDisqusExport(r)
+	case "rss":
+		return parseWXRExport(r)
+	default:
+		return nil, fmt.Errorf("unknown export format with root element %q", root)
+	}
+}
+
+func parseDisqusExport(r io.Reader) ([]*importedComment, error) {
+	export := &disqusExport{}
+	if err := xml.NewDecoder(r).Decode(export); err != nil {
+		return nil, err
+	}
+	threads := map[string]string{}
+	for _, t := range export.Threads {
+		threads[t.ID] = t.Link
+	}
+	comments := []*importedComment{}
+	for _, p := range export.Posts {
+		if p.IsDeleted || p.IsSpam {
+			continue
+		}
+		comments = append(comments, &importedComment{
+			id:        p.ID,
+			parent:    p.Parent.ID,
+			threadURL: threads[p.Thread.ID],
+			name:      p.Author.Name,
+			content:   p.Message,
+			created:   noError(dateparse.ParseIn(p.CreatedAt, time.UTC)),
+		})
+	}
+	return comments, nil
+}
+
+func parseWXRExport(r io.Reader) ([]*importedComment, error) {
+	export := &wxrExport{}
+	if err := xml.NewDecoder(r).Decode(export); err != nil {
+		return nil, err
+	}
+	comments := []*importedComment{}
+	for _, item := range export.Items {
+		for _, c := range item.Comments {
+			// Only approved comments, no pingbacks or trackbacks
+			if c.Approved != "1" || (c.Type != "" && c.Type != "comment") {
+				continue
+			}
+			created, err := dateparse.ParseIn(c.DateGMT, time.UTC)
+			if err != nil || created.Year() < 1 {
+				created, _ = dateparse.ParseIn(c.Date, time.UTC)
+			}
+			comments = append(comments, &importedComment{
+				id:        c.ID,
+				parent:    lo.If(c.Parent != "0", c.Parent).Else(""),
+				threadURL: item.Link,
+				name:      c.Author,
+				website:   c.AuthorURL,
+				content:   c.Content,
+				created:   created,
+			})
+		}
+	}
+	return comments, nil
+}
+
+func (a *goBlog) importComments(comments []*importedComment) error {
+	// Comments without valid date get the import time
+	importTime := time.Now()
+	for _, ic := range comments {
+		if ic.created.Unix() <= 0 {
+			ic.created, ic.noDate = importTime, true
+		}
+	}
+	// Import in chronological order, but parents always before their replies
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].created.Before(comments[j].created)
+	})
+	comments = importedCommentsParentsFirst(comments)
+	importedIds := map[string]int{}
+	unmatched := map[string]int{}
+	imported, duplicates, orphaned := 0, 0, 0
+	for _, ic := range comments {
+		path, err := a.db.postPathForImportedThread(ic.threadURL)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			unmatched[ic.threadURL]++
+			continue
+		}
+		p, err := a.getPost(path)
+		if err != nil {
+			return err
+		}
+		bc := a.getBlogFromPost(p)
+		if !bc.commentsEnabled() {
+			unmatched[ic.threadURL]++
+			continue
+		}
+		c := &comment{
+			Target:    path,
+			Name:      defaultIfEmpty(cleanHTMLText(ic.name), "Anonymous"),
+			Website:   cleanHTMLText(ic.website),
+			Comment:   cleanHTMLText(ic.content),
+			Parent:    importedIds[ic.parent],
+			Status:    commentStatusApproved,
+			PlainText: true,
+		}
+		if c.Comment == "" {
+			continue
+		}
+		if _, parentImported := importedIds[ic.parent]; ic.parent != "" && !parentImported {
+			// The parent wasn't imported (for example because it's not approved), so the reply is top-level
+			orphaned++
+		}
+		id, created, err := a.db.insertImportedComment(c, ic.created, ic.noDate)
+		if err != nil {
+			return err
+		}
+		importedIds[ic.id] = id
+		if !created {
+			duplicates++
+			continue
+		}
+		// Insert approved webmention to show the comment on the post
+		source := a.getFullAddress(bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, id)))
+		if err = a.db.insertWebmention(&mention{
+			Source:  source,
+			Target:  a.commentReplyTarget(bc, c.Target, c.Parent),
+			Url:     source,
+			Created: ic.created.Unix(),
+			Content: c.Comment,
+			Author:  c.Name,
+			Type:    mentionTypeReply,
+		}, webmentionStatusApproved); err != nil {
+			return err
+		}
+		imported++
+	}
+	log.Printf("Imported %d comments, skipped %d already imported comments", imported, duplicates)
+	if orphaned > 0 {
+		log.Printf("%d replies lost their parent comment and were added as top-level comments", orphaned)
+	}
+	if len(unmatched) > 0 {
+		log.Printf("%d threads couldn't be matched to posts with enabled comments:", len(unmatched))
+		for _, threadURL := range lo.Keys(unmatched) {
+			log.Printf("%s (%d comments)", defaultIfEmpty(threadURL, "(no URL)"), unmatched[threadURL])
+		}
+	}
+	return nil
+}
+
+// Move parents in front of their replies, even if their date is newer or missing
+func importedCommentsParentsFirst(comments []*importedComment) []*importedComment {
+	byId := lo.KeyBy(lo.Filter(comments, func(ic *importedComment, _ int) bool { return ic.id != "" }), func(ic *importedComment) string { return ic.id })
+	ordered := make([]*importedComment, 0, len(comments))
+	added := map[*importedComment]bool{}
+	var add func(ic *importedComment)
+	add = func(ic *importedComment) {
+		if added[ic] {
+			return
+		}
+		added[ic] = true
+		if parent, ok := byId[ic.parent]; ok && ic.parent != "" {
+			add(parent)
+		}
+		ordered = append(ordered, ic)
+	}
+	for _, ic := range comments {
+		add(ic)
+	}
+	return ordered
+}
+
+// Find the post path for a thread URL, checks post paths, aliases and short paths
+func (db *database) postPathForImportedThread(threadURL string) (string, error) {
+	u, err := url.Parse(threadURL)
+	if err != nil || threadURL == "" {
+		return "", nil
+	}
+	p := unescapedPath(u.Path)
+	candidates := lo.Uniq(lo.Filter([]string{p, strings.TrimSuffix(p, "/"), p + "/"}, func(s string, _ int) bool {
+		return s != "" && s != "//"
+	}))
+	for _, candidate := range candidates {
+		row, err := db.QueryRow(`
+		select path from posts where path = @path
+		union all
+		select path from shortpath where printf('/s/%x', id) = @path
+		union all
+		select path from post_parameters where parameter = 'aliases' and value = @path
+		limit 1
+		`, sql.Named("path", candidate))
+		if err != nil {
+			return "", err
+		}
+		var path string
+		if err = row.Scan(&path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// Insert an imported comment if it doesn't exist yet, returns the ID and if it was created,
+// comments without date from the export are matched without the date
+func (db *database) insertImportedComment(c *comment, created time.Time, anyDate bool) (int, bool, error) {
+	createdString := created.UTC().Format(time.RFC3339)
+	row, err := db.QueryRow(
+		"select id from comments where target = @target and name = @name and comment = @comment and (@anydate or created = @created)",
+		sql.Named("target", c.Target), sql.Named("name", c.Name), sql.Named("comment", c.Comment), sql.Named("anydate", anyDate), sql.Named("created", createdString),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	var id int
+	if err = row.Scan(&id); err == nil {
+		return id, false, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+	result, err := db.Exec(
+		"insert into comments (target, comment, name, website, parent, status, created, plaintext) values (@target, @comment, @name, @website, @parent, @status, @created, @plaintext)",
+		sql.Named("target", c.Target), sql.Named("comment", c.Comment), sql.Named("name", c.Name), sql.Named("website", c.Website),
+		sql.Named("parent", c.Parent), sql.Named("status", c.Status), sql.Named("created", createdString), sql.Named("plaintext", c.PlainText),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	newId, err := result.LastInsertId()
+	return int(newId), true, err
+}
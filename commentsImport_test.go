@@ -0,0 +1,260 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDisqusExport = `<?xml version="1.0" encoding="utf-8"?>
+<disqus xmlns="http://disqus.com" xmlns:dsq="http://disqus.com/disqus-internals">
+	<thread dsq:id="1">
+		<link>https://old.example.com/old-post/</link>
+	</thread>
+	<thread dsq:id="2">
+		<link>https://old.example.com/unknown</link>
+	</thread>
+	<post dsq:id="10">
+		<message><![CDATA[<p>First comment</p>]]></message>
+		<createdAt>2020-01-01T10:00:00Z</createdAt>
+		<isDeleted>false</isDeleted>
+		<isSpam>false</isSpam>
+		<author><name>Alice</name></author>
+		<thread dsq:id="1" />
+	</post>
+	<post dsq:id="11">
+		<message><![CDATA[<p>Reply</p>]]></message>
+		<createdAt>2020-01-02T10:00:00Z</createdAt>
+		<isDeleted>false</isDeleted>
+		<isSpam>false</isSpam>
+		<author><name>Bob</name></author>
+		<thread dsq:id="1" />
+		<parent dsq:id="10" />
+	</post>
+	<post dsq:id="12">
+		<message><![CDATA[<p>Spam</p>]]></message>
+		<createdAt>2020-01-03T10:00:00Z</createdAt>
+		<isDeleted>false</isDeleted>
+		<isSpam>true</isSpam>
+		<author><name>Spammer</name></author>
+		<thread dsq:id="1" />
+	</post>
+	<post dsq:id="13">
+		<message><![CDATA[<p>Lost comment</p>]]></message>
+		<createdAt>2020-01-04T10:00:00Z</createdAt>
+		<isDeleted>false</isDeleted>
+		<isSpam>false</isSpam>
+		<author><name>Carol</name></author>
+		<thread dsq:id="2" />
+	</post>
+</disqus>`
+
+const testWXRExport = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:wp="http://wordpress.org/export/1.2/">
+	<channel>
+		<item>
+			<link>https://example.com/post</link>
+			<wp:comment>
+				<wp:comment_id>5</wp:comment_id>
+				<wp:comment_author>Dave</wp:comment_author>
+				<wp:comment_author_url>https://dave.example.org</wp:comment_author_url>
+				<wp:comment_date>2021-05-01 12:00:00</wp:comment_date>
+				<wp:comment_date_gmt>2021-05-01 10:00:00</wp:comment_date_gmt>
+				<wp:comment_content>WordPress comment</wp:comment_content>
+				<wp:comment_approved>1</wp:comment_approved>
+				<wp:comment_type>comment</wp:comment_type>
+				<wp:comment_parent>0</wp:comment_parent>
+			</wp:comment>
+			<wp:comment>
+				<wp:comment_id>6</wp:comment_id>
+				<wp:comment_author>Other blog</wp:comment_author>
+				<wp:comment_date_gmt>2021-05-02 10:00:00</wp:comment_date_gmt>
+				<wp:comment_content>Pingback</wp:comment_content>
+				<wp:comment_approved>1</wp:comment_approved>
+				<wp:comment_type>pingback</wp:comment_type>
+				<wp:comment_parent>0</wp:comment_parent>
+			</wp:comment>
+			<wp:comment>
+				<wp:comment_id>7</wp:comment_id>
+				<wp:comment_author>Eve</wp:comment_author>
+				<wp:comment_date_gmt>2021-05-03 10:00:00</wp:comment_date_gmt>
+				<wp:comment_content>Unapproved</wp:comment_content>
+				<wp:comment_approved>0</wp:comment_approved>
+				<wp:comment_parent>0</wp:comment_parent>
+			</wp:comment>
+		</item>
+	</channel>
+</rss>`
+
+func Test_parseCommentsExport(t *testing.T) {
+	comments, err := parseCommentsExport(strings.NewReader(testDisqusExport))
+	require.NoError(t, err)
+	require.Len(t, comments, 3)
+	assert.Equal(t, "https://old.example.com/old-post/", comments[0].threadURL)
+	assert.Equal(t, "Alice", comments[0].name)
+	assert.Equal(t, "10", comments[1].parent)
+	assert.Equal(t, 2020, comments[1].created.Year())
+
+	comments, err = parseCommentsExport(strings.NewReader(testWXRExport))
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "Dave", comments[0].name)
+	assert.Equal(t, "https://dave.example.org", comments[0].website)
+	assert.Equal(t, "", comments[0].parent)
+	assert.Equal(t, 10, comments[0].created.Hour())
+
+	_, err = parseCommentsExport(strings.NewReader(`<html></html>`))
+	assert.Error(t, err)
+}
+
+func Test_importComments(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Comments = &configComments{Enabled: true}
+
+	err = app.createPost(&post{
+		Path:    "/post",
+		Content: "Post",
+		Parameters: map[string][]string{
+			"aliases": {"/old-post"},
+		},
+	})
+	require.NoError(t, err)
+
+	// Disqus with alias and reply
+	comments, err := parseCommentsExport(strings.NewReader(testDisqusExport))
+	require.NoError(t, err)
+	err = app.importComments(comments)
+	require.NoError(t, err)
+
+	count, err := app.db.countComments(&commentsRequestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	c, err := app.db.getComment(2)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "/post", c.Target)
+	assert.Equal(t, "Bob", c.Name)
+	assert.Equal(t, 1, c.Parent)
+	assert.Equal(t, commentStatusApproved, c.Status)
+
+	// Comments are shown as approved webmentions
+	mentions, err := app.db.getWebmentions(&webmentionsRequestConfig{target: "https://example.com/post", status: webmentionStatusApproved})
+	require.NoError(t, err)
+	require.Len(t, mentions, 1)
+	assert.Equal(t, "Alice", mentions[0].Author)
+	assert.Equal(t, int64(1577872800), mentions[0].Created)
+
+	// Importing again doesn't duplicate comments
+	comments, err = parseCommentsExport(strings.NewReader(testDisqusExport))
+	require.NoError(t, err)
+	err = app.importComments(comments)
+	require.NoError(t, err)
+
+	count, err = app.db.countComments(&commentsRequestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// WordPress
+	comments, err = parseCommentsExport(strings.NewReader(testWXRExport))
+	require.NoError(t, err)
+	err = app.importComments(comments)
+	require.NoError(t, err)
+
+	c, err = app.db.getComment(3)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "Dave", c.Name)
+	assert.Equal(t, "https://dave.example.org", c.Website)
+	assert.True(t, c.PlainText)
+
+	// Missing dates and parents
+	comments, err = parseCommentsExport(strings.NewReader(testWXRExportIncomplete))
+	require.NoError(t, err)
+	require.NoError(t, app.importComments(comments))
+
+	all, err := app.db.getComments(&commentsRequestConfig{target: "/post"})
+	require.NoError(t, err)
+	byName := lo.KeyBy(all, func(c *comment) string { return c.Name })
+	require.Contains(t, byName, "Frank")
+	require.Contains(t, byName, "Grace")
+	require.Contains(t, byName, "Heidi")
+	// Import time instead of a negative date
+	created, err := dateparse.ParseIn(byName["Frank"].Created, time.UTC)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), created, time.Minute)
+	// Reply to the comment without date is imported after its parent
+	assert.Equal(t, byName["Frank"].ID, byName["Grace"].Parent)
+	// The parent isn't approved, so the reply is top-level
+	assert.Equal(t, 0, byName["Heidi"].Parent)
+
+	// Importing again doesn't duplicate comments without date
+	count, err = app.db.countComments(&commentsRequestConfig{})
+	require.NoError(t, err)
+	comments, err = parseCommentsExport(strings.NewReader(testWXRExportIncomplete))
+	require.NoError(t, err)
+	require.NoError(t, app.importComments(comments))
+	newCount, err := app.db.countComments(&commentsRequestConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, count, newCount)
+}
+
+const testWXRExportIncomplete = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:wp="http://wordpress.org/export/1.2/">
+	<channel>
+		<item>
+			<link>https://example.com/post</link>
+			<wp:comment>
+				<wp:comment_id>20</wp:comment_id>
+				<wp:comment_author>Frank</wp:comment_author>
+				<wp:comment_date>0000-00-00 00:00:00</wp:comment_date>
+				<wp:comment_date_gmt>0000-00-00 00:00:00</wp:comment_date_gmt>
+				<wp:comment_content>Comment without date</wp:comment_content>
+				<wp:comment_approved>1</wp:comment_approved>
+				<wp:comment_parent>0</wp:comment_parent>
+			</wp:comment>
+			<wp:comment>
+				<wp:comment_id>21</wp:comment_id>
+				<wp:comment_author>Grace</wp:comment_author>
+				<wp:comment_date_gmt>2021-06-01 10:00:00</wp:comment_date_gmt>
+				<wp:comment_content>Reply to comment without date</wp:comment_content>
+				<wp:comment_approved>1</wp:comment_approved>
+				<wp:comment_parent>20</wp:comment_parent>
+			</wp:comment>
+			<wp:comment>
+				<wp:comment_id>22</wp:comment_id>
+				<wp:comment_author>Ivan</wp:comment_author>
+				<wp:comment_date_gmt>2021-06-02 10:00:00</wp:comment_date_gmt>
+				<wp:comment_content>Unapproved parent</wp:comment_content>
+				<wp:comment_approved>0</wp:comment_approved>
+				<wp:comment_parent>0</wp:comment_parent>
+			</wp:comment>
+			<wp:comment>
+				<wp:comment_id>23</wp:comment_id>
+				<wp:comment_author>Heidi</wp:comment_author>
+				<wp:comment_date_gmt>2021-06-03 10:00:00</wp:comment_date_gmt>
+				<wp:comment_content>Reply to unapproved comment</wp:comment_content>
+				<wp:comment_approved>1</wp:comment_approved>
+				<wp:comment_parent>22</wp:comment_parent>
+			</wp:comment>
+		</item>
+	</channel>
+</rss>`
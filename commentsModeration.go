@@ -0,0 +1,143 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type commentStatus string
+
+const (
+	commentStatusApproved commentStatus = "approved"
+	commentStatusPending  commentStatus = "pending"
+
+	commentApproveSubPath = "/approve"
+	commentSpamSubPath    = "/spam"
+	commentNotSpamSubPath = "/notspam"
+)
+
+// Check if a new comment needs to be held for moderation
+func (a *goBlog) newCommentStatus(bc *configBlog, comment, name, website, original string) commentStatus {
+	cc := bc.Comments
+	if cc == nil {
+		return commentStatusApproved
+	}
+	// Rules apply to all comments, even from known commenters
+	if cc.ModerationMaxLinks > 0 && commentLinkCount(comment) > cc.ModerationMaxLinks {
+		return commentStatusPending
+	}
+	lowerText := strings.ToLower(strings.Join([]string{comment, name, website}, " "))
+	for _, keyword := range cc.ModerationKeywords {
+		if keyword != "" && strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return commentStatusPending
+		}
+	}
+	if !cc.Moderation {
+		return commentStatusApproved
+	}
+	// Auto-approve ActivityPub actors with previously approved comments,
+	// the website of comments from the form can be set to anything, so it's no proof of identity
+	if original != "" && website != "" {
+		if approved, err := a.db.hasApprovedActivityPubComment(website); err == nil && approved {
+			return commentStatusApproved
+		}
+	}
+	return commentStatusPending
+}
+
+func commentLinkCount(comment string) int {
+	lowerComment := strings.ToLower(comment)
+	return strings.Count(lowerComment, "http://") + strings.Count(lowerComment, "https://")
+}
+
+// Comments with an original are received using ActivityPub with a verified signature, the website is the actor
+func (db *database) hasApprovedActivityPubComment(website string) (bool, error) {
+	row, err := db.QueryRow(
+		"select exists(select 1 from comments where website = @website and original != '' and status = @status)",
+		sql.Named("website", website), sql.Named("status", commentStatusApproved),
+	)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	err = row.Scan(&exists)
+	return exists, err
+}
+
+func (db *database) setCommentStatus(id int, status commentStatus) error {
+	_, err := db.Exec("update comments set status = @status where id = @id", sql.Named("status", status), sql.Named("id", id))
+	return err
+}
+
+// Approve a comment awaiting moderation and send the webmention
+func (a *goBlog) approveComment(bc *configBlog, id int) error {
+	c, err := a.db.getComment(id)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return errors.New("comment not found")
+	}
+	if c.Status == commentStatusApproved {
+		return nil
+	}
+	if err = a.db.setCommentStatus(id, commentStatusApproved); err != nil {
+		return err
+	}
+	a.cache.purge()
+	commentAddress := bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, id))
+	_ = a.createWebmention(a.getFullAddress(commentAddress), a.commentReplyTarget(bc, c.Target, c.Parent))
+	go a.notifyCommentSubscribers(bc, id)
+	return nil
+}
+
+func (a *goBlog) commentsAdminApprove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("commentid"))
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, bc := a.getBlog(r)
+	if err = a.approveComment(bc, id); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, ".", http.StatusFound)
+}
+
+// Train the spam filter with the comment, delete spam and approve ham
+func (a *goBlog) commentsAdminTrainSpam(spam bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.FormValue("commentid"))
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c, err := a.db.getComment(id)
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			a.serve404(w, r)
+			return
+		}
+		a.trainSpamFilter(fmt.Sprintf("comment/%d", id), spam, c.Comment, c.Name, c.Website)
+		if spam {
+			err = a.db.deleteComment(id)
+			a.cache.purge()
+		} else {
+			_, bc := a.getBlog(r)
+			err = a.approveComment(bc, id)
+		}
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, ".", http.StatusFound)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"go.goblog.app/app/pkgs/bufferpool"
+)
+
+const (
+	commentSubscriptionConfirmSubPath     = "/subscription/confirm"
+	commentSubscriptionUnsubscribeSubPath = "/subscription/unsubscribe"
+
+	commentSubscriptionTokenLength = 32
+)
+
+type commentSubscription struct {
+	ID     int
+	Target string
+	Email  string
+	Token  string
+}
+
+// Emails to commenters are sent using the SMTP settings of the contact form
+func (bc *configBlog) commentEmailsEnabled() bool {
+	return bc.commentsEnabled() && bc.Contact != nil && bc.Contact.SMTPHost != "" && bc.Contact.EmailFrom != ""
+}
+
+func cleanCommentEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", errors.New("invalid email address")
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+// Create an unconfirmed subscription for the thread of the target and send the confirmation email
+func (a *goBlog) subscribeToComments(bc *configBlog, target, email string) error {
+	if !bc.commentEmailsEnabled() || email == "" {
+		return nil
+	}
+	target, _, err := a.checkCommentTarget(target)
+	if err != nil {
+		return err
+	}
+	// Replies subscribe to the thread of the post
+	_, target, _, err = a.checkCommentParent(bc, target)
+	if err != nil {
+		return err
+	}
+	token, created, err := a.db.createCommentSubscription(target, email)
+	if err != nil || !created {
+		return err
+	}
+	confirmLink := a.getFullAddress(bc.getRelativePath(commentPath+commentSubscriptionConfirmSubPath)) + "?" + url.Values{"token": {token}}.Encode()
+	body := bufferpool.Get()
+	defer bufferpool.Put(body)
+	_, _ = fmt.Fprintf(body, "Please confirm that you want to get notified about new comments on %s by opening this link:\n\n%s\n\n", a.getFullAddress(target), confirmLink)
+	_, _ = body.WriteString("If you didn't request this, you can ignore this email.")
+	return a.sendEmail(bc.Contact, email, "", "Confirm your subscription to new comments", body.String(), nil)
+}
+
+// Notify the confirmed subscribers of the thread about a new approved comment
+func (a *goBlog) notifyCommentSubscribers(bc *configBlog, id int) {
+	if !bc.commentEmailsEnabled() {
+		return
+	}
+	c, err := a.db.getComment(id)
+	if err != nil || c == nil || c.Status != commentStatusApproved {
+		return
+	}
+	subscriptions, err := a.db.getCommentSubscriptions(c.Target)
+	if err != nil {
+		log.Println("Failed to get comment subscriptions:", err.Error())
+		return
+	}
+	commentAddress := a.getFullAddress(bc.getRelativePath(fmt.Sprintf("%s/%d", commentPath, c.ID)))
+	for _, s := range subscriptions {
+		if s.Email == c.Email {
+			// Don't notify about own comments
+			continue
+		}
+		unsubscribeLink := a.getFullAddress(bc.getRelativePath(commentPath+commentSubscriptionUnsubscribeSubPath)) + "?" + url.Values{"token": {s.Token}}.Encode()
+		body := bufferpool.Get()
+		_, _ = fmt.Fprintf(body, "%s commented on %s:\n\n%s\n\n%s\n\n", c.Name, a.getFullAddress(c.Target), c.Comment, commentAddress)
+		_, _ = fmt.Fprintf(body, "Unsubscribe: %s", unsubscribeLink)
+		if err := a.sendEmail(bc.Contact, s.Email, "", "New comment on "+a.getFullAddress(c.Target), body.String(), map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeLink + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}); err != nil {
+			log.Println("Failed to send comment notification:", err.Error())
+		}
+		bufferpool.Put(body)
+	}
+}
+
+func (a *goBlog) serveCommentSubscriptionConfirm(w http.ResponseWriter, r *http.Request) {
+	confirmed, err := a.db.confirmCommentSubscription(r.FormValue("token"))
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !confirmed {
+		a.serveError(w, r, "invalid token", http.StatusBadRequest)
+		return
+	}
+	a.render(w, r, a.renderCommentSubscription, &renderData{
+		Data: "subscriptionconfirmed",
+	})
+}
+
+// Links in emails are opened by link scanners, so GET requests only show a form to confirm unsubscribing
+func (a *goBlog) serveCommentSubscriptionUnsubscribeForm(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	if token == "" {
+		a.serveError(w, r, "invalid token", http.StatusBadRequest)
+		return
+	}
+	a.render(w, r, a.renderCommentSubscriptionUnsubscribe, &renderData{
+		Data: token,
+	})
+}
+
+// Supports one-click unsubscribe using POST requests (RFC 8058)
+func (a *goBlog) serveCommentSubscriptionUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if err := a.db.deleteCommentSubscription(r.FormValue("token")); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.render(w, r, a.renderCommentSubscription, &renderData{
+		Data: "unsubscribed",
+	})
+}
+
+func (db *database) createCommentSubscription(target, email string) (token string, created bool, err error) {
+	token, err = secureRandomString(commentSubscriptionTokenLength, []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")...)
+	if err != nil {
+		return "", false, err
+	}
+	res, err := db.Exec(
+		"insert or ignore into commentsubscriptions (target, email, token, created) values (@target, @email, @token, @created)",
+		sql.Named("target", target), sql.Named("email", email), sql.Named("token", token), sql.Named("created", utcNowString()),
+	)
+	if err != nil {
+		return "", false, err
+	}
+	inserted, err := res.RowsAffected()
+	return token, inserted > 0, err
+}
+
+func (db *database) confirmCommentSubscription(token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	res, err := db.Exec("update commentsubscriptions set confirmed = 1 where token = @token", sql.Named("token", token))
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	return updated > 0, err
+}
+
+func (db *database) deleteCommentSubscription(token string) error {
+	_, err := db.Exec("delete from commentsubscriptions where token = @token", sql.Named("token", token))
+	return err
+}
+
+func (db *database) getCommentSubscriptions(target string) ([]*commentSubscription, error) {
+	rows, err := db.Query(
+		"select id, target, email, token from commentsubscriptions where target = @target and confirmed = 1",
+		sql.Named("target", target),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subscriptions := []*commentSubscription{}
+	for rows.Next() {
+		s := &commentSubscription{}
+		if err = rows.Scan(&s.ID, &s.Target, &s.Email, &s.Token); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
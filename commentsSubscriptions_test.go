@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cleanCommentEmail(t *testing.T) {
+	email, err := cleanCommentEmail(" Test@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "test@example.com", email)
+
+	email, err = cleanCommentEmail("")
+	require.NoError(t, err)
+	assert.Equal(t, "", email)
+
+	_, err = cleanCommentEmail("no email")
+	assert.Error(t, err)
+}
+
+func Test_commentSubscriptions(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Comments = &configComments{Enabled: true}
+	bc.Contact = &configContact{SMTPHost: "smtp.example.com", EmailFrom: "blog@example.com"}
+	assert.True(t, bc.commentEmailsEnabled())
+
+	// Email is saved privately
+	_, _, err = app.createComment(bc, "https://example.com/abc", "Test", "Name", "", "Commenter@example.com", "")
+	require.NoError(t, err)
+	c, err := app.db.getComment(1)
+	require.NoError(t, err)
+	assert.Equal(t, "commenter@example.com", c.Email)
+
+	_, status, err := app.createComment(bc, "https://example.com/abc", "Test", "Name", "", "invalid", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Unconfirmed subscription
+	token, created, err := app.db.createCommentSubscription("/abc", "commenter@example.com")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEmpty(t, token)
+
+	_, created, err = app.db.createCommentSubscription("/abc", "commenter@example.com")
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	subscriptions, err := app.db.getCommentSubscriptions("/abc")
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 0)
+
+	// Confirm
+	req := httptest.NewRequest(http.MethodGet, "/comment/subscription/confirm?token=wrong", nil)
+	rec := httptest.NewRecorder()
+	app.serveCommentSubscriptionConfirm(rec, req.WithContext(context.WithValue(req.Context(), blogKey, app.cfg.DefaultBlog)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/comment/subscription/confirm?token="+token, nil)
+	rec = httptest.NewRecorder()
+	app.serveCommentSubscriptionConfirm(rec, req.WithContext(context.WithValue(req.Context(), blogKey, app.cfg.DefaultBlog)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	subscriptions, err = app.db.getCommentSubscriptions("/abc")
+	require.NoError(t, err)
+	if assert.Len(t, subscriptions, 1) {
+		assert.Equal(t, "commenter@example.com", subscriptions[0].Email)
+	}
+
+	// Opening the unsubscribe link only shows a form
+	req = httptest.NewRequest(http.MethodGet, "/comment/subscription/unsubscribe?token="+token, nil)
+	rec = httptest.NewRecorder()
+	app.serveCommentSubscriptionUnsubscribeForm(rec, req.WithContext(context.WithValue(req.Context(), blogKey, app.cfg.DefaultBlog)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<form class="fw p" method=post action=/comment/subscription/unsubscribe>`)
+	assert.Contains(t, rec.Body.String(), `name=token value=`+token+`>`)
+
+	subscriptions, err = app.db.getCommentSubscriptions("/abc")
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 1)
+
+	// One-click unsubscribe
+	req = httptest.NewRequest(http.MethodPost, "/comment/subscription/unsubscribe?token="+token, nil)
+	rec = httptest.NewRecorder()
+	app.serveCommentSubscriptionUnsubscribe(rec, req.WithContext(context.WithValue(req.Context(), blogKey, app.cfg.DefaultBlog)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	subscriptions, err = app.db.getCommentSubscriptions("/abc")
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 0)
+}
@@ -33,6 +33,7 @@ func Test_comments(t *testing.T) {
 	err := app.initConfig(false)
 	require.NoError(t, err)
 	_ = app.initTemplateStrings()
+	app.initMarkdown()
 	app.initSessions()
 
 	t.Run("Successful comment", func(t *testing.T) {
@@ -215,7 +216,7 @@ func Test_commentsUpdateByOriginal(t *testing.T) {
 
 	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
 
-	addr, _, err := app.createComment(bc, "https://example.com/abc", "Test", "Name", "https://example.org", "https://example.org/1")
+	addr, _, err := app.createComment(bc, "https://example.com/abc", "Test", "Name", "https://example.org", "", "https://example.org/1")
 	require.NoError(t, err)
 
 	splittedAddr := strings.Split(addr, "/")
@@ -233,7 +234,7 @@ func Test_commentsUpdateByOriginal(t *testing.T) {
 	assert.Equal(t, "https://example.org", comment.Website)
 	assert.Equal(t, "https://example.org/1", comment.Original)
 
-	_, _, err = app.createComment(bc, "https://example.com/abc", "Edited comment", "Edited name", "", "https://example.org/1")
+	_, _, err = app.createComment(bc, "https://example.com/abc", "Edited comment", "Edited name", "", "", "https://example.org/1")
 	require.NoError(t, err)
 
 	comments, err = app.db.getComments(&commentsRequestConfig{id: id})
@@ -248,3 +249,194 @@ func Test_commentsUpdateByOriginal(t *testing.T) {
 	assert.Equal(t, "", comment.Website)
 
 }
+
+func Test_commentsThreaded(t *testing.T) {
+
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+
+	addr, _, err := app.createComment(bc, "https://example.com/abc", "Parent", "Name", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/comment/1", addr)
+
+	// Reply to the comment
+	addr, _, err = app.createComment(bc, "https://example.com/comment/1", "Reply", "Other name", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/comment/2", addr)
+
+	reply, err := app.db.getComment(2)
+	require.NoError(t, err)
+	require.NotNil(t, reply)
+
+	assert.Equal(t, "/abc", reply.Target)
+	assert.Equal(t, 1, reply.Parent)
+	assert.Equal(t, "https://example.com/comment/1", app.commentReplyTarget(bc, reply.Target, reply.Parent))
+
+	// Reply to the reply is still threaded below the post
+	_, _, err = app.createComment(bc, "https://example.com/comment/2", "Reply to reply", "Name", "", "", "")
+	require.NoError(t, err)
+
+	replyToReply, err := app.db.getComment(3)
+	require.NoError(t, err)
+	require.NotNil(t, replyToReply)
+
+	assert.Equal(t, "/abc", replyToReply.Target)
+	assert.Equal(t, 2, replyToReply.Parent)
+
+	// Reply to a comment that doesn't exist
+	_, status, err := app.createComment(bc, "https://example.com/comment/99", "Reply", "Name", "", "", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Replies to deleted comments are moved to the parent
+	require.NoError(t, app.db.deleteComment(2))
+
+	replyToReply, err = app.db.getComment(3)
+	require.NoError(t, err)
+	require.NotNil(t, replyToReply)
+	assert.Equal(t, 1, replyToReply.Parent)
+
+	require.NoError(t, app.db.deleteComment(1))
+
+	replyToReply, err = app.db.getComment(3)
+	require.NoError(t, err)
+	require.NotNil(t, replyToReply)
+	assert.Equal(t, 0, replyToReply.Parent)
+
+}
+
+func Test_commentsModeration(t *testing.T) {
+
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Comments = &configComments{
+		Enabled:            true,
+		Moderation:         true,
+		ModerationKeywords: []string{"Casino"},
+		ModerationMaxLinks: 1,
+	}
+
+	// New commenter is held for moderation
+	addr, status, err := app.createComment(bc, "https://example.com/abc", "Test", "Name", "https://example.org", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "/comment/1", addr)
+
+	pending, err := app.db.countComments(&commentsRequestConfig{status: commentStatusPending})
+	require.NoError(t, err)
+	assert.Equal(t, 1, pending)
+
+	// Pending comments are only visible when logged in
+	mux := chi.NewMux()
+	mux.Get("/comment/{id}", app.serveComment)
+	req := httptest.NewRequest(http.MethodGet, "/comment/1", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), blogKey, app.cfg.DefaultBlog)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Approve
+	err = app.approveComment(bc, 1)
+	require.NoError(t, err)
+
+	c, err := app.db.getComment(1)
+	require.NoError(t, err)
+	assert.Equal(t, commentStatusApproved, c.Status)
+
+	// The website of form comments is no proof of identity, so commenters aren't approved automatically
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Second comment", "Name", "https://example.org", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	// Known ActivityPub actors are approved automatically
+	_, status, err = app.createComment(bc, "https://example.com/abc", "From the fediverse", "Actor", "https://social.example/@actor", "", "https://social.example/notes/1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	require.NoError(t, app.approveComment(bc, 3))
+
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Another note", "Actor", "https://social.example/@actor", "", "https://social.example/notes/2")
+	require.NoError(t, err)
+	assert.Equal(t, 0, status)
+
+	// Form comments using the website of the actor aren't
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Forged", "Actor", "https://social.example/@actor", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	// But rules still apply
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Visit my casino", "Actor", "https://social.example/@actor", "", "https://social.example/notes/3")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	_, status, err = app.createComment(bc, "https://example.com/abc", "https://a.example https://b.example", "Actor", "https://social.example/@actor", "", "https://social.example/notes/4")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	// Rules apply without moderation mode too
+	bc.Comments.Moderation = false
+
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Hello", "Other", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, status)
+
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Casino", "Other", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+
+}
+
+func Test_commentsPlainText(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	require.NoError(t, app.initConfig(false))
+	app.initMarkdown()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+
+	_, _, err := app.createComment(bc, "https://example.com/abc", "Some *Markdown*", "Name", "", "", "")
+	require.NoError(t, err)
+	_, _, err = app.createComment(bc, "https://example.com/abc", "Old comment", "Name", "", "", "")
+	require.NoError(t, err)
+
+	// Comments from before the Markdown support are marked by the migration
+	_, err = app.db.Exec("update comments set comment = ?, plaintext = 1 where id = 2", "*Not emphasized* <b>\nSecond line")
+	require.NoError(t, err)
+
+	comments, err := app.db.getComments(&commentsRequestConfig{ids: []int{1, 2}})
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, "<p>Some <em>Markdown</em></p>\n", app.renderCommentContent(comments[1]))
+	assert.Equal(t, "<p>*Not emphasized* &lt;b&gt;<br>Second line</p>", app.renderCommentContent(comments[0]))
+
+	// Edited comments are Markdown
+	require.NoError(t, app.db.updateComment(2, "*Emphasized*", "Name", "", commentStatusApproved))
+	c, err := app.db.getComment(2)
+	require.NoError(t, err)
+	assert.False(t, c.PlainText)
+	assert.Equal(t, "<p><em>Emphasized</em></p>\n", app.renderCommentContent(c))
+}
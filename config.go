@@ -27,9 +27,11 @@ type config struct {
 	PathRedirects []*configRegexRedirect `mapstructure:"pathRedirects"`
 	ActivityPub   *configActivityPub     `mapstructure:"activityPub"`
 	Webmention    *configWebmention      `mapstructure:"webmention"`
+	SpamFilter    *configSpamFilter      `mapstructure:"spamFilter"`
 	Notifications *configNotifications   `mapstructure:"notifications"`
 	PrivateMode   *configPrivateMode     `mapstructure:"privateMode"`
 	IndexNow      *configIndexNow        `mapstructure:"indexNow"`
+	WebSub        *configWebSub          `mapstructure:"webSub"`
 	EasterEgg     *configEasterEgg       `mapstructure:"easterEgg"`
 	MapTiles      *configMapTiles        `mapstructure:"mapTiles"`
 	TTS           *configTTS             `mapstructure:"tts"`
@@ -58,6 +60,7 @@ type configServer struct {
 	TorSingleHop        bool     `mapstructure:"torSingleHop"`
 	SecurityHeaders     bool     `mapstructure:"securityHeaders"`
 	CSPDomains          []string `mapstructure:"cspDomains"`
+	TrustProxy          bool     `mapstructure:"trustProxy"`
 	publicHostname      string
 	shortPublicHostname string
 	mediaHostname       string
@@ -120,7 +123,11 @@ type configSection struct {
 	PathTemplate string `mapstructure:"pathtemplate"`
 	ShowFull     bool   `mapstructure:"showFull"`
 	HideOnStart  bool   `mapstructure:"hideOnStart"`
-	Name         string
+	// Podcast feed
+	Podcast         bool   `mapstructure:"podcast"`
+	PodcastCategory string `mapstructure:"podcastCategory"`
+	PodcastEmail    bool   `mapstructure:"podcastEmail"`
+	Name            string
 }
 
 type configTaxonomy struct {
@@ -151,6 +158,7 @@ type configSearch struct {
 	Title       string `mapstructure:"title"`
 	Description string `mapstructure:"description"`
 	Placeholder string `mapstructure:"placeholder"`
+	Fuzzy       bool   `mapstructure:"fuzzy"`
 }
 
 type configBlogStats struct {
@@ -182,7 +190,11 @@ type configOnThisDay struct {
 }
 
 type configComments struct {
-	Enabled bool `mapstructure:"enabled"`
+	Enabled            bool     `mapstructure:"enabled"`
+	Moderation         bool     `mapstructure:"moderation"`
+	ModerationKeywords []string `mapstructure:"moderationKeywords"`
+	ModerationMaxLinks int      `mapstructure:"moderationMaxLinks"`
+	EditWindow         int      `mapstructure:"editWindow"`
 }
 
 type configGeoMap struct {
@@ -327,6 +339,11 @@ type configIndexNow struct {
 	Enabled bool `mapstructure:"enabled"`
 }
 
+type configWebSub struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Hub     string `mapstructure:"hub"`
+}
+
 type configEasterEgg struct {
 	Enabled bool `mapstructure:"enabled"`
 }
@@ -336,6 +353,12 @@ type configWebmention struct {
 	DisableReceiving bool `mapstructure:"disableReceiving"`
 }
 
+type configSpamFilter struct {
+	Enabled         bool    `mapstructure:"enabled"`
+	HoldThreshold   float64 `mapstructure:"holdThreshold"`
+	RejectThreshold float64 `mapstructure:"rejectThreshold"`
+}
+
 type configMapTiles struct {
 	Source      string `mapstructure:"source"`
 	Attribution string `mapstructure:"attribution"`
@@ -572,6 +595,16 @@ func (a *goBlog) initConfig(logging bool) error {
 			}
 		}
 	}
+	// Trigram index for fuzzy search
+	if err = a.db.setTrigramIndex(lo.SomeBy(lo.Values(a.cfg.Blogs), func(bc *configBlog) bool {
+		return bc.Search != nil && bc.Search.Enabled && bc.Search.Fuzzy
+	})); err != nil {
+		return err
+	}
+	// Stemmed index for the languages of the blogs
+	if err = a.updateStemmedSearchIndex(); err != nil {
+		return err
+	}
 	// Log success
 	a.cfg.initialized = true
 	log.Println("Initialized configuration")
@@ -55,21 +55,44 @@ func (a *goBlog) sendContactSubmission(w http.ResponseWriter, r *http.Request) {
 	}
 	// Add message text to message
 	_, _ = message.WriteString(formMessage)
-	// Send submission
-	go func() {
-		if err := a.sendContactEmail(bc.Contact, message.String(), formEmail); err != nil {
-			log.Println(err.Error())
-		}
-	}()
-	// Send notification
-	go a.sendNotification(message.String())
+	// Check for spam
+	switch a.checkSpam(message.String()) {
+	case spamVerdictReject:
+		a.serveError(w, r, "Message rejected as spam", http.StatusBadRequest)
+		return
+	case spamVerdictHold:
+		// Only save as notification
+		go a.sendNotification(spamNotificationPrefix + message.String())
+	default:
+		// Send submission
+		go func() {
+			if err := a.sendContactEmail(bc.Contact, message.String(), formEmail); err != nil {
+				log.Println(err.Error())
+			}
+		}()
+		// Send notification
+		go a.sendNotification(message.String())
+	}
 	// Give feedback
 	a.render(w, r, a.renderContactSent, &renderData{})
 }
 
-func (*goBlog) sendContactEmail(cc *configContact, body, replyTo string) error {
+func (a *goBlog) sendContactEmail(cc *configContact, body, replyTo string) error {
+	// Check required config
+	if cc == nil || cc.EmailTo == "" {
+		return fmt.Errorf("email not send as config is missing")
+	}
+	subject := cc.EmailSubject
+	if subject == "" {
+		subject = "New contact message"
+	}
+	return a.sendEmail(cc, cc.EmailTo, replyTo, subject, body, nil)
+}
+
+// Send an email using the SMTP settings of the contact form
+func (*goBlog) sendEmail(cc *configContact, to, replyTo, subject, body string, headers map[string]string) error {
 	// Check required config
-	if cc == nil || cc.SMTPHost == "" || cc.EmailFrom == "" || cc.EmailTo == "" {
+	if cc == nil || cc.SMTPHost == "" || cc.EmailFrom == "" || to == "" {
 		return fmt.Errorf("email not send as config is missing")
 	}
 	// Connect to SMTP
@@ -89,16 +112,15 @@ func (*goBlog) sendContactEmail(cc *configContact, body, replyTo string) error {
 	}
 	// Build email
 	msg := mail.NewMSG()
-	msg.AddTo(cc.EmailTo)
+	msg.AddTo(to)
 	msg.SetFrom(cc.EmailFrom)
 	if replyTo != "" {
 		msg.SetReplyTo(replyTo)
 	}
-	msg.SetDate(time.Now().UTC().Format("2006-01-02 15:04:05 MST"))
-	subject := cc.EmailSubject
-	if subject == "" {
-		subject = "New contact message"
+	for header, value := range headers {
+		msg.AddHeader(header, value)
 	}
+	msg.SetDate(time.Now().UTC().Format("2006-01-02 15:04:05 MST"))
 	msg.SetSubject(subject)
 	msg.SetBody(mail.TextPlain, body)
 	// Send mail
@@ -12,7 +12,9 @@ import (
 	"github.com/dgraph-io/ristretto"
 	"github.com/google/uuid"
 	sqlite "github.com/mattn/go-sqlite3"
+	"github.com/samber/lo"
 	"github.com/schollz/sqlite3dump"
+	"go.goblog.app/app/pkgs/builderpool"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -78,6 +80,7 @@ func (a *goBlog) openDatabase(file string, logging bool) (*database, error) {
 				"urlize":         urlize,
 				"lowerx":         strings.ToLower,
 				"lowerunescaped": lowerUnescapedPath,
+				"stem":           a.stemSearchText,
 			} {
 				if err := c.RegisterFunc(n, f, true); err != nil {
 					return err
@@ -282,6 +285,131 @@ func (db *database) QueryRowContext(c context.Context, query string, args ...any
 
 // Other things
 
-func (d *database) rebuildFTSIndex() {
-	_, _ = d.Exec("insert into posts_fts(posts_fts) values ('rebuild')")
+// Full-text search indexes, all are updated by triggers when posts or titles change
+const (
+	ftsIndexDefault = "posts_fts"
+	ftsIndexStemmed = "posts_fts_stemmed"
+	ftsIndexTrigram = "posts_fts_trigram" // optional, only exists if fuzzy search is enabled
+
+	ftsIndexSource = "select p.rowid as id, p.path as path, coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = p.path and pp.parameter = 'title'), '') as title, p.content as content from posts p"
+	// The stemmed index contains the words stemmed in the language of the blog of the post
+	ftsIndexStemmedSource = "select p.rowid as id, p.path as path, stem(coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = p.path and pp.parameter = 'title'), ''), p.blog) as title, stem(p.content, p.blog) as content from posts p"
+)
+
+func ftsIndexSourceFor(index string) string {
+	if index == ftsIndexStemmed {
+		return ftsIndexStemmedSource
+	}
+	return ftsIndexSource
+}
+
+// Get the existing full-text search indexes
+func (d *database) ftsIndexes() ([]string, error) {
+	rows, err := d.Query(
+		"select name from sqlite_master where type = 'table' and name in (@default, @stemmed, @trigram) order by name",
+		sql.Named("default", ftsIndexDefault), sql.Named("stemmed", ftsIndexStemmed), sql.Named("trigram", ftsIndexTrigram),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	indexes := []string{}
+	for rows.Next() {
+		var index string
+		if err = rows.Scan(&index); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+// Create or drop the trigram index
+func (d *database) setTrigramIndex(enabled bool) error {
+	indexes, err := d.ftsIndexes()
+	if err != nil {
+		return err
+	}
+	if exists := lo.Contains(indexes, ftsIndexTrigram); exists == enabled {
+		return nil
+	}
+	triggers := []string{"insert", "update", "delete", "title_insert", "title_update", "title_delete"}
+	sqlBuilder := builderpool.Get()
+	defer builderpool.Put(sqlBuilder)
+	sqlBuilder.WriteString("begin;")
+	if !enabled {
+		for _, trigger := range triggers {
+			sqlBuilder.WriteString("drop trigger if exists trigger_" + ftsIndexTrigram + "_" + trigger + ";")
+		}
+		sqlBuilder.WriteString("drop table if exists " + ftsIndexTrigram + ";")
+	} else {
+		title := func(path string) string {
+			return "coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = " + path + " and pp.parameter = 'title'), '')"
+		}
+		sqlBuilder.WriteString("create virtual table " + ftsIndexTrigram + " using fts5(path unindexed, title, content, tokenize = 'trigram');")
+		sqlBuilder.WriteString("insert into " + ftsIndexTrigram + " (rowid, path, title, content) " + ftsIndexSource + ";")
+		for i, body := range []string{
+			"after insert on posts begin insert into " + ftsIndexTrigram + " (rowid, path, title, content) values (new.rowid, new.path, " + title("new.path") + ", new.content); end;",
+			"after update of path, content on posts begin update " + ftsIndexTrigram + " set path = new.path, content = new.content where rowid = old.rowid; end;",
+			"after delete on posts begin delete from " + ftsIndexTrigram + " where rowid = old.rowid; end;",
+			"after insert on post_parameters when new.parameter = 'title' begin update " + ftsIndexTrigram + " set title = " + title("new.path") + " where rowid = (select rowid from posts where path = new.path); end;",
+			"after update on post_parameters when old.parameter = 'title' or new.parameter = 'title' begin update " + ftsIndexTrigram + " set title = " + title("new.path") + " where rowid = (select rowid from posts where path = new.path); end;",
+			"after delete on post_parameters when old.parameter = 'title' begin update " + ftsIndexTrigram + " set title = " + title("old.path") + " where rowid = (select rowid from posts where path = old.path); end;",
+		} {
+			sqlBuilder.WriteString("create trigger trigger_" + ftsIndexTrigram + "_" + triggers[i] + " " + body)
+		}
+	}
+	sqlBuilder.WriteString("commit;")
+	_, err = d.Exec(sqlBuilder.String(), dbNoCache)
+	return err
+}
+
+// The FTS indexes are updated by triggers, this rebuilds them completely
+func (d *database) rebuildFTSIndex() error {
+	indexes, err := d.ftsIndexes()
+	if err != nil {
+		return err
+	}
+	return d.rebuildFTSIndexes(indexes...)
+}
+
+func (d *database) rebuildFTSIndexes(indexes ...string) error {
+	sqlBuilder := builderpool.Get()
+	defer builderpool.Put(sqlBuilder)
+	sqlBuilder.WriteString("begin;")
+	for _, index := range indexes {
+		sqlBuilder.WriteString("delete from " + index + ";")
+		sqlBuilder.WriteString("insert into " + index + " (rowid, path, title, content) " + ftsIndexSourceFor(index) + ";")
+		sqlBuilder.WriteString("insert into " + index + "(" + index + ") values ('optimize');")
+	}
+	sqlBuilder.WriteString("commit;")
+	_, err := d.Exec(sqlBuilder.String(), dbNoCache)
+	return err
+}
+
+// Check the integrity of the FTS indexes and return the number of posts that are missing or outdated in them
+func (d *database) checkFTSIndex() (int, error) {
+	indexes, err := d.ftsIndexes()
+	if err != nil {
+		return 0, err
+	}
+	inconsistent := 0
+	for _, index := range indexes {
+		if _, err := d.Exec("insert into "+index+"("+index+", rank) values ('integrity-check', 1)", dbNoCache); err != nil {
+			return 0, err
+		}
+		row, err := d.QueryRow(`select
+		(select count(*) from (`+ftsIndexSourceFor(index)+`) p where not exists (
+			select 1 from `+index+` f where f.rowid = p.id and f.path = p.path and f.title = p.title and f.content = p.content
+		)) + (select count(*) from `+index+` f where not exists (select 1 from posts p where p.rowid = f.rowid))`, dbNoCache)
+		if err != nil {
+			return 0, err
+		}
+		var count int
+		if err = row.Scan(&count); err != nil {
+			return 0, err
+		}
+		inconsistent += count
+	}
+	return inconsistent, nil
 }
@@ -0,0 +1,3 @@
+create table loginevents (id integer primary key autoincrement, time text not null, event text not null, username text not null default '', ip text not null default '', useragent text not null default '');
+create index index_loginevents_time on loginevents (time);
+create table totprecoverycodes (hash text primary key);
\ No newline at end of file
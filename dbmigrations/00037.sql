@@ -0,0 +1 @@
+create table apppasswords (id integer primary key autoincrement, username text not null, hash text not null, scopes text not null default '', created text not null, lastused text not null default '', unique(username));
\ No newline at end of file
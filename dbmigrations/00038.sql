@@ -0,0 +1 @@
+alter table comments add parent integer not null default 0;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table comments add status text not null default "approved";
\ No newline at end of file
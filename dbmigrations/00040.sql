@@ -0,0 +1,3 @@
+create table spamtokens (token text not null primary key, spam integer not null default 0, ham integer not null default 0);
+create table spammessages (id integer primary key check (id = 1), spam integer not null default 0, ham integer not null default 0);
+insert into spammessages (id) values (1);
\ No newline at end of file
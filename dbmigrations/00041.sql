@@ -0,0 +1,2 @@
+alter table comments add email text not null default "";
+create table commentsubscriptions (id integer primary key autoincrement, target text not null, email text not null, token text not null unique, confirmed integer not null default 0, created text not null, unique(target, email));
\ No newline at end of file
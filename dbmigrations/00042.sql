@@ -0,0 +1 @@
+alter table comments add created text not null default "";
\ No newline at end of file
@@ -0,0 +1,2 @@
+alter table webmentions add authorphoto text not null default "";
+update webmentions set type = 'mention' where type is null or type = '';
\ No newline at end of file
@@ -0,0 +1 @@
+create table webmentionssent (id integer primary key autoincrement, path text not null, target text not null, endpoint text not null default '', status integer not null default 0, location text not null default '', error text not null default '', tries integer not null default 0, updated text not null, unique(path, target));
\ No newline at end of file
@@ -0,0 +1,3 @@
+alter table webmentions add private integer not null default 0;
+create table privatewebmentioncodes (code text primary key, path text not null, expires integer not null);
+create table privatewebmentiontokens (token text primary key, path text not null, expires integer not null);
\ No newline at end of file
@@ -0,0 +1,10 @@
+drop table posts_fts;
+drop view posts_fts_view;
+create virtual table posts_fts using fts5(path unindexed, title, content);
+insert into posts_fts (rowid, path, title, content) select p.rowid, p.path, coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = p.path and pp.parameter = 'title'), ''), p.content from posts p;
+create trigger trigger_posts_fts_insert after insert on posts begin insert into posts_fts (rowid, path, title, content) values (new.rowid, new.path, coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), ''), new.content); end;
+create trigger trigger_posts_fts_update after update of path, content on posts begin update posts_fts set path = new.path, content = new.content where rowid = old.rowid; end;
+create trigger trigger_posts_fts_delete after delete on posts begin delete from posts_fts where rowid = old.rowid; end;
+create trigger trigger_posts_fts_title_insert after insert on post_parameters when new.parameter = 'title' begin update posts_fts set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = new.path); end;
+create trigger trigger_posts_fts_title_update after update on post_parameters when old.parameter = 'title' or new.parameter = 'title' begin update posts_fts set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = new.path); end;
+create trigger trigger_posts_fts_title_delete after delete on post_parameters when old.parameter = 'title' begin update posts_fts set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = old.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = old.path); end;
\ No newline at end of file
@@ -0,0 +1,15 @@
+create virtual table posts_fts_stemmed using fts5(path unindexed, title, content, tokenize = 'porter unicode61 remove_diacritics 2');
+insert into posts_fts_stemmed (rowid, path, title, content) select p.rowid, p.path, coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = p.path and pp.parameter = 'title'), ''), p.content from posts p;
+create virtual table posts_fts_vocab using fts5vocab(posts_fts, row);
+drop trigger trigger_posts_fts_insert;
+drop trigger trigger_posts_fts_update;
+drop trigger trigger_posts_fts_delete;
+drop trigger trigger_posts_fts_title_insert;
+drop trigger trigger_posts_fts_title_update;
+drop trigger trigger_posts_fts_title_delete;
+create trigger trigger_posts_fts_insert after insert on posts begin insert into posts_fts (rowid, path, title, content) values (new.rowid, new.path, coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), ''), new.content); insert into posts_fts_stemmed (rowid, path, title, content) values (new.rowid, new.path, coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), ''), new.content); end;
+create trigger trigger_posts_fts_update after update of path, content on posts begin update posts_fts set path = new.path, content = new.content where rowid = old.rowid; update posts_fts_stemmed set path = new.path, content = new.content where rowid = old.rowid; end;
+create trigger trigger_posts_fts_delete after delete on posts begin delete from posts_fts where rowid = old.rowid; delete from posts_fts_stemmed where rowid = old.rowid; end;
+create trigger trigger_posts_fts_title_insert after insert on post_parameters when new.parameter = 'title' begin update posts_fts set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = new.path); update posts_fts_stemmed set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = new.path); end;
+create trigger trigger_posts_fts_title_update after update on post_parameters when old.parameter = 'title' or new.parameter = 'title' begin update posts_fts set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = new.path); update posts_fts_stemmed set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = new.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = new.path); end;
+create trigger trigger_posts_fts_title_delete after delete on post_parameters when old.parameter = 'title' begin update posts_fts set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = old.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = old.path); update posts_fts_stemmed set title = coalesce((select group_concat(pp.value, ' ') from post_parameters pp where pp.path = old.path and pp.parameter = 'title'), '') where rowid = (select rowid from posts where path = old.path); end;
\ No newline at end of file
@@ -0,0 +1,2 @@
+create table websubsubscriptions (id integer primary key autoincrement, topic text not null, callback text not null, secret text not null default '', expires integer not null, unique(topic, callback));
+create index index_websubsubscriptions_topic on websubsubscriptions (topic);
\ No newline at end of file
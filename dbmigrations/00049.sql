@@ -0,0 +1,2 @@
+alter table sections add podcast boolean not null default false;
+alter table sections add podcastcategory text not null default '';
\ No newline at end of file
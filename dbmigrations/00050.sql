@@ -0,0 +1 @@
+alter table sections add podcastemail boolean not null default false;
\ No newline at end of file
@@ -0,0 +1,2 @@
+alter table comments add plaintext boolean not null default false;
+update comments set plaintext = 1;
\ No newline at end of file
@@ -0,0 +1 @@
+create table spamtrained (item text not null primary key, hash text not null, spam boolean not null);
\ No newline at end of file
@@ -133,6 +133,22 @@ func (a *goBlog) serveEditorPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		http.Redirect(w, r, post.Path, http.StatusFound)
+	case "sendwebmentions":
+		parsedURL, err := url.Parse(r.FormValue("url"))
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		post, err := a.getPost(parsedURL.Path)
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = a.sendWebmentions(post); err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, post.Path, http.StatusFound)
 	case "helpgpx":
 		file, _, err := r.FormFile("file")
 		if err != nil {
@@ -1,8 +1,23 @@
 package main
 
 import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/samber/lo"
+	"go.goblog.app/app/pkgs/builderpool"
 )
 
 func (a *goBlog) exportMarkdownFiles(dir string) error {
@@ -25,3 +40,318 @@ func (a *goBlog) exportMarkdownFiles(dir string) error {
 	}
 	return nil
 }
+
+const (
+	fullExportVersion      = 1
+	fullExportDataFile     = "goblog.json"
+	fullExportMarkdownDir  = "markdown"
+	fullExportMediaDir     = "media"
+	fullExportProfileImage = "profileImage"
+	fullExportBlobKey      = "base64"
+)
+
+// Tables of the full export in the order of the restore,
+// sessions, tokens, regenerable caches and queues aren't exported
+var fullExportTables = []string{
+	"posts", "post_parameters", "shortpath", "deleted", "reactions",
+	"comments", "commentsubscriptions", "webmentions", "webmentionssent",
+	"activitypub_followers", "websubsubscriptions", "notifications",
+	"sections", "settings", "apppasswords", "totprecoverycodes",
+	"spamtokens", "spammessages", "spamtrained", "persistent_cache",
+}
+
+// Tables that are already filled on startup or by migrations and replaced on restore
+var fullExportReplacedTables = []string{"sections", "settings", "spammessages"}
+
+// Keys and key prefixes of the persistent cache that can't be regenerated,
+// existing entries (like keys generated on startup) are overwritten on restore
+var (
+	fullExportCacheKeys     = []string{activityPubKeyCacheKey, indexNowKeyCacheKey, commentEditSecretCacheKey}
+	fullExportCachePrefixes = []string{mentionAvatarCachePrefix, editorStateCacheKey}
+)
+
+type fullExportData struct {
+	Version int                         `json:"version"`
+	Created string                      `json:"created"`
+	Tables  map[string][]map[string]any `json:"tables"`
+}
+
+// Export the database, the posts as Markdown, the media files and the profile image to a single zip archive
+func (a *goBlog) exportFull(file string) (err error) {
+	file = defaultIfEmpty(file, "export.zip")
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	zw := zip.NewWriter(f)
+	// Database
+	data := &fullExportData{
+		Version: fullExportVersion,
+		Created: utcNowString(),
+		Tables:  map[string][]map[string]any{},
+	}
+	for _, table := range fullExportTables {
+		query, args := fullExportQuery(table)
+		if data.Tables[table], err = a.db.exportTable(query, args...); err != nil {
+			return fmt.Errorf("failed to export table %s: %w", table, err)
+		}
+	}
+	w, err := zw.Create(fullExportDataFile)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(w).Encode(data); err != nil {
+		return err
+	}
+	// Posts as Markdown
+	posts, err := a.getPosts(&postsRequestConfig{withoutRenderedTitle: true})
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		if w, err = zw.Create(path.Join(fullExportMarkdownDir, p.Path+".md")); err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, p.contentWithParams()); err != nil {
+			return err
+		}
+	}
+	// Media files
+	if a.mediaStorageEnabled() {
+		files, err := a.mediaFiles()
+		if err != nil {
+			return err
+		}
+		for _, mf := range files {
+			if w, err = zw.Create(path.Join(fullExportMediaDir, mf.Name)); err != nil {
+				return err
+			}
+			if err = a.copyMediaFile(w, mf); err != nil {
+				return fmt.Errorf("failed to export media file %s: %w", mf.Name, err)
+			}
+		}
+	}
+	// Profile image
+	if pi, err := os.Open(profileImageFile); err == nil {
+		defer pi.Close()
+		if w, err = zw.Create(fullExportProfileImage); err != nil {
+			return err
+		}
+		if _, err = io.Copy(w, pi); err != nil {
+			return err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	log.Printf("Exported %d posts to %s", len(posts), file)
+	return nil
+}
+
+func (a *goBlog) copyMediaFile(w io.Writer, mf *mediaFile) error {
+	if local, ok := a.mediaStorage.(*localMediaStorage); ok {
+		f, err := os.Open(filepath.Join(local.path, mf.Name))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	}
+	return requests.URL(a.getFullAddress(mf.Location)).Client(a.httpClient).ToWriter(w).Fetch(context.Background())
+}
+
+// Restore a full export into an empty database
+func (a *goBlog) importFull(file string) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	// Read database export
+	dataFile, err := zr.Open(fullExportDataFile)
+	if err != nil {
+		return fmt.Errorf("no full export: %w", err)
+	}
+	data := &fullExportData{}
+	decoder := json.NewDecoder(dataFile)
+	decoder.UseNumber()
+	err = decoder.Decode(data)
+	_ = dataFile.Close()
+	if err != nil {
+		return err
+	}
+	if data.Version != fullExportVersion {
+		return fmt.Errorf("unsupported export version %d", data.Version)
+	}
+	// Only restore into an empty database
+	for _, table := range fullExportTables {
+		if lo.Contains(fullExportReplacedTables, table) || table == "persistent_cache" {
+			continue
+		}
+		var count int
+		row, err := a.db.QueryRow("select count(*) from " + table)
+		if err != nil {
+			return err
+		}
+		if err = row.Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("database is not empty, table " + table + " has entries")
+		}
+	}
+	// Restore database in a single transaction
+	sqlBuilder := builderpool.Get()
+	defer builderpool.Put(sqlBuilder)
+	var sqlArgs []any
+	sqlBuilder.WriteString("begin;")
+	for _, table := range fullExportReplacedTables {
+		sqlBuilder.WriteString("delete from " + table + ";")
+	}
+	for _, table := range fullExportTables {
+		columns, err := a.db.tableColumns(table)
+		if err != nil {
+			return err
+		}
+		for _, row := range data.Tables[table] {
+			// Only use known columns, the names are used in the query
+			rowColumns := lo.Filter(lo.Keys(row), func(c string, _ int) bool { return lo.Contains(columns, c) })
+			if len(rowColumns) == 0 {
+				continue
+			}
+			sort.Strings(rowColumns)
+			sqlBuilder.WriteString(lo.Ternary(table == "persistent_cache", "insert or replace", "insert or rollback"))
+			sqlBuilder.WriteString(" into " + table + " (" + strings.Join(rowColumns, ", ") + ") values (")
+			sqlBuilder.WriteString(strings.TrimSuffix(strings.Repeat("?, ", len(rowColumns)), ", "))
+			sqlBuilder.WriteString(");")
+			for _, c := range rowColumns {
+				sqlArgs = append(sqlArgs, fullExportValue(row[c]))
+			}
+		}
+	}
+	sqlBuilder.WriteString("commit;")
+	if _, err = a.db.Exec(sqlBuilder.String(), append([]any{dbNoCache}, sqlArgs...)...); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	// Restore media files and profile image
+	mediaCount := 0
+	for _, f := range zr.File {
+		if name, isMedia := strings.CutPrefix(f.Name, fullExportMediaDir+"/"); isMedia && !f.FileInfo().IsDir() {
+			if !isSafeMediaFileName(name) {
+				return fmt.Errorf("invalid media file name %q", name)
+			}
+			if err = a.importFullFile(f, func(r io.Reader) error {
+				_, err := a.saveMediaFile(name, r)
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to restore media file %s: %w", name, err)
+			}
+			mediaCount++
+		} else if f.Name == fullExportProfileImage {
+			if err = a.importFullFile(f, func(r io.Reader) error {
+				return saveToFile(r, profileImageFile)
+			}); err != nil {
+				return fmt.Errorf("failed to restore profile image: %w", err)
+			}
+		}
+	}
+	log.Printf("Restored %d posts and %d media files from %s", len(data.Tables["posts"]), mediaCount, file)
+	return nil
+}
+
+func (a *goBlog) importFullFile(f *zip.File, save func(r io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return save(rc)
+}
+
+// Media files are saved flat, names with paths could escape the media directory
+func isSafeMediaFileName(name string) bool {
+	return name != "" && name != "." && !strings.ContainsAny(name, "/\\") && !strings.Contains(name, "..")
+}
+
+func fullExportQuery(table string) (string, []any) {
+	if table != "persistent_cache" {
+		return "select * from " + table, nil
+	}
+	var args []any
+	conditions := []string{"key in (" + strings.TrimSuffix(strings.Repeat("?, ", len(fullExportCacheKeys)), ", ") + ")"}
+	for _, key := range fullExportCacheKeys {
+		args = append(args, key)
+	}
+	for _, prefix := range fullExportCachePrefixes {
+		conditions = append(conditions, "substr(key, 1, ?) = ?")
+		args = append(args, len(prefix), prefix)
+	}
+	return "select * from persistent_cache where " + strings.Join(conditions, " or "), args
+}
+
+// Blobs are exported as base64, JSON numbers are restored as integers if possible
+func fullExportValue(v any) any {
+	switch tv := v.(type) {
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return i
+		}
+		f, _ := tv.Float64()
+		return f
+	case map[string]any:
+		if b64, ok := tv[fullExportBlobKey].(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(b64); err == nil {
+				return b
+			}
+		}
+	}
+	return v
+}
+
+func (db *database) exportTable(query string, args ...any) ([]map[string]any, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := map[string]any{}
+		for i, c := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[c] = map[string]string{fullExportBlobKey: base64.StdEncoding.EncodeToString(b)}
+			} else {
+				row[c] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func (db *database) tableColumns(table string) ([]string, error) {
+	rows, err := db.Query("select * from " + table + " limit 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
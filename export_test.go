@@ -1,9 +1,13 @@
 package main
 
 import (
+	"archive/zip"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,3 +50,102 @@ func Test_export(t *testing.T) {
 	assert.Contains(t, fileContent, `ABC`)
 
 }
+
+func Test_exportFull(t *testing.T) {
+	newApp := func() *goBlog {
+		app := &goBlog{
+			cfg: createDefaultTestConfig(t),
+		}
+		require.NoError(t, app.initConfig(false))
+		app.initMarkdown()
+		app.mediaStorageInit.Do(func() {})
+		app.mediaStorage = &localMediaStorage{path: t.TempDir()}
+		return app
+	}
+
+	app := newApp()
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+
+	require.NoError(t, app.createPost(&post{Path: "/posts/exported", Section: "posts", Content: "Exported post", Parameters: map[string][]string{"title": {"Exported"}}}))
+	_, _, err := app.createComment(bc, "http://localhost:8080/posts/exported", "A comment", "Commenter", "", "", "")
+	require.NoError(t, err)
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://example.com/reply", Target: "http://localhost:8080/posts/exported", Content: "A reply", Created: time.Now().Unix()}, webmentionStatusApproved))
+	require.NoError(t, app.saveSection(app.cfg.DefaultBlog, &configSection{Name: "photos", Title: "Photos"}))
+	_, err = app.saveMediaFile("image.jpg", strings.NewReader("image"))
+	require.NoError(t, err)
+	avatar := []byte{0xff, 0xd8, 0xff, 0x00, 0x80, 0xfe}
+	require.NoError(t, app.db.cachePersistently(mentionAvatarCachePrefix+"abc", avatar))
+	require.NoError(t, app.db.cachePersistently("blogstats_default", []byte("stats")))
+	require.NoError(t, app.loadActivityPubPrivateKey())
+	codes, err := app.db.generateTOTPRecoveryCodes()
+	require.NoError(t, err)
+
+	exportFile := filepath.Join(t.TempDir(), "export.zip")
+	require.NoError(t, app.exportFull(exportFile))
+	require.FileExists(t, exportFile)
+
+	// Restore into a new instance, that already generated an own key
+	restored := newApp()
+	require.NoError(t, restored.loadActivityPubPrivateKey())
+	require.NoError(t, restored.importFull(exportFile))
+
+	p, err := restored.getPost("/posts/exported")
+	require.NoError(t, err)
+	assert.Equal(t, "Exported post", p.Content)
+	assert.Equal(t, "Exported", p.Title())
+
+	comments, err := restored.db.getComments(&commentsRequestConfig{})
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "A comment", comments[0].Comment)
+
+	mentions, err := restored.db.getWebmentions(&webmentionsRequestConfig{})
+	require.NoError(t, err)
+	require.Len(t, mentions, 1)
+	assert.Equal(t, "A reply", mentions[0].Content)
+
+	require.NoError(t, restored.loadSections())
+	assert.Contains(t, restored.cfg.Blogs[restored.cfg.DefaultBlog].Sections, "photos")
+
+	files, err := restored.mediaFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "image.jpg", files[0].Name)
+
+	restoredAvatar, err := restored.db.retrievePersistentCache(mentionAvatarCachePrefix + "abc")
+	require.NoError(t, err)
+	assert.Equal(t, avatar, restoredAvatar)
+
+	stats, err := restored.db.retrievePersistentCache("blogstats_default")
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+
+	restored.apPrivateKey = nil
+	require.NoError(t, restored.loadActivityPubPrivateKey())
+	assert.True(t, app.apPrivateKey.Equal(restored.apPrivateKey))
+
+	assert.True(t, restored.db.useTOTPRecoveryCode(codes[0]))
+
+	// The search index is updated
+	count, err := restored.db.countPosts(&postsRequestConfig{search: "Exported"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Only restore into an empty database
+	assert.ErrorContains(t, restored.importFull(exportFile), "not empty")
+
+	// Media file names with paths are rejected
+	unsafeFile := filepath.Join(t.TempDir(), "unsafe.zip")
+	f, err := os.Create(unsafeFile)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(fullExportDataFile)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(w, `{"version":%d,"tables":{}}`, fullExportVersion)
+	require.NoError(t, err)
+	_, err = zw.Create(fullExportMediaDir + "/..")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+	assert.ErrorContains(t, newApp().importFull(unsafeFile), "invalid media file name")
+}
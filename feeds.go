@@ -1,12 +1,17 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/araddon/dateparse"
 	"github.com/jlelse/feeds"
+	"github.com/samber/lo"
 	"go.goblog.app/app/pkgs/bufferpool"
 	"go.goblog.app/app/pkgs/contenttype"
 )
@@ -23,23 +28,18 @@ const (
 	minJsonFeed feedType = "min.json"
 )
 
-func (a *goBlog) generateFeed(blog string, f feedType, w http.ResponseWriter, r *http.Request, posts []*post, title, description, path, query string) {
-	now := time.Now()
-	title = a.renderMdTitle(defaultIfEmpty(title, a.cfg.Blogs[blog].Title))
-	description = defaultIfEmpty(description, a.cfg.Blogs[blog].Description)
-	feed := &feeds.Feed{
-		Title:       title,
-		Description: description,
-		Link:        &feeds.Link{Href: a.getFullAddress(path) + query},
-		Created:     now,
-		Author: &feeds.Author{
-			Name:  a.cfg.User.Name,
-			Email: a.cfg.User.Email,
-		},
-		Image: &feeds.Image{
-			Url: a.profileImagePath(profileImageFormatJPEG, 0, 0),
-		},
-	}
+// Additional feed links (WebSub and RFC 5005 paging) and whether the feed is an archive document
+type feedPaging struct {
+	links   []*feeds.AtomLink
+	archive bool
+}
+
+const feedHistoryNamespace = "http://purl.org/syndication/history/1.0"
+
+func (a *goBlog) generateFeed(blog string, f feedType, w http.ResponseWriter, r *http.Request, posts []*post, title, description, path, query string, section *configSection, paging *feedPaging) {
+	podcast := section != nil && section.Podcast
+	var podcastAudios []*podcastAudio
+	items := make([]*feeds.Item, 0, len(posts))
 	for _, p := range posts {
 		buf := bufferpool.Get()
 		switch f {
@@ -48,7 +48,7 @@ func (a *goBlog) generateFeed(blog string, f feedType, w http.ResponseWriter, r
 		default:
 			a.feedHtml(buf, p)
 		}
-		feed.Add(&feeds.Item{
+		item := &feeds.Item{
 			Title:       p.RenderedTitle,
 			Link:        &feeds.Link{Href: a.fullPostURL(p)},
 			Description: a.postSummary(p),
@@ -57,9 +57,65 @@ func (a *goBlog) generateFeed(blog string, f feedType, w http.ResponseWriter, r
 			Created:     noError(dateparse.ParseLocal(p.Published)),
 			Updated:     noError(dateparse.ParseLocal(p.Updated)),
 			Tags:        sortedStrings(p.Parameters[a.cfg.Micropub.CategoryParam]),
-		})
+		}
+		if podcast {
+			podcastAudios = append(podcastAudios, a.addPodcastEnclosure(item, p))
+		}
+		items = append(items, item)
 		bufferpool.Put(buf)
 	}
+	if a.webSubEnabled() {
+		// Add WebSub discovery links, only post feeds are published to the hub
+		hub, self := a.webSubHubURL(), a.getFullAddress(r.URL.Path)+query
+		a.setWebSubLinkHeaders(w, self)
+		webSubLinks := []*feeds.AtomLink{{Href: hub, Rel: "hub"}, {Href: self, Rel: "self"}}
+		if paging == nil {
+			paging = &feedPaging{}
+		}
+		paging = &feedPaging{links: append(webSubLinks, paging.links...), archive: paging.archive}
+	}
+	var rssWriter feedRssWriter
+	if podcast {
+		rssWriter = func(feed *feeds.Feed, links []*feeds.AtomLink, archive bool) func(w io.Writer) error {
+			return a.podcastRss(feed, section, posts, podcastAudios, links, archive)
+		}
+	}
+	a.serveFeed(blog, f, w, r, items, title, description, path, query, paging, rssWriter)
+}
+
+// Custom writer for RSS feeds, for example for podcasts
+type feedRssWriter func(feed *feeds.Feed, links []*feeds.AtomLink, archive bool) func(w io.Writer) error
+
+// Serve a feed of the items, also used for feeds that don't contain posts
+func (a *goBlog) serveFeed(blog string, f feedType, w http.ResponseWriter, r *http.Request, items []*feeds.Item, title, description, path, query string, paging *feedPaging, rssWriter feedRssWriter) {
+	// The feed is as old as the newest item, so the output (and the ETag) only changes when the content changes
+	var created time.Time
+	for _, item := range items {
+		for _, t := range []time.Time{item.Created, item.Updated} {
+			if t.After(created) {
+				created = t
+			}
+		}
+	}
+	if created.IsZero() {
+		created = time.Now()
+	}
+	title = a.renderMdTitle(defaultIfEmpty(title, a.cfg.Blogs[blog].Title))
+	description = defaultIfEmpty(description, a.cfg.Blogs[blog].Description)
+	feed := &feeds.Feed{
+		Title:       title,
+		Description: description,
+		Link:        &feeds.Link{Href: a.getFullAddress(path) + query},
+		Created:     created,
+		Author: &feeds.Author{
+			Name:  a.cfg.User.Name,
+			Email: a.cfg.User.Email,
+		},
+		Image: &feeds.Image{
+			Url: a.profileImagePath(profileImageFormatJPEG, 0, 0),
+		},
+		Items: items,
+	}
 	var feedWriteFunc func(w io.Writer) error
 	var feedMediaType string
 	switch f {
@@ -76,10 +132,140 @@ func (a *goBlog) generateFeed(blog string, f feedType, w http.ResponseWriter, r
 		a.serve404(w, r)
 		return
 	}
+	var links []*feeds.AtomLink
+	archive := false
+	if paging != nil {
+		links, archive = paging.links, paging.archive
+	}
+	if len(links) > 0 || archive {
+		feedWriteFunc = feedWithLinks(feed, f, links, archive)
+	}
+	if rssWriter != nil && (f == rssFeed || f == minRssFeed) {
+		feedWriteFunc = rssWriter(feed, links, archive)
+	}
+	// Generate the feed completely to support conditional requests
+	buf := bufferpool.Get()
+	defer bufferpool.Put(buf)
 	pipeReader, pipeWriter := io.Pipe()
 	go func() {
 		_ = pipeWriter.CloseWithError(feedWriteFunc(pipeWriter))
 	}()
+	err := a.min.Get().Minify(feedMediaType, buf, pipeReader)
+	_ = pipeReader.CloseWithError(err)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Only use the content hash, feeds also change without newer posts (deleted posts, config changes etc.)
+	eTag := fmt.Sprintf("%x", sha256.Sum256(buf.Bytes()))
+	w.Header().Set("ETag", eTag)
 	w.Header().Set(contentType, feedMediaType+contenttype.CharsetUtf8Suffix)
-	_ = pipeReader.CloseWithError(a.min.Get().Minify(feedMediaType, w, pipeReader))
+	if notModified(r, eTag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = buf.WriteTo(w)
+}
+
+// The feeds library only supports a single link per feed, so wrap the feeds to add the WebSub and paging links
+
+type rssFeedWithLinks struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	AtomNamespace    string   `xml:"xmlns:atom,attr"`
+	HistoryNamespace string   `xml:"xmlns:fh,attr,omitempty"`
+	Channel          *rssChannelWithLinks
+}
+
+type rssChannelWithLinks struct {
+	XMLName xml.Name `xml:"channel"`
+	*feeds.RssFeed
+	Links   []*rssAtomLink
+	Archive *feedArchiveMarker
+}
+
+type rssAtomLink struct {
+	XMLName xml.Name `xml:"atom:link"`
+	Href    string   `xml:"href,attr"`
+	Rel     string   `xml:"rel,attr"`
+}
+
+func rssAtomLinks(links []*feeds.AtomLink) []*rssAtomLink {
+	return lo.Map(links, func(l *feeds.AtomLink, _ int) *rssAtomLink {
+		return &rssAtomLink{Href: l.Href, Rel: l.Rel}
+	})
+}
+
+// Marks archive documents that never change (RFC 5005)
+type feedArchiveMarker struct {
+	XMLName xml.Name `xml:"fh:archive"`
+}
+
+func newFeedArchiveMarker(archive bool) (string, *feedArchiveMarker) {
+	if !archive {
+		return "", nil
+	}
+	return feedHistoryNamespace, &feedArchiveMarker{}
+}
+
+func (r *rssFeedWithLinks) FeedXml() any {
+	return r
+}
+
+type atomFeedWithLinks struct {
+	XMLName          xml.Name `xml:"feed"`
+	HistoryNamespace string   `xml:"xmlns:fh,attr,omitempty"`
+	*feeds.AtomFeed
+	Links   []*feeds.AtomLink
+	Archive *feedArchiveMarker
+}
+
+func (a *atomFeedWithLinks) FeedXml() any {
+	return a
+}
+
+func feedWithLinks(feed *feeds.Feed, f feedType, links []*feeds.AtomLink, archive bool) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		historyNamespace, archiveMarker := newFeedArchiveMarker(archive)
+		switch f {
+		case rssFeed, minRssFeed:
+			return feeds.WriteXML(&rssFeedWithLinks{
+				Version:          "2.0",
+				ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+				AtomNamespace:    "http://www.w3.org/2005/Atom",
+				HistoryNamespace: historyNamespace,
+				Channel: &rssChannelWithLinks{
+					RssFeed: (&feeds.Rss{Feed: feed}).RssFeed(),
+					Links:   rssAtomLinks(links),
+					Archive: archiveMarker,
+				},
+			}, w)
+		case atomFeed, minAtomFeed:
+			return feeds.WriteXML(&atomFeedWithLinks{
+				HistoryNamespace: historyNamespace,
+				AtomFeed:         (&feeds.Atom{Feed: feed}).AtomFeed(),
+				Links:            links,
+				Archive:          archiveMarker,
+			}, w)
+		default:
+			jf := (&feeds.JSON{Feed: feed}).JSONFeed()
+			for _, l := range links {
+				switch l.Rel {
+				case "self":
+					jf.FeedUrl = l.Href
+				case "hub":
+					jf.Hubs = append(jf.Hubs, &feeds.JSONHub{Type: "WebSub", Url: l.Href})
+				case "next":
+					jf.NextUrl = l.Href
+				case "prev-archive":
+					// JSON Feed only knows the next URL, use it to go back in time from the subscription feed
+					if jf.NextUrl == "" {
+						jf.NextUrl = l.Href
+					}
+				}
+			}
+			return json.NewEncoder(w).Encode(jf)
+		}
+	}
 }
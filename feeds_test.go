@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/carlmjohnson/requests"
@@ -56,3 +58,108 @@ func Test_feeds(t *testing.T) {
 		}
 	}
 }
+
+func Test_feedPaging(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+
+	require.NoError(t, app.initConfig(false))
+	app.initMarkdown()
+	require.NoError(t, app.initTemplateStrings())
+	require.NoError(t, app.initCache())
+	app.initSessions()
+	app.cfg.Blogs[app.cfg.DefaultBlog].Pagination = 2
+
+	app.d = app.buildRouter()
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, app.createPost(&post{
+			Path:      fmt.Sprintf("/posts/%d", i),
+			Section:   "posts",
+			Published: fmt.Sprintf("2020-01-0%dT00:00:00Z", i),
+			Content:   fmt.Sprintf("Post %d", i),
+		}))
+	}
+
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Subscription feed", func(t *testing.T) {
+		rec := get("/posts.atom", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, `<link href="http://localhost:8080/posts.atom" rel="first"`)
+		assert.Contains(t, body, `<link href="http://localhost:8080/posts/page/2.atom" rel="next"`)
+		assert.Contains(t, body, `<link href="http://localhost:8080/posts/page/3.atom" rel="last"`)
+		assert.Contains(t, body, `<link href="http://localhost:8080/posts/archive/2.atom" rel="prev-archive"`)
+		assert.NotContains(t, body, "fh:archive")
+
+		rec = get("/posts.json", nil)
+		assert.Contains(t, rec.Body.String(), `"next_url":"http://localhost:8080/posts/page/2.json"`)
+	})
+
+	t.Run("Paged feed", func(t *testing.T) {
+		rec := get("/posts/page/2.rss", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, `<atom:link href="http://localhost:8080/posts.rss" rel="previous"`)
+		assert.Contains(t, body, `<atom:link href="http://localhost:8080/posts/page/3.rss" rel="next"`)
+		assert.NotContains(t, body, "prev-archive")
+		assert.Contains(t, body, "/posts/3")
+		assert.Contains(t, body, "/posts/2")
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		rec := get("/posts/archive/1.atom", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, `xmlns:fh="http://purl.org/syndication/history/1.0"`)
+		assert.Contains(t, body, "<fh:archive")
+		assert.Contains(t, body, `<link href="http://localhost:8080/posts.atom" rel="current"`)
+		assert.Contains(t, body, `<link href="http://localhost:8080/posts/archive/2.atom" rel="next-archive"`)
+		assert.NotContains(t, body, `rel="prev-archive"`)
+		// The oldest posts
+		assert.Contains(t, body, "/posts/1")
+		assert.Contains(t, body, "/posts/2")
+		assert.NotContains(t, body, "/posts/3")
+		// Only the ETag for conditional requests
+		assert.NotEmpty(t, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Header().Get("Last-Modified"))
+
+		rec = get("/posts/archive/2.rss", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<atom:link href="http://localhost:8080/posts/archive/1.rss" rel="prev-archive"`)
+
+		// Incomplete pages are not archived
+		assert.Equal(t, http.StatusNotFound, get("/posts/archive/3.rss", nil).Code)
+		assert.Equal(t, http.StatusNotFound, get("/posts/archive/0.rss", nil).Code)
+	})
+
+	t.Run("Conditional requests", func(t *testing.T) {
+		for _, f := range []feedType{rssFeed, atomFeed, jsonFeed, minRssFeed} {
+			rec := get("/posts."+string(f), nil)
+			require.Equal(t, http.StatusOK, rec.Code)
+			eTag := rec.Header().Get("ETag")
+			require.NotEmpty(t, eTag)
+			assert.Empty(t, rec.Header().Get("Last-Modified"))
+
+			assert.Equal(t, http.StatusNotModified, get("/posts."+string(f), map[string]string{"If-None-Match": `"` + eTag + `"`}).Code)
+			assert.Equal(t, http.StatusOK, get("/posts."+string(f), map[string]string{"If-None-Match": `"abc"`}).Code)
+			// Only the ETag is used, dates can't detect deleted posts
+			assert.Equal(t, http.StatusOK, get("/posts."+string(f), map[string]string{"If-Modified-Since": "Sun, 05 Jan 2030 00:00:00 GMT"}).Code)
+		}
+
+		// Deleting an older post changes the ETag
+		eTag := get("/posts.rss", nil).Header().Get("ETag")
+		require.NoError(t, app.deletePost("/posts/1"))
+		assert.Equal(t, http.StatusOK, get("/posts.rss", map[string]string{"If-None-Match": `"` + eTag + `"`}).Code)
+	})
+}
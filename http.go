@@ -17,6 +17,7 @@ import (
 	"github.com/justinas/alice"
 	"github.com/klauspost/compress/flate"
 	"github.com/samber/lo"
+	"go.goblog.app/app/pkgs/bodylimit"
 	"go.goblog.app/app/pkgs/httpcompress"
 	"go.goblog.app/app/pkgs/maprouter"
 	"go.goblog.app/app/pkgs/plugintypes"
@@ -132,6 +133,7 @@ func (*goBlog) redirectToHttps(w http.ResponseWriter, r *http.Request) {
 
 const (
 	paginationPath = "/page/{page:[0-9-]+}"
+	archivePath    = "/archive/{archive:[0-9]+}"
 	feedPath       = ".{feed:(rss|json|atom|min\\.rss|min\\.json|min\\.atom)}"
 )
 
@@ -201,6 +203,7 @@ func (a *goBlog) buildRouter() http.Handler {
 
 	// Notifications
 	r.Route(notificationsPath, a.notificationsRouter)
+	r.With(a.authMiddleware).Get(notificationsPath+feedPath, a.serveNotificationsFeed)
 
 	// Assets
 	r.Group(a.assetsRouter)
@@ -235,6 +238,11 @@ func (a *goBlog) buildRouter() http.Handler {
 		}
 	}
 
+	// WebSub
+	if a.webSubBuiltinHub() {
+		r.With(bodylimit.BodyLimit(100*bodylimit.KB)).Post(webSubPath, a.serveWebSubHub)
+	}
+
 	// Robots.txt
 	r.With(cacheLoggedIn, a.cacheMiddleware).Get(robotsTXTPath, a.serveRobotsTXT)
 
@@ -304,6 +312,12 @@ func (a *goBlog) servePostsAliasesRedirects() http.HandlerFunc {
 					case visibilityPublic, visibilityUnlisted:
 						alicePrivate.Append(a.checkActivityStreamsRequest, a.cacheMiddleware).ThenFunc(a.servePost).ServeHTTP(w, r)
 					default: // private, etc.
+						// Receivers of private webmentions can read the post with an access token
+						if a.checkPrivateWebmentionToken(r) {
+							a.servePost(w, r)
+							return
+						}
+						w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", a.getFullAddress(indieAuthPath+indieAuthTokenSubpath), "token_endpoint"))
 						alice.New(a.authMiddleware).ThenFunc(a.servePost).ServeHTTP(w, r)
 					}
 					return
@@ -25,7 +25,8 @@ func (a *goBlog) initHTTPLog() (err error) {
 func (a *goBlog) logMiddleware(next http.Handler) http.Handler {
 	h := handlers.CombinedLoggingHandler(a.logf, next)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Remove remote address for privacy
+		// Remove remote address for privacy (but keep it for the login throttling)
+		r = keepRemoteAddr(r)
 		r.RemoteAddr = ""
 		h.ServeHTTP(w, r)
 	})
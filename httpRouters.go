@@ -70,7 +70,7 @@ func (a *goBlog) webmentionsRouter(r chi.Router) {
 		r.Use(a.authMiddleware)
 		r.Get("/", a.webmentionAdmin)
 		r.Get(paginationPath, a.webmentionAdmin)
-		r.Post("/{action:(delete|approve|reverify)}", a.webmentionAdminAction)
+		r.Post("/{action:(delete|approve|reverify|spam|notspam)}", a.webmentionAdminAction)
 	})
 }
 
@@ -80,6 +80,8 @@ func (a *goBlog) notificationsRouter(r chi.Router) {
 	r.Get("/", a.notificationsAdmin)
 	r.Get(paginationPath, a.notificationsAdmin)
 	r.Post("/delete", a.notificationsAdminDelete)
+	r.Post("/spam", a.notificationsAdminTrainSpam(true))
+	r.Post("/notspam", a.notificationsAdminTrainSpam(false))
 }
 
 // Assets
@@ -121,6 +123,12 @@ func (a *goBlog) otherRoutesRouter(r chi.Router) {
 	// Hlsjs
 	r.With(cacheLoggedIn, a.cacheMiddleware, noIndexHeader).HandleFunc("/hlsjs/*", a.serveFs(hlsjsFiles, "/-/"))
 
+	// Webmention avatars
+	r.Get("/avatar/{hash}", a.serveMentionAvatar)
+
+	// Podcast chapters
+	r.With(cacheLoggedIn, a.cacheMiddleware).Get(podcastChaptersPath, a.servePodcastChapters)
+
 	// Reactions
 	if a.reactionsEnabled() {
 		r.Get("/reactions", a.getReactions)
@@ -165,6 +173,9 @@ func (a *goBlog) blogRouter(blog string, conf *configBlog) func(r chi.Router) {
 		// Comments
 		r.Group(a.blogCommentsRouter(conf))
 
+		// Interactions feed
+		r.Group(a.blogInteractionsRouter(conf))
+
 		// Stats
 		r.Group(a.blogStatsRouter(conf))
 
@@ -194,6 +205,8 @@ func (a *goBlog) blogHomeRouter(conf *configBlog) func(r chi.Router) {
 			r.With(a.checkActivityStreamsRequest, a.cacheMiddleware).Get(conf.getRelativePath(""), a.serveHome)
 			r.With(a.cacheMiddleware).Get(conf.getRelativePath("")+feedPath, a.serveHome)
 			r.With(a.cacheMiddleware).Get(conf.getRelativePath(paginationPath), a.serveHome)
+			r.With(a.cacheMiddleware).Get(conf.getRelativePath(paginationPath)+feedPath, a.serveHome)
+			r.With(a.cacheMiddleware).Get(conf.getRelativePath(archivePath)+feedPath, a.serveHome)
 		}
 	}
 }
@@ -215,6 +228,8 @@ func (a *goBlog) blogSectionsRouter(conf *configBlog) func(r chi.Router) {
 				r.Get(secPath, a.serveIndex)
 				r.Get(secPath+feedPath, a.serveIndex)
 				r.Get(secPath+paginationPath, a.serveIndex)
+				r.Get(secPath+paginationPath+feedPath, a.serveIndex)
+				r.Get(secPath+archivePath+feedPath, a.serveIndex)
 				r.Group(a.dateRoutes(conf, section.Name))
 			})
 		}
@@ -238,6 +253,8 @@ func (a *goBlog) blogTaxonomiesRouter(conf *configBlog) func(r chi.Router) {
 					r.Get(taxValPath, a.serveTaxonomyValue)
 					r.Get(taxValPath+feedPath, a.serveTaxonomyValue)
 					r.Get(taxValPath+paginationPath, a.serveTaxonomyValue)
+					r.Get(taxValPath+paginationPath+feedPath, a.serveTaxonomyValue)
+					r.Get(taxValPath+archivePath+feedPath, a.serveTaxonomyValue)
 				})
 			}
 		}
@@ -262,16 +279,22 @@ func (a *goBlog) dateRoutes(conf *configBlog, pathPrefix string) func(r chi.Rout
 		r.Get(yearPath, a.serveDate)
 		r.Get(yearPath+feedPath, a.serveDate)
 		r.Get(yearPath+paginationPath, a.serveDate)
+		r.Get(yearPath+paginationPath+feedPath, a.serveDate)
+		r.Get(yearPath+archivePath+feedPath, a.serveDate)
 
 		monthPath := yearPath + `/{month:(x|\d{2})}`
 		r.Get(monthPath, a.serveDate)
 		r.Get(monthPath+feedPath, a.serveDate)
 		r.Get(monthPath+paginationPath, a.serveDate)
+		r.Get(monthPath+paginationPath+feedPath, a.serveDate)
+		r.Get(monthPath+archivePath+feedPath, a.serveDate)
 
 		dayPath := monthPath + `/{day:(\d{2})}`
 		r.Get(dayPath, a.serveDate)
 		r.Get(dayPath+feedPath, a.serveDate)
 		r.Get(dayPath+paginationPath, a.serveDate)
+		r.Get(dayPath+paginationPath+feedPath, a.serveDate)
+		r.Get(dayPath+archivePath+feedPath, a.serveDate)
 	}
 }
 
@@ -294,6 +317,8 @@ func (a *goBlog) blogPhotosRouter(conf *configBlog) func(r chi.Router) {
 			r.Get(photoPath, a.serveIndex)
 			r.Get(photoPath+feedPath, a.serveIndex)
 			r.Get(photoPath+paginationPath, a.serveIndex)
+			r.Get(photoPath+paginationPath+feedPath, a.serveIndex)
+			r.Get(photoPath+archivePath+feedPath, a.serveIndex)
 		}
 	}
 }
@@ -316,6 +341,9 @@ func (a *goBlog) blogSearchRouter(conf *configBlog) func(r chi.Router) {
 					r.Get(searchResultPath, a.serveSearchResult)
 					r.Get(searchResultPath+feedPath, a.serveSearchResult)
 					r.Get(searchResultPath+paginationPath, a.serveSearchResult)
+					r.Get(searchResultPath+paginationPath+feedPath, a.serveSearchResult)
+					r.Get(searchResultPath+archivePath+feedPath, a.serveSearchResult)
+					r.Get(searchAPIPath, a.serveSearchAPI)
 				})
 				r.With(
 					// No private mode, to allow using OpenSearch in browser
@@ -386,12 +414,26 @@ func (a *goBlog) blogCommentsRouter(conf *configBlog) func(r chi.Router) {
 				)
 				r.With(a.cacheMiddleware, noIndexHeader).Get("/{id:[0-9]+}", a.serveComment)
 				r.With(a.captchaMiddleware, bodylimit.BodyLimit(bodylimit.MB)).Post("/", a.createCommentFromRequest)
+				r.With(bodylimit.BodyLimit(100*bodylimit.KB)).Post(commentPreviewSubPath, a.serveCommentPreview)
+				r.Group(func(r chi.Router) {
+					// Commenters with an edit token
+					r.Use(a.commentAuthorMiddleware)
+					r.Get(commentAuthorEditSubPath, a.serveCommentsEditor)
+					r.With(bodylimit.BodyLimit(bodylimit.MB)).Post(commentAuthorEditSubPath, a.serveCommentsEditor)
+					r.Post(commentAuthorDeleteSubPath, a.commentAuthorDelete)
+				})
+				r.Get(commentSubscriptionConfirmSubPath, a.serveCommentSubscriptionConfirm)
+				r.Get(commentSubscriptionUnsubscribeSubPath, a.serveCommentSubscriptionUnsubscribeForm)
+				r.Post(commentSubscriptionUnsubscribeSubPath, a.serveCommentSubscriptionUnsubscribe)
 				r.Group(func(r chi.Router) {
 					// Admin
 					r.Use(a.authMiddleware)
 					r.Get("/", a.commentsAdmin)
 					r.Get(paginationPath, a.commentsAdmin)
 					r.Post(commentDeleteSubPath, a.commentsAdminDelete)
+					r.Post(commentApproveSubPath, a.commentsAdminApprove)
+					r.Post(commentSpamSubPath, a.commentsAdminTrainSpam(true))
+					r.Post(commentNotSpamSubPath, a.commentsAdminTrainSpam(false))
 					r.Get(commentEditSubPath, a.serveCommentsEditor)
 					r.Post(commentEditSubPath, a.serveCommentsEditor)
 				})
@@ -400,6 +442,13 @@ func (a *goBlog) blogCommentsRouter(conf *configBlog) func(r chi.Router) {
 	}
 }
 
+// Blog - Interactions feed
+func (a *goBlog) blogInteractionsRouter(conf *configBlog) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.With(a.privateModeHandler, a.cacheMiddleware).Get(conf.getRelativePath(interactionsPath)+feedPath, a.serveInteractionsFeed)
+	}
+}
+
 // Blog - Stats
 func (a *goBlog) blogStatsRouter(conf *configBlog) func(r chi.Router) {
 	return func(r chi.Router) {
@@ -487,5 +536,9 @@ func (a *goBlog) blogSettingsRouter(_ *configBlog) func(r chi.Router) {
 		r.Post(settingsUpdateUserPath, a.settingsUpdateUser)
 		r.Post(settingsUpdateProfileImagePath, a.serveUpdateProfileImage)
 		r.Post(settingsDeleteProfileImagePath, a.serveDeleteProfileImage)
+		r.Post(settingsRevokeSessionPath, a.settingsRevokeSession)
+		r.Post(settingsRecoveryCodesPath, a.settingsGenerateRecoveryCodes)
+		r.Post(settingsCreateAppPasswordPath, a.settingsCreateAppPassword)
+		r.Post(settingsDeleteAppPasswordPath, a.settingsDeleteAppPassword)
 	}
 }
@@ -66,10 +66,12 @@ func (a *goBlog) indexNow(url string) {
 	}
 }
 
+const indexNowKeyCacheKey = "indexnowkey"
+
 func (a *goBlog) indexNowKey() []byte {
 	a.inLoad.Do(func() {
 		// Try to load key from database
-		keyBytes, err := a.db.retrievePersistentCache("indexnowkey")
+		keyBytes, err := a.db.retrievePersistentCache(indexNowKeyCacheKey)
 		if err != nil {
 			log.Println("Failed to retrieve cached IndexNow key:", err.Error())
 			return
@@ -78,7 +80,7 @@ func (a *goBlog) indexNowKey() []byte {
 			// Generate 128 character key with hexadecimal characters
 			keyBytes = []byte(randomString(128, []rune("0123456789abcdef")...))
 			// Store key in database
-			err = a.db.cachePersistently("indexnowkey", keyBytes)
+			err = a.db.cachePersistently(indexNowKeyCacheKey, keyBytes)
 			if err != nil {
 				log.Println("Failed to cache IndexNow key:", err.Error())
 				return
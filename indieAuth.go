@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -19,6 +20,23 @@ func (a *goBlog) initIndieAuth() {
 
 func (a *goBlog) checkIndieAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check app passwords
+		if username, password, ok := r.BasicAuth(); ok {
+			scopes, err := a.checkBasicAuth(r, username, password)
+			if err != nil {
+				// Don't check the credentials again when rendering the error
+				setLoggedIn(r, false)
+			}
+			if errors.Is(err, errLoginLocked) {
+				a.serveError(w, r, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+				return
+			} else if err != nil {
+				a.serveError(w, r, "Invalid app password", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), indieAuthScope, strings.Join(scopes, " "))))
+			return
+		}
 		bearerToken := defaultIfEmpty(r.Header.Get("Authorization"), r.URL.Query().Get("access_token"))
 		data, err := a.db.indieAuthVerifyToken(bearerToken)
 		if err != nil {
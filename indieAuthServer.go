@@ -106,6 +106,10 @@ func (a *goBlog) indieAuthVerificationToken(w http.ResponseWriter, r *http.Reque
 		a.db.indieAuthRevokeToken(r.Form.Get("token"))
 		return
 	}
+	// Private Webmention code
+	if a.privateWebmentionTokenResponse(w, r) {
+		return
+	}
 	// Token request
 	a.indieAuthVerification(w, r, true)
 }
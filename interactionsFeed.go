@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/go-chi/chi/v5"
+	"github.com/jlelse/feeds"
+	"go.goblog.app/app/pkgs/bufferpool"
+	"go.goblog.app/app/pkgs/htmlbuilder"
+)
+
+const (
+	interactionsPath           = "/interactions"
+	interactionsFeedPathParam  = "path"
+	notificationsFeedMaxLength = 50
+)
+
+// Feed of the approved comments and public webmentions of all public posts of a blog or of a single post
+func (a *goBlog) serveInteractionsFeed(w http.ResponseWriter, r *http.Request) {
+	blog, bc := a.getBlog(r)
+	title, path := a.ts.GetTemplateStringVariant(bc.Lang, "interactions"), bc.getRelativePath("")
+	commentsConfig := &commentsRequestConfig{status: commentStatusApproved, limit: bc.Pagination}
+	mentionsConfig := &webmentionsRequestConfig{
+		status:        webmentionStatusApproved,
+		public:        true,
+		excludesource: a.getFullAddress(bc.getRelativePath(commentPath)) + "/", // Comments are already included
+		limit:         bc.Pagination,
+	}
+	if postPath := r.URL.Query().Get(interactionsFeedPathParam); postPath != "" {
+		// Feed of a single post
+		p, err := a.getPost(postPath)
+		if errors.Is(err, errPostNotFound) {
+			a.serve404(w, r)
+			return
+		} else if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if p.Blog != blog || p.Status != statusPublished || (p.Visibility != visibilityPublic && p.Visibility != visibilityUnlisted) {
+			a.serve404(w, r)
+			return
+		}
+		commentsConfig.target = p.Path
+		mentionsConfig.target = a.fullPostURL(p)
+		title = fmt.Sprintf("%s: %s", title, defaultIfEmpty(p.RenderedTitle, a.fallbackTitle(p)))
+		path = p.Path
+	} else {
+		commentsConfig.blog = blog
+		mentionsConfig.targetblog, mentionsConfig.targetaddress = blog, a.cfg.Server.PublicAddress
+	}
+	comments, err := a.db.getComments(commentsConfig)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	mentions, err := a.db.getWebmentions(mentionsConfig)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items := make([]*feeds.Item, 0, len(comments)+len(mentions))
+	for _, c := range comments {
+		commentURL := a.getFullAddress(bc.getRelativePath(commentPath + "/" + strconv.Itoa(c.ID)))
+		items = append(items, &feeds.Item{
+			Title:       fmt.Sprintf("%s %s", a.ts.GetTemplateStringVariant(bc.Lang, "acommentby"), c.Name),
+			Link:        &feeds.Link{Href: commentURL},
+			Author:      interactionFeedAuthor(c.Name),
+			Description: a.renderTextSafe(c.Comment),
+			Id:          commentURL,
+			Content:     a.interactionFeedHtml(bc, a.commentReplyTarget(bc, c.Target, c.Parent), a.renderCommentContent(c)),
+			Created:     noError(dateparse.ParseLocal(c.Created)),
+		})
+	}
+	for _, m := range mentions {
+		items = append(items, &feeds.Item{
+			Title:       defaultIfEmpty(m.Title, defaultIfEmpty(m.Author, m.Url)),
+			Link:        &feeds.Link{Href: m.Url},
+			Author:      interactionFeedAuthor(m.Author),
+			Description: m.Content,
+			Id:          m.Source,
+			Content:     a.interactionFeedHtml(bc, m.Target, a.renderCommentMarkdown(m.Content)),
+			Created:     time.Unix(m.Created, 0),
+		})
+	}
+	// Newest first
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Created.After(items[j].Created)
+	})
+	if len(items) > bc.Pagination {
+		items = items[:bc.Pagination]
+	}
+	a.serveFeed(blog, feedType(chi.URLParam(r, "feed")), w, r, items, title, "", path, "", nil, nil)
+}
+
+func (a *goBlog) interactionsFeedURL(bc *configBlog, p *post, f feedType) string {
+	return a.getFullAddress(bc.getRelativePath(interactionsPath+"."+string(f))) + "?" + interactionsFeedPathParam + "=" + url.QueryEscape(p.Path)
+}
+
+func interactionFeedAuthor(name string) *feeds.Author {
+	if name == "" {
+		return nil
+	}
+	return &feeds.Author{Name: name}
+}
+
+// Link to the target of comments and webmentions followed by their already rendered content
+func (a *goBlog) interactionFeedHtml(bc *configBlog, target, contentHtml string) string {
+	buf := bufferpool.Get()
+	defer bufferpool.Put(buf)
+	hb := htmlbuilder.NewHtmlBuilder(buf)
+	hb.WriteElementOpen("p")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(bc.Lang, "replyto"))
+	hb.WriteUnescaped(" ")
+	hb.WriteElementOpen("a", "href", target)
+	hb.WriteEscaped(target)
+	hb.WriteElementClose("a")
+	hb.WriteElementClose("p")
+	hb.WriteUnescaped(contentHtml)
+	return buf.String()
+}
+
+// Admin-only feed of the latest notifications
+func (a *goBlog) serveNotificationsFeed(w http.ResponseWriter, r *http.Request) {
+	notifications, err := a.db.getNotifications(&notificationsRequestConfig{limit: notificationsFeedMaxLength})
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	blog, bc := a.getBlog(r)
+	items := make([]*feeds.Item, 0, len(notifications))
+	for _, n := range notifications {
+		buf := bufferpool.Get()
+		hb := htmlbuilder.NewHtmlBuilder(buf)
+		hb.WriteElementOpen("p")
+		hb.WriteEscaped(n.Text)
+		hb.WriteElementClose("p")
+		items = append(items, &feeds.Item{
+			Title:   truncateStringWithEllipsis(n.Text, 60),
+			Link:    &feeds.Link{Href: a.getFullAddress(notificationsPath)},
+			Id:      fmt.Sprintf("%s/%d", notificationsPath, n.ID),
+			Content: buf.String(),
+			Created: time.Unix(n.Time, 0),
+		})
+		bufferpool.Put(buf)
+	}
+	// Never cache notifications in shared caches
+	w.Header().Set(cacheControl, "private,no-cache")
+	a.serveFeed(blog, feedType(chi.URLParam(r, "feed")), w, r, items, a.ts.GetTemplateStringVariant(bc.Lang, "notifications"), "", notificationsPath, "", nil, nil)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_interactionsFeed(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.User.AppPasswords = []*configAppPassword{{Username: "feedreader", Password: "pass"}}
+
+	require.NoError(t, app.initConfig(false))
+	require.NoError(t, app.initTemplateStrings())
+	require.NoError(t, app.initCache())
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Comments = &configComments{Enabled: true}
+
+	app.d = app.buildRouter()
+
+	require.NoError(t, app.createPost(&post{Path: "/posts/public", Section: "posts", Content: "Public post"}))
+	require.NoError(t, app.createPost(&post{Path: "/posts/private", Section: "posts", Content: "Private post", Visibility: visibilityPrivate}))
+
+	_, _, err := app.createComment(bc, "http://localhost:8080/posts/public", "Nice **post**", "Commenter", "", "", "")
+	require.NoError(t, err)
+	_, _, err = app.createComment(bc, "http://localhost:8080/posts/private", "Secret comment", "Commenter", "", "", "")
+	require.NoError(t, err)
+
+	now := time.Now().Unix()
+	for _, m := range []*mention{
+		{Source: "https://example.com/reply", Target: "http://localhost:8080/posts/public", Content: "Great reply", Author: "Replier", Created: now},
+		{Source: "https://example.com/private", Target: "http://localhost:8080/posts/public", Content: "Private reply", Created: now, Private: true},
+		{Source: "https://example.com/other", Target: "http://localhost:8080/posts/private", Content: "Reply to private post", Created: now},
+		// Webmentions of local comments are already included as comments
+		{Source: "http://localhost:8080/comment/1", Target: "http://localhost:8080/posts/public", Content: "Nice post", Created: now},
+	} {
+		require.NoError(t, app.db.insertWebmention(m, webmentionStatusApproved))
+	}
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://example.com/unapproved", Target: "http://localhost:8080/posts/public", Content: "Unapproved reply", Created: now}, webmentionStatusVerified))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("Blog", func(t *testing.T) {
+		rec := get("/interactions.rss")
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, "<title>A comment by Commenter</title>")
+		assert.Contains(t, body, "Nice <strong>post</strong>")
+		assert.Contains(t, body, "Great reply")
+		assert.NotContains(t, body, "Secret comment")
+		assert.NotContains(t, body, "Private reply")
+		assert.NotContains(t, body, "Reply to private post")
+		assert.NotContains(t, body, "Unapproved reply")
+		assert.Equal(t, 1, strings.Count(body, "<guid>http://localhost:8080/comment/1</guid>"))
+	})
+
+	t.Run("Post", func(t *testing.T) {
+		rec := get("/interactions.atom?path=/posts/public")
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, "Commenter")
+		assert.Contains(t, body, "Replier")
+
+		rec = get("/interactions.json?path=/posts/public")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"url":"http://localhost:8080/comment/1"`)
+
+		// Private and unknown posts
+		assert.Equal(t, http.StatusNotFound, get("/interactions.rss?path=/posts/private").Code)
+		assert.Equal(t, http.StatusNotFound, get("/interactions.rss?path=/posts/unknown").Code)
+
+		// Discovery
+		assert.Contains(t, get("/posts/public").Body.String(), `href="http://localhost:8080/interactions.rss?path=%2Fposts%2Fpublic"`)
+	})
+
+	t.Run("Notifications", func(t *testing.T) {
+		require.NoError(t, app.db.saveNotification(&notification{Time: now, Text: "Secret notification"}))
+
+		rec := get("/notifications.rss")
+		assert.NotContains(t, rec.Body.String(), "Secret notification")
+
+		req := httptest.NewRequest(http.MethodGet, "/notifications.atom", nil)
+		req.SetBasicAuth("feedreader", "pass")
+		rec = httptest.NewRecorder()
+		app.d.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Secret notification")
+		assert.Contains(t, rec.Header().Get(cacheControl), "private")
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+)
+
+const (
+	loginEventSuccess         = "login"
+	loginEventFailure         = "loginfailed"
+	loginEventBasicAuthFailed = "basicauthfailed"
+	loginEventLocked          = "loginlocked"
+	loginEventLogout          = "logout"
+	loginEventSessionRevoked  = "sessionrevoked"
+	loginEventRecoveryCode    = "recoverycodeused"
+	loginEventRecoveryCodeGen = "recoverycodesgenerated"
+
+	// Failed logins are counted in this time window
+	loginThrottleWindow = 15 * time.Minute
+	// Maximum failed logins in the window before locking
+	loginMaxFailuresPerIP      = 10
+	loginMaxFailuresPerAccount = 25
+	// Keep login events for the security log for 30 days
+	loginEventsRetention = 30 * 24 * time.Hour
+
+	remoteAddrKey contextKey = "remoteAddr"
+)
+
+type loginEvent struct {
+	Time      time.Time
+	Event     string
+	Username  string
+	IP        string
+	UserAgent string
+}
+
+func (a *goBlog) initLoginSecurity() {
+	deleteOldLoginEvents := func() {
+		if _, err := a.db.Exec(
+			"delete from loginevents where time < @time",
+			sql.Named("time", time.Now().UTC().Add(-loginEventsRetention).Format(time.RFC3339)),
+		); err != nil {
+			log.Println("Failed to delete old login events:", err.Error())
+		}
+	}
+	deleteOldLoginEvents()
+	a.hourlyHooks = append(a.hourlyHooks, deleteOldLoginEvents)
+}
+
+var (
+	errLoginLocked      = errors.New("too many failed login attempts, try again later")
+	errWrongAppPassword = errors.New("invalid app password")
+)
+
+// Get the IP address of the client, even if the log middleware removed it from the request.
+// Behind a reverse proxy all requests have the address of the proxy, so the address from the
+// proxy headers is used if the proxy is trusted.
+func (a *goBlog) remoteIP(r *http.Request) string {
+	if a.cfg.Server.TrustProxy {
+		// The last address is the one added by the proxy, the others can be set by the client
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addrs := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(addrs[len(addrs)-1]); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	addr := r.RemoteAddr
+	if ctxAddr, ok := r.Context().Value(remoteAddrKey).(string); ok {
+		addr = ctxAddr
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// Keep the remote address in the request context
+func keepRemoteAddr(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(remoteAddrKey).(string); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), remoteAddrKey, r.RemoteAddr))
+}
+
+func (a *goBlog) logLoginEvent(r *http.Request, event, username string) {
+	if err := a.db.saveLoginEvent(&loginEvent{
+		Time:      time.Now(),
+		Event:     event,
+		Username:  username,
+		IP:        a.remoteIP(r),
+		UserAgent: r.UserAgent(),
+	}); err != nil {
+		log.Println("Failed to save login event:", err.Error())
+	}
+}
+
+func (db *database) saveLoginEvent(e *loginEvent) error {
+	_, err := db.Exec(
+		"insert into loginevents (time, event, username, ip, useragent) values (@time, @event, @username, @ip, @useragent)",
+		sql.Named("time", e.Time.UTC().Format(time.RFC3339)),
+		sql.Named("event", e.Event),
+		sql.Named("username", e.Username),
+		sql.Named("ip", e.IP),
+		sql.Named("useragent", e.UserAgent),
+	)
+	return err
+}
+
+func (db *database) getLoginEvents(limit int) ([]*loginEvent, error) {
+	rows, err := db.Query(
+		"select time, event, username, ip, useragent from loginevents order by id desc limit @limit",
+		sql.Named("limit", limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []*loginEvent{}
+	for rows.Next() {
+		e := &loginEvent{}
+		var timeStr string
+		if err = rows.Scan(&timeStr, &e.Event, &e.Username, &e.IP, &e.UserAgent); err != nil {
+			return nil, err
+		}
+		e.Time = noError(dateparse.ParseIn(timeStr, time.UTC))
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Check app password credentials from Basic auth with the same throttling and security log as the login form,
+// successful requests aren't logged, because clients send the credentials with every request
+func (a *goBlog) checkBasicAuth(r *http.Request, username, password string) ([]string, error) {
+	locked, err := a.db.isLoginLocked(a.remoteIP(r), username)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		a.logLoginEvent(r, loginEventLocked, username)
+		return nil, errLoginLocked
+	}
+	scopes, ok := a.checkAppPasswords(username, password)
+	if !ok {
+		a.logLoginEvent(r, loginEventBasicAuthFailed, username)
+		return nil, errWrongAppPassword
+	}
+	return scopes, nil
+}
+
+// Check if there were too many failed logins from the IP or for the account recently
+func (db *database) isLoginLocked(ip, username string) (bool, error) {
+	row, err := db.QueryRow(
+		`select
+			count(case when ip = @ip then 1 end),
+			count(case when username = @username then 1 end)
+		from loginevents where event in (@event, @basicauthevent) and time >= @since`,
+		sql.Named("ip", ip),
+		sql.Named("username", username),
+		sql.Named("event", loginEventFailure),
+		sql.Named("basicauthevent", loginEventBasicAuthFailed),
+		sql.Named("since", time.Now().UTC().Add(-loginThrottleWindow).Format(time.RFC3339)),
+	)
+	if err != nil {
+		return false, err
+	}
+	var ipFailures, accountFailures int
+	if err = row.Scan(&ipFailures, &accountFailures); err != nil {
+		return false, err
+	}
+	return ipFailures >= loginMaxFailuresPerIP || accountFailures >= loginMaxFailuresPerAccount, nil
+}
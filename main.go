@@ -149,6 +149,21 @@ func main() {
 		return
 	}
 
+	// Full export
+	if len(os.Args) >= 3 && os.Args[1] == "export" && os.Args[2] == "--full" {
+		var file string
+		if len(os.Args) >= 4 {
+			file = os.Args[3]
+		}
+		err = app.exportFull(file)
+		if err != nil {
+			app.logErrAndQuit("Failed to export:", err.Error())
+			return
+		}
+		app.shutdown.ShutdownAndWait()
+		return
+	}
+
 	// Markdown export
 	if len(os.Args) >= 2 && os.Args[1] == "export" {
 		var dir string
@@ -164,6 +179,59 @@ func main() {
 		return
 	}
 
+	// Rebuild or check the full-text search index
+	if len(os.Args) >= 2 && os.Args[1] == "reindex" {
+		if len(os.Args) < 3 || os.Args[2] != "check" {
+			if err = app.db.rebuildFTSIndex(); err != nil {
+				app.logErrAndQuit("Failed to rebuild search index:", err.Error())
+				return
+			}
+			log.Println("Rebuilt search index")
+		}
+		inconsistent, err := app.db.checkFTSIndex()
+		if err != nil {
+			app.logErrAndQuit("Search index integrity check failed:", err.Error())
+			return
+		}
+		if inconsistent > 0 {
+			app.logErrAndQuit("Search index is outdated for", inconsistent, "posts, run reindex to rebuild it")
+			return
+		}
+		log.Println("Search index is consistent")
+		app.shutdown.ShutdownAndWait()
+		return
+	}
+
+	// Import comments
+	if len(os.Args) >= 2 && os.Args[1] == "import-comments" {
+		if len(os.Args) < 3 {
+			app.logErrAndQuit("Usage: import-comments <file>")
+			return
+		}
+		err = app.importCommentsFile(os.Args[2])
+		if err != nil {
+			app.logErrAndQuit("Failed to import comments:", err.Error())
+			return
+		}
+		app.shutdown.ShutdownAndWait()
+		return
+	}
+
+	// Restore full export
+	if len(os.Args) >= 2 && os.Args[1] == "import" {
+		if len(os.Args) < 3 {
+			app.logErrAndQuit("Usage: import <file>")
+			return
+		}
+		err = app.importFull(os.Args[2])
+		if err != nil {
+			app.logErrAndQuit("Failed to import:", err.Error())
+			return
+		}
+		app.shutdown.ShutdownAndWait()
+		return
+	}
+
 	// Initialize components
 	app.initComponents()
 
@@ -216,10 +284,13 @@ func (app *goBlog) initComponents() {
 	app.initBlogStats()
 	app.initTTS()
 	app.initSessions()
+	app.initLoginSecurity()
 	app.initIndieAuth()
 	app.startPostsScheduler()
 	app.initPostsDeleter()
 	app.initIndexNow()
+	app.initWebSub()
+	app.initPodcast()
 
 	log.Println("Initialized components")
 }
@@ -13,12 +13,13 @@ import (
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/util"
+	"go.goblog.app/app/pkgs/bufferpool"
 	"go.goblog.app/app/pkgs/highlighting"
 	"go.goblog.app/app/pkgs/htmlbuilder"
 )
 
 func (a *goBlog) initMarkdown() {
-	if a.md != nil && a.absoluteMd != nil && a.titleMd != nil {
+	if a.md != nil && a.absoluteMd != nil && a.titleMd != nil && a.commentMd != nil {
 		// Already initialized
 		return
 	}
@@ -64,6 +65,37 @@ func (a *goBlog) initMarkdown() {
 			emoji.Emoji,
 		),
 	)
+	a.commentMd = goldmark.New(
+		goldmark.WithParser(
+			// Override, only a restricted set of Markdown without headings and raw HTML
+			parser.NewParser(
+				parser.WithBlockParsers(
+					util.Prioritized(parser.NewListParser(), 300),
+					util.Prioritized(parser.NewListItemParser(), 400),
+					util.Prioritized(parser.NewCodeBlockParser(), 500),
+					util.Prioritized(parser.NewFencedCodeBlockParser(), 700),
+					util.Prioritized(parser.NewBlockquoteParser(), 800),
+					util.Prioritized(parser.NewParagraphParser(), 1000),
+				),
+				parser.WithInlineParsers(
+					util.Prioritized(parser.NewCodeSpanParser(), 100),
+					util.Prioritized(parser.NewLinkParser(), 200),
+					util.Prioritized(parser.NewAutoLinkParser(), 300),
+					util.Prioritized(parser.NewEmphasisParser(), 500),
+				),
+				parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+			),
+		),
+		goldmark.WithRendererOptions(
+			html.WithHardWraps(),
+		),
+		goldmark.WithExtensions(
+			extension.Strikethrough,
+			extension.Linkify,
+			emoji.Emoji,
+			&commentExtension{},
+		),
+	)
 }
 
 func (a *goBlog) renderMarkdownToWriter(w io.Writer, source string, absoluteLinks bool) (err error) {
@@ -96,6 +128,19 @@ func (a *goBlog) renderTextSafe(s string) string {
 	return r
 }
 
+// Render comments and webmention content with the restricted Markdown, the result is safe HTML
+func (a *goBlog) renderCommentMarkdown(s string) string {
+	if s == "" {
+		return ""
+	}
+	buf := bufferpool.Get()
+	defer bufferpool.Put(buf)
+	if err := a.commentMd.Convert([]byte(s), buf); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
 func (a *goBlog) renderMdTitle(s string) string {
 	if s == "" {
 		return ""
@@ -193,3 +238,70 @@ func (c *customRenderer) renderImage(w util.BufWriter, source []byte, node ast.N
 	hb.WriteElementClose("a")
 	return ast.WalkSkipChildren, nil
 }
+
+// Comments
+type commentExtension struct{}
+
+func (*commentExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&commentRenderer{}, 500),
+	))
+}
+
+type commentRenderer struct{}
+
+func (c *commentRenderer) RegisterFuncs(r renderer.NodeRendererFuncRegisterer) {
+	r.Register(ast.KindLink, c.renderLink)
+	r.Register(ast.KindAutoLink, c.renderAutoLink)
+	r.Register(ast.KindImage, c.renderImage)
+}
+
+// All links in comments are user generated content and open in a new tab
+func (*commentRenderer) writeLinkOpen(w util.BufWriter, destination []byte) {
+	if html.IsDangerousURL(destination) {
+		destination = nil
+	}
+	_, _ = w.WriteString("<a href=\"")
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape(destination, true)))
+	_, _ = w.WriteString(`" target="_blank" rel="nofollow noopener noreferrer ugc">`)
+}
+
+func (c *commentRenderer) renderLink(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		c.writeLinkOpen(w, node.(*ast.Link).Destination)
+	} else {
+		_, _ = w.WriteString("</a>")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (c *commentRenderer) renderAutoLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.AutoLink)
+	destination := n.URL(source)
+	if n.AutoLinkType == ast.AutoLinkEmail && !bytes.HasPrefix(bytes.ToLower(destination), []byte("mailto:")) {
+		destination = append([]byte("mailto:"), destination...)
+	}
+	c.writeLinkOpen(w, destination)
+	_, _ = w.Write(util.EscapeHTML(n.Label(source)))
+	_, _ = w.WriteString("</a>")
+	return ast.WalkContinue, nil
+}
+
+// Images aren't embedded, only linked with the alt text
+func (c *commentRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	label := n.Text(source)
+	if len(label) == 0 {
+		label = n.Destination
+	}
+	c.writeLinkOpen(w, n.Destination)
+	_, _ = w.Write(util.EscapeHTML(label))
+	_, _ = w.WriteString("</a>")
+	return ast.WalkSkipChildren, nil
+}
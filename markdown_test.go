@@ -92,6 +92,37 @@ func Test_markdown(t *testing.T) {
 	})
 }
 
+func Test_commentMarkdown(t *testing.T) {
+	app := &goBlog{
+		cfg: &config{
+			Server: &configServer{
+				PublicAddress: "https://example.com",
+			},
+		},
+	}
+
+	app.initMarkdown()
+
+	rendered := app.renderCommentMarkdown("*Emphasis* and `code`\n\n> Quote\n\n- List")
+	assert.Contains(t, rendered, "<em>Emphasis</em>")
+	assert.Contains(t, rendered, "<code>code</code>")
+	assert.Contains(t, rendered, "<blockquote>")
+	assert.Contains(t, rendered, "<li>List</li>")
+
+	// Links are marked as user generated content
+	rendered = app.renderCommentMarkdown("[Link](https://example.org) https://example.net")
+	assert.Contains(t, rendered, `<a href="https://example.org" target="_blank" rel="nofollow noopener noreferrer ugc">Link</a>`)
+	assert.Contains(t, rendered, `<a href="https://example.net" target="_blank" rel="nofollow noopener noreferrer ugc">https://example.net</a>`)
+
+	// No headings, raw HTML, dangerous links or embedded images
+	rendered = app.renderCommentMarkdown("# Heading\n\n<script>alert('XSS')</script>\n\n[XSS](javascript:alert(1)) ![Image](https://example.org/image.png)")
+	assert.NotContains(t, rendered, "<h1")
+	assert.NotContains(t, rendered, "<script>")
+	assert.NotContains(t, rendered, "javascript:")
+	assert.NotContains(t, rendered, "<img")
+	assert.Contains(t, rendered, `<a href="https://example.org/image.png" target="_blank" rel="nofollow noopener noreferrer ugc">Image</a>`)
+}
+
 func Benchmark_markdown(b *testing.B) {
 	markdownExample, err := os.ReadFile("testdata/markdownexample.md")
 	if err != nil {
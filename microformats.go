@@ -30,8 +30,23 @@ func (a *goBlog) initMicroformatsCache() {
 
 type microformatsResult struct {
 	Title, Content, Author, Url string
+	AuthorPhoto                 string
 	source                      string
 	hasUrl                      bool
+	// Values of response properties (in-reply-to, like-of etc.)
+	references map[string][]string
+}
+
+// Response properties and their mention types, in order of precedence
+var mfReferenceProperties = []struct {
+	property string
+	typ      mentionType
+}{
+	{"rsvp", mentionTypeRSVP},
+	{"in-reply-to", mentionTypeReply},
+	{"repost-of", mentionTypeRepost},
+	{"like-of", mentionTypeLike},
+	{"bookmark-of", mentionTypeBookmark},
 }
 
 func (a *goBlog) parseMicroformats(u string, cache bool) (*microformatsResult, error) {
@@ -111,8 +126,10 @@ func (m *microformatsResult) fill(mf *microformats.Microformat) bool {
 					m.Url = url0
 					// Reset attributes to refill
 					m.Author = ""
+					m.AuthorPhoto = ""
 					m.Title = ""
 					m.Content = ""
+					m.references = nil
 				} else if m.hasUrl {
 					// Already found entry
 					return false
@@ -131,6 +148,8 @@ func (m *microformatsResult) fill(mf *microformats.Microformat) bool {
 		m.fillContent(mf)
 		// Author
 		m.fillAuthor(mf)
+		// Responses
+		m.fillReferences(mf)
 		return m.hasUrl
 	}
 	for _, mfc := range mf.Children {
@@ -182,8 +201,63 @@ func (m *microformatsResult) fillAuthor(mf *microformats.Microformat) {
 					m.Author = strings.TrimSpace(name)
 				}
 			}
+			if photos, ok := author.Properties["photo"]; ok && len(photos) > 0 {
+				m.AuthorPhoto = mfValueURL(photos[0])
+			}
+		}
+	}
+}
+
+func (m *microformatsResult) fillReferences(mf *microformats.Microformat) {
+	if m.references != nil {
+		return
+	}
+	m.references = map[string][]string{}
+	for _, rp := range mfReferenceProperties {
+		for _, value := range mf.Properties[rp.property] {
+			if u := mfValueURL(value); u != "" {
+				m.references[rp.property] = append(m.references[rp.property], u)
+			}
+		}
+	}
+}
+
+// Detect the type of the mention, the response properties have to reference the target,
+// RSVPs are replies with a rsvp property
+func (m *microformatsResult) mentionType(target string) mentionType {
+	refersTo := func(property string) bool {
+		for _, u := range m.references[property] {
+			if lowerUnescapedPath(u) == lowerUnescapedPath(target) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(m.references["rsvp"]) > 0 && refersTo("in-reply-to") {
+		return mentionTypeRSVP
+	}
+	for _, rp := range mfReferenceProperties {
+		if rp.property != "rsvp" && refersTo(rp.property) {
+			return rp.typ
+		}
+	}
+	return mentionTypeMention
+}
+
+// Get the URL of a property value, which can be a plain URL, an image with alt text or an embedded microformat (h-cite etc.)
+func mfValueURL(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]string:
+		return strings.TrimSpace(v["value"])
+	case *microformats.Microformat:
+		if urls, ok := v.Properties["url"]; ok && len(urls) > 0 {
+			return mfValueURL(urls[0])
 		}
+		return strings.TrimSpace(v.Value)
 	}
+	return ""
 }
 
 func mfHasType(mf *microformats.Microformat, typ string) bool {
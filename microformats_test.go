@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -35,3 +36,32 @@ func Test_parseMicroformats(t *testing.T) {
 	assert.Equal(t, "https://example.net/articles/micropub-crossposting-to-twitter-and-enabling-tweetstorms", m.Url)
 
 }
+
+func Test_mentionType(t *testing.T) {
+	target := "https://example.com/post"
+	entry := func(properties string) string {
+		return `<div class="h-entry"><a class="u-url" href="https://example.org/1">Link</a>` +
+			`<a class="p-author h-card" href="https://example.org"><img class="u-photo" src="https://example.org/photo.jpg" alt="">Author</a>` +
+			properties + `<a href="` + target + `">Target</a></div>`
+	}
+
+	for _, tc := range []struct {
+		name, properties string
+		expected         mentionType
+	}{
+		{"Mention", ``, mentionTypeMention},
+		{"Reply", `<a class="u-in-reply-to" href="` + target + `">Reply</a>`, mentionTypeReply},
+		{"Like", `<a class="u-like-of" href="` + target + `">Like</a>`, mentionTypeLike},
+		{"Repost h-cite", `<div class="u-repost-of h-cite"><a class="u-url" href="` + target + `">Repost</a></div>`, mentionTypeRepost},
+		{"Bookmark", `<a class="u-bookmark-of" href="` + target + `">Bookmark</a>`, mentionTypeBookmark},
+		{"RSVP", `<a class="u-in-reply-to" href="` + target + `">Event</a><data class="p-rsvp" value="yes">Going</data>`, mentionTypeRSVP},
+		{"Like of other post", `<a class="u-like-of" href="https://example.net/other">Like</a>`, mentionTypeMention},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := parseMicroformatsFromReader("https://example.org/1", strings.NewReader(entry(tc.properties)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m.mentionType(target))
+			assert.Equal(t, "https://example.org/photo.jpg", m.AuthorPhoto)
+		})
+	}
+}
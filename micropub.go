@@ -546,7 +546,8 @@ func (a *goBlog) micropubCreatePostFromJson(w http.ResponseWriter, r *http.Reque
 }
 
 func (a *goBlog) micropubCheckScope(w http.ResponseWriter, r *http.Request, required string) bool {
-	if !strings.Contains(r.Context().Value(indieAuthScope).(string), required) {
+	// Compare whole scopes, "undelete" doesn't contain the "delete" scope
+	if !lo.Contains(strings.Fields(r.Context().Value(indieAuthScope).(string)), required) {
 		a.serveError(w, r, required+" scope missing", http.StatusForbidden)
 		return false
 	}
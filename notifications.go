@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -201,3 +202,39 @@ func (a *goBlog) notificationsAdminDelete(w http.ResponseWriter, r *http.Request
 	}
 	http.Redirect(w, r, ".", http.StatusFound)
 }
+
+func (db *database) getNotification(id int) (*notification, error) {
+	row, err := db.QueryRow("select id, time, text from notifications where id = @id", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	n := &notification{}
+	if err = row.Scan(&n.ID, &n.Time, &n.Text); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Train the spam filter with the notification (e.g. contact form submissions), spam gets deleted
+func (a *goBlog) notificationsAdminTrainSpam(spam bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.FormValue("notificationid"))
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n, err := a.db.getNotification(id)
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.trainSpamFilter(fmt.Sprintf("notification/%d", id), spam, strings.TrimPrefix(n.Text, spamNotificationPrefix))
+		if spam {
+			if err = a.db.deleteNotification(id); err != nil {
+				a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		http.Redirect(w, r, ".", http.StatusFound)
+	}
+}
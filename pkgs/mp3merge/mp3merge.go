@@ -3,6 +3,7 @@ package mp3merge
 import (
 	"errors"
 	"io"
+	"time"
 
 	"github.com/dmulholl/mp3lib"
 	"go.goblog.app/app/pkgs/bufferpool"
@@ -77,3 +78,42 @@ func MergeMP3(out io.Writer, in ...io.Reader) error {
 	_, err := io.Copy(out, tmpOut)
 	return err
 }
+
+// Get the duration of an mp3 by adding up the durations of all frames.
+func Duration(in io.Reader) (time.Duration, error) {
+	if in == nil {
+		return 0, errors.New("nil input")
+	}
+
+	var duration time.Duration
+	var frames int
+	isFirstFrame := true
+
+	for {
+		// Read the next frame from the input
+		frame := mp3lib.NextFrame(in)
+		if frame == nil {
+			break
+		}
+
+		// Skip the first frame if it's a VBR header
+		if isFirstFrame {
+			isFirstFrame = false
+			if mp3lib.IsXingHeader(frame) || mp3lib.IsVbriHeader(frame) {
+				continue
+			}
+		}
+
+		if frame.SamplingRate == 0 {
+			continue
+		}
+
+		duration += time.Duration(frame.SampleCount) * time.Second / time.Duration(frame.SamplingRate)
+		frames++
+	}
+
+	if frames == 0 {
+		return 0, errors.New("no mp3 frames found")
+	}
+	return duration, nil
+}
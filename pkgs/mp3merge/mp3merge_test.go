@@ -0,0 +1,35 @@
+package mp3merge
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Create an mp3 with silent MPEG-1 Layer III frames (128 kbit/s, 44.1 kHz, 1152 samples each)
+func testMP3(frames int) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return bytes.Repeat(frame, frames)
+}
+
+func TestDuration(t *testing.T) {
+	d, err := Duration(bytes.NewReader(testMP3(100)))
+	require.NoError(t, err)
+	assert.Equal(t, 2612, int(d/time.Millisecond))
+
+	_, err = Duration(bytes.NewReader([]byte("no mp3")))
+	assert.Error(t, err)
+}
+
+func TestMergeMP3(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, MergeMP3(&out, bytes.NewReader(testMP3(10)), bytes.NewReader(testMP3(20))))
+
+	d, err := Duration(&out)
+	require.NoError(t, err)
+	assert.Equal(t, 783, int(d/time.Millisecond))
+}
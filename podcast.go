@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/jlelse/feeds"
+	"go.goblog.app/app/pkgs/mp3merge"
+)
+
+// Podcast feeds with iTunes and Podcasting 2.0 tags for sections with podcast mode
+// https://help.apple.com/itc/podcasts_connect/#/itcb54353390
+// https://podcastindex.org/namespace/1.0
+
+const (
+	podcastChaptersParam   = "chapters"
+	podcastTranscriptParam = "transcript"
+	podcastExplicitParam   = "explicit"
+
+	podcastChaptersPath = "/podcastchapters"
+
+	podcastAudioQueue       = "podcastaudio"
+	podcastAudioCachePrefix = "podcastaudio_"
+
+	itunesNamespace  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	podcastNamespace = "https://podcastindex.org/namespace/1.0"
+)
+
+func (a *goBlog) initPodcast() {
+	// Read the audio information of new episodes in the background, feeds only use the cached information
+	hook := func(p *post) {
+		if !p.isPublicPublishedSectionPost() || !a.isPodcastEpisode(p) {
+			return
+		}
+		if audio := a.podcastEpisodeAudio(p); audio != "" {
+			// Retry failed audio files on updates
+			if info := a.cachedPodcastAudioInfo(audio); info == nil || info.Failed {
+				if err := a.queuePodcastAudio(audio); err != nil {
+					log.Printf("queueing podcast audio for %s failed: %v", p.Path, err)
+				}
+			}
+		}
+	}
+	a.pPostHooks = append(a.pPostHooks, hook)
+	a.pUpdateHooks = append(a.pUpdateHooks, hook)
+	a.listenOnQueue(podcastAudioQueue, time.Minute, func(qi *queueItem, dequeue func(), _ func(time.Duration)) {
+		defer dequeue()
+		if err := a.readPodcastAudioInfo(string(qi.content)); err != nil {
+			log.Printf("reading podcast audio %s failed: %v", string(qi.content), err)
+		}
+	})
+}
+
+func (a *goBlog) queuePodcastAudio(audioURL string) error {
+	if queued, err := a.db.queueContains(podcastAudioQueue, []byte(audioURL)); err != nil || queued {
+		return err
+	}
+	return a.enqueue(podcastAudioQueue, []byte(audioURL), time.Now())
+}
+
+func (a *goBlog) isPodcastEpisode(p *post) bool {
+	bc := a.getBlogFromPost(p)
+	if bc == nil {
+		return false
+	}
+	section := bc.Sections[p.Section]
+	return section != nil && section.Podcast
+}
+
+// Get the audio of an episode, uploaded audio is preferred over TTS audio
+func (a *goBlog) podcastEpisodeAudio(p *post) string {
+	audio := p.firstParameter(a.cfg.Micropub.AudioParam)
+	if audio == "" {
+		audio = p.TTS()
+	}
+	if audio == "" {
+		return ""
+	}
+	return a.getFullAddress(audio)
+}
+
+type podcastAudio struct {
+	Length   int64
+	Type     string
+	Duration time.Duration
+	// Reading the audio failed, the episode has no enclosure until the post is updated
+	Failed bool `json:",omitempty"`
+}
+
+// Count the written bytes
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// Get the cached information of an audio file, nil if it wasn't read yet
+func (a *goBlog) cachedPodcastAudioInfo(audioURL string) *podcastAudio {
+	data, err := a.db.retrievePersistentCache(podcastAudioCachePrefix + audioURL)
+	if err != nil || data == nil {
+		return nil
+	}
+	info := &podcastAudio{}
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil
+	}
+	return info
+}
+
+// Download an audio file to read the length, type and duration, the result (also a failure) is cached persistently
+func (a *goBlog) readPodcastAudioInfo(audioURL string) error {
+	info, err := a.downloadPodcastAudioInfo(audioURL)
+	if err != nil {
+		info = &podcastAudio{Failed: true}
+	}
+	if data, marshalErr := json.Marshal(info); marshalErr == nil {
+		_ = a.db.cachePersistently(podcastAudioCachePrefix+audioURL, data)
+	}
+	return err
+}
+
+func (a *goBlog) downloadPodcastAudioInfo(audioURL string) (*podcastAudio, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	info := &podcastAudio{}
+	err := requests.URL(audioURL).Client(a.httpClient).
+		Handle(func(r *http.Response) error {
+			defer r.Body.Close()
+			info.Type = podcastAudioType(audioURL, r.Header.Get(contentType))
+			var length byteCounter
+			body := io.TeeReader(r.Body, &length)
+			if info.Type == "audio/mpeg" {
+				if duration, err := mp3merge.Duration(body); err == nil {
+					info.Duration = duration
+				}
+			}
+			if _, err := io.Copy(io.Discard, body); err != nil {
+				return err
+			}
+			info.Length = int64(length)
+			return nil
+		}).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func podcastAudioType(audioURL, headerType string) string {
+	if mt, _, err := mime.ParseMediaType(headerType); err == nil && strings.HasPrefix(mt, "audio/") {
+		return mt
+	}
+	ext := ""
+	if u, err := url.Parse(audioURL); err == nil {
+		ext = strings.ToLower(path.Ext(u.Path))
+	}
+	switch ext {
+	case ".mp3", "":
+		return "audio/mpeg"
+	case ".m4a":
+		return "audio/mp4"
+	case ".ogg", ".oga":
+		return "audio/ogg"
+	case ".opus":
+		return "audio/opus"
+	}
+	if mt := mime.TypeByExtension(ext); mt != "" {
+		return mt
+	}
+	return "audio/mpeg"
+}
+
+type podcastChapter struct {
+	StartTime float64 `json:"startTime"`
+	Title     string  `json:"title"`
+}
+
+// Parse the chapters parameter, one chapter per value in the format "01:02:03 Title" or "02:03 Title"
+func podcastChapters(p *post) []*podcastChapter {
+	var chapters []*podcastChapter
+	for _, value := range p.Parameters[podcastChaptersParam] {
+		start, title, found := strings.Cut(strings.TrimSpace(value), " ")
+		if !found || strings.TrimSpace(title) == "" {
+			continue
+		}
+		parts := strings.Split(start, ":")
+		if len(parts) > 3 {
+			continue
+		}
+		seconds, valid := 0.0, true
+		for _, part := range parts {
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil || n < 0 {
+				valid = false
+				break
+			}
+			seconds = seconds*60 + n
+		}
+		if valid {
+			chapters = append(chapters, &podcastChapter{StartTime: seconds, Title: strings.TrimSpace(title)})
+		}
+	}
+	return chapters
+}
+
+// Serve the chapters of an episode as JSON chapters file
+// https://github.com/Podcastindex-org/podcast-namespace/blob/main/chapters/jsonChapters.md
+func (a *goBlog) servePodcastChapters(w http.ResponseWriter, r *http.Request) {
+	p, err := a.getPost(r.URL.Query().Get("path"))
+	if err != nil || p.Status != statusPublished || (p.Visibility != visibilityPublic && p.Visibility != visibilityUnlisted) {
+		a.serve404(w, r)
+		return
+	}
+	chapters := podcastChapters(p)
+	if len(chapters) == 0 {
+		a.serve404(w, r)
+		return
+	}
+	a.respondWithMinifiedJson(w, map[string]any{
+		"version":  "1.2.0",
+		"chapters": chapters,
+	})
+}
+
+type podcastTranscript struct {
+	XMLName xml.Name `xml:"podcast:transcript"`
+	URL     string   `xml:"url,attr"`
+	Type    string   `xml:"type,attr"`
+}
+
+// Get the transcripts of an episode, the post itself is the transcript of TTS audio
+func (a *goBlog) podcastTranscripts(p *post) []*podcastTranscript {
+	var transcripts []*podcastTranscript
+	for _, transcript := range p.Parameters[podcastTranscriptParam] {
+		tt := "text/html"
+		switch strings.ToLower(path.Ext(transcript)) {
+		case ".vtt":
+			tt = "text/vtt"
+		case ".srt":
+			tt = "application/x-subrip"
+		case ".json":
+			tt = "application/json"
+		case ".txt":
+			tt = "text/plain"
+		}
+		transcripts = append(transcripts, &podcastTranscript{URL: a.getFullAddress(transcript), Type: tt})
+	}
+	if len(transcripts) == 0 && p.firstParameter(a.cfg.Micropub.AudioParam) == "" && p.TTS() != "" {
+		transcripts = append(transcripts, &podcastTranscript{URL: a.fullPostURL(p), Type: "text/html"})
+	}
+	return transcripts
+}
+
+type podcastRssFeed struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	AtomNamespace    string   `xml:"xmlns:atom,attr"`
+	HistoryNamespace string   `xml:"xmlns:fh,attr,omitempty"`
+	ItunesNamespace  string   `xml:"xmlns:itunes,attr"`
+	PodcastNamespace string   `xml:"xmlns:podcast,attr"`
+	Channel          *podcastRssChannel
+}
+
+type podcastRssChannel struct {
+	XMLName xml.Name `xml:"channel"`
+	*feeds.RssFeed
+	Links          []*rssAtomLink
+	Archive        *feedArchiveMarker
+	ItunesAuthor   string            `xml:"itunes:author,omitempty"`
+	ItunesSummary  string            `xml:"itunes:summary,omitempty"`
+	ItunesType     string            `xml:"itunes:type"`
+	ItunesImage    *itunesImage      `xml:"itunes:image,omitempty"`
+	ItunesCategory *itunesCategory   `xml:"itunes:category,omitempty"`
+	ItunesExplicit string            `xml:"itunes:explicit"`
+	ItunesOwner    *itunesOwner      `xml:"itunes:owner,omitempty"`
+	Items          []*podcastRssItem `xml:"item"`
+}
+
+type podcastRssItem struct {
+	*feeds.RssItem
+	ItunesTitle       string               `xml:"itunes:title,omitempty"`
+	ItunesDuration    int64                `xml:"itunes:duration,omitempty"`
+	ItunesImage       *itunesImage         `xml:"itunes:image,omitempty"`
+	ItunesExplicit    string               `xml:"itunes:explicit,omitempty"`
+	ItunesEpisodeType string               `xml:"itunes:episodeType"`
+	PodcastChapters   *podcastChaptersLink `xml:"podcast:chapters,omitempty"`
+	PodcastTranscript []*podcastTranscript
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type itunesCategory struct {
+	Text string `xml:"text,attr"`
+}
+
+type itunesOwner struct {
+	Name  string `xml:"itunes:name"`
+	Email string `xml:"itunes:email,omitempty"`
+}
+
+type podcastChaptersLink struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// Add the enclosure of an episode to the feed item, audio that wasn't read yet is queued and skipped
+func (a *goBlog) addPodcastEnclosure(item *feeds.Item, p *post) *podcastAudio {
+	audio := a.podcastEpisodeAudio(p)
+	if audio == "" {
+		return nil
+	}
+	info := a.cachedPodcastAudioInfo(audio)
+	if info == nil {
+		if err := a.queuePodcastAudio(audio); err != nil {
+			log.Printf("queueing podcast audio for %s failed: %v", p.Path, err)
+		}
+		return nil
+	}
+	if info.Failed {
+		return nil
+	}
+	item.Enclosure = &feeds.Enclosure{Url: audio, Length: strconv.FormatInt(info.Length, 10), Type: info.Type}
+	return info
+}
+
+// Create the RSS feed of a podcast section, posts and audio infos have the same order as the feed items
+func (a *goBlog) podcastRss(feed *feeds.Feed, section *configSection, posts []*post, audios []*podcastAudio, links []*feeds.AtomLink, archive bool) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		historyNamespace, archiveMarker := newFeedArchiveMarker(archive)
+		rssFeed := (&feeds.Rss{Feed: feed}).RssFeed()
+		channel := &podcastRssChannel{
+			RssFeed:        rssFeed,
+			Links:          rssAtomLinks(links),
+			Archive:        archiveMarker,
+			ItunesAuthor:   a.cfg.User.Name,
+			ItunesSummary:  feed.Description,
+			ItunesType:     "episodic",
+			ItunesImage:    &itunesImage{Href: a.getFullAddress(a.profileImagePath(profileImageFormatJPEG, 0, 0))},
+			ItunesExplicit: "false",
+		}
+		if a.cfg.User.Name != "" {
+			channel.ItunesOwner = &itunesOwner{Name: a.cfg.User.Name}
+			// The email is public in the feed, so it's only included when enabled for the podcast
+			if section.PodcastEmail {
+				channel.ItunesOwner.Email = a.cfg.User.Email
+			}
+		}
+		if section.PodcastCategory != "" {
+			channel.ItunesCategory = &itunesCategory{Text: section.PodcastCategory}
+		}
+		for i, ri := range rssFeed.Items {
+			p := posts[i]
+			item := &podcastRssItem{
+				RssItem:           ri,
+				ItunesTitle:       p.RenderedTitle,
+				ItunesEpisodeType: "full",
+				PodcastTranscript: a.podcastTranscripts(p),
+			}
+			if audio := audios[i]; audio != nil {
+				item.ItunesDuration = int64(audio.Duration.Seconds())
+			}
+			if photos := a.photoLinks(p); len(photos) > 0 {
+				item.ItunesImage = &itunesImage{Href: a.getFullAddress(photos[0])}
+			}
+			if p.firstParameter(podcastExplicitParam) == "true" {
+				item.ItunesExplicit = "true"
+			}
+			if len(podcastChapters(p)) > 0 {
+				item.PodcastChapters = &podcastChaptersLink{URL: a.podcastChaptersURL(p), Type: "application/json+chapters"}
+			}
+			channel.Items = append(channel.Items, item)
+		}
+		return feeds.WriteXML(&podcastRssFeed{
+			Version:          "2.0",
+			ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+			AtomNamespace:    "http://www.w3.org/2005/Atom",
+			HistoryNamespace: historyNamespace,
+			ItunesNamespace:  itunesNamespace,
+			PodcastNamespace: podcastNamespace,
+			Channel:          channel,
+		}, w)
+	}
+}
+
+func (f *podcastRssFeed) FeedXml() any {
+	return f
+}
+
+func (a *goBlog) podcastChaptersURL(p *post) string {
+	return fmt.Sprintf("%s?path=%s", a.getFullAddress("/-"+podcastChaptersPath), url.QueryEscape(p.Path))
+}
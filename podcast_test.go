@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jlelse/feeds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_podcast(t *testing.T) {
+	// Silent MPEG-1 Layer III frames (128 kbit/s, 44.1 kHz), 2000 frames are about 52 seconds
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	mp3 := bytes.Repeat(frame, 2000)
+
+	var downloads atomic.Int32
+	fc := newFakeHttpClient()
+	fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		if r.URL.Path == "/broken.mp3" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Header().Set(contentType, "audio/mpeg")
+		_, _ = rw.Write(mp3)
+	}))
+
+	app := &goBlog{
+		cfg:        createDefaultTestConfig(t),
+		httpClient: fc.Client,
+	}
+	app.cfg.User.Name = "Podcaster"
+	app.cfg.User.Email = "podcaster@example.com"
+	require.NoError(t, app.initConfig(false))
+	require.NoError(t, app.initTemplateStrings())
+	require.NoError(t, app.initCache())
+	app.initMarkdown()
+	app.initSessions()
+
+	// Create podcast section
+	require.NoError(t, app.saveSection(app.cfg.DefaultBlog, &configSection{Name: "podcast", Title: "Podcast", Podcast: true, PodcastCategory: "Technology"}))
+	require.NoError(t, app.loadSections())
+	require.True(t, app.cfg.Blogs[app.cfg.DefaultBlog].Sections["podcast"].Podcast)
+
+	app.d = app.buildRouter()
+
+	require.NoError(t, app.createPost(&post{
+		Path:      "/podcast/episode-1",
+		Section:   "podcast",
+		Published: "2023-01-01T10:00:00Z",
+		Content:   "First episode",
+		Parameters: map[string][]string{
+			"title":      {"Episode 1"},
+			"audio":      {"https://media.example.com/episode-1.mp3"},
+			"images":     {"https://media.example.com/episode-1.jpg"},
+			"chapters":   {"00:00 Intro", "1:02 Main topic", "invalid"},
+			"explicit":   {"true"},
+			"transcript": {"https://media.example.com/episode-1.vtt"},
+		},
+	}))
+	require.NoError(t, app.createPost(&post{
+		Path:      "/podcast/episode-2",
+		Section:   "podcast",
+		Published: "2023-01-02T10:00:00Z",
+		Content:   "Second episode, read aloud",
+		Parameters: map[string][]string{
+			"title": {"Episode 2"},
+			"tts":   {"https://media.example.com/tts.mp3"},
+		},
+	}))
+	require.NoError(t, app.createPost(&post{
+		Path:      "/posts/normal",
+		Section:   "posts",
+		Published: "2023-01-03T10:00:00Z",
+		Content:   "Normal post",
+		Parameters: map[string][]string{
+			"audio": {"https://media.example.com/other.mp3"},
+		},
+	}))
+
+	t.Run("Audio is read in the background", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/podcast.rss", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "<enclosure")
+		assert.Equal(t, int32(0), downloads.Load())
+
+		for _, audio := range []string{"https://media.example.com/episode-1.mp3", "https://media.example.com/tts.mp3"} {
+			queued, err := app.db.queueContains(podcastAudioQueue, []byte(audio))
+			require.NoError(t, err)
+			assert.True(t, queued)
+			require.NoError(t, app.readPodcastAudioInfo(audio))
+		}
+		assert.Equal(t, int32(2), downloads.Load())
+		app.cache.purge()
+	})
+
+	t.Run("Failures are cached", func(t *testing.T) {
+		audio := "https://media.example.com/broken.mp3"
+		assert.Error(t, app.readPodcastAudioInfo(audio))
+		downloadsBefore := downloads.Load()
+
+		p := &post{Path: "/podcast/broken", Section: "podcast", Parameters: map[string][]string{"audio": {audio}}}
+		item := &feeds.Item{}
+		assert.Nil(t, app.addPodcastEnclosure(item, p))
+		assert.Nil(t, item.Enclosure)
+		assert.Equal(t, downloadsBefore, downloads.Load())
+		queued, err := app.db.queueContains(podcastAudioQueue, []byte(audio))
+		require.NoError(t, err)
+		assert.False(t, queued)
+	})
+
+	t.Run("RSS", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/podcast.rss", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+
+		assert.Contains(t, body, `xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`)
+		assert.Contains(t, body, `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+		assert.Contains(t, body, `<itunes:category text="Technology"/>`)
+		assert.Contains(t, body, `<itunes:type>episodic</itunes:type>`)
+		assert.Contains(t, body, `<itunes:image href="http://localhost:8080/profile.jpg"/>`)
+		assert.Contains(t, body, `<enclosure url="https://media.example.com/episode-1.mp3" length="834000" type="audio/mpeg"/>`)
+		assert.Contains(t, body, `<enclosure url="https://media.example.com/tts.mp3" length="834000" type="audio/mpeg"/>`)
+		assert.Contains(t, body, `<itunes:duration>52</itunes:duration>`)
+		assert.Contains(t, body, `<itunes:title>Episode 1</itunes:title>`)
+		assert.Contains(t, body, `<itunes:image href="https://media.example.com/episode-1.jpg"/>`)
+		assert.Contains(t, body, `<itunes:explicit>true</itunes:explicit>`)
+		assert.Contains(t, body, `<podcast:chapters url="http://localhost:8080/-/podcastchapters?path=%2Fpodcast%2Fepisode-1" type="application/json+chapters"/>`)
+		assert.Contains(t, body, `<podcast:transcript url="https://media.example.com/episode-1.vtt" type="text/vtt"/>`)
+		// The post itself is the transcript of TTS audio
+		assert.Contains(t, body, `<podcast:transcript url="http://localhost:8080/podcast/episode-2" type="text/html"/>`)
+		assert.Equal(t, 2, strings.Count(body, "<item>"))
+		// The owner email is opt-in
+		assert.Contains(t, body, `<itunes:owner><itunes:name>Podcaster</itunes:name></itunes:owner>`)
+		assert.NotContains(t, body, "<itunes:email>")
+	})
+
+	t.Run("Owner email", func(t *testing.T) {
+		section := app.cfg.Blogs[app.cfg.DefaultBlog].Sections["podcast"]
+		section.PodcastEmail = true
+		require.NoError(t, app.saveSection(app.cfg.DefaultBlog, section))
+		require.NoError(t, app.loadSections())
+		require.True(t, app.cfg.Blogs[app.cfg.DefaultBlog].Sections["podcast"].PodcastEmail)
+		app.cache.purge()
+
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/podcast.rss", nil))
+		assert.Contains(t, rec.Body.String(), `<itunes:email>podcaster@example.com</itunes:email>`)
+	})
+
+	t.Run("Other feeds", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/podcast.json", nil))
+		assert.Contains(t, rec.Body.String(), `"attachments":[{"url":"https://media.example.com/tts.mp3","mime_type":"audio/mpeg"`)
+
+		// Sections without podcast mode don't have enclosures
+		rec = httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts.rss", nil))
+		assert.NotContains(t, rec.Body.String(), "enclosure")
+		assert.NotContains(t, rec.Body.String(), "itunes")
+	})
+
+	t.Run("Chapters", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/podcastchapters?path=/podcast/episode-1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"chapters":[{"startTime":0,"title":"Intro"},{"startTime":62,"title":"Main topic"}],"version":"1.2.0"}`, strings.TrimSpace(rec.Body.String()))
+
+		rec = httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/podcastchapters?path=/podcast/episode-2", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
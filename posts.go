@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jlelse/feeds"
 	"github.com/samber/lo"
 	"github.com/vcraescu/go-paginator/v2"
 	"go.goblog.app/app/pkgs/bufferpool"
@@ -293,12 +294,63 @@ type indexConfig struct {
 	status           []postStatus
 	visibility       []postVisibility
 	search           string
+	anyParams        []string
+	publishedAfter   time.Time
+	publishedBefore  time.Time
+	filterParams     url.Values // query parameters of filters to keep for pagination and feeds
+}
+
+// Get the parameter filters from the "p:" query parameters
+func indexParamFilters(query url.Values) (params, paramValues []string, paramUrlValues url.Values) {
+	params, paramValues, paramUrlValues = []string{}, []string{}, url.Values{}
+	for param, values := range query {
+		if strings.HasPrefix(param, "p:") {
+			paramKey := strings.TrimPrefix(param, "p:")
+			for _, value := range values {
+				params, paramValues = append(params, paramKey), append(paramValues, value)
+				paramUrlValues.Add(param, value)
+			}
+		}
+	}
+	return
 }
 
 const defaultPhotosPath = "/photos"
 
 const indexConfigKey contextKey = "indexConfig"
 
+// Paging links of feeds (RFC 5005), the first page links to the newest complete archive document
+func (a *goBlog) pagedFeedLinks(p paginator.Paginator, size int, path string, f feedType, query string) *feedPaging {
+	page, _ := p.Page()
+	pages, _ := p.PageNums()
+	if pages < 2 {
+		return nil
+	}
+	paging := &feedPaging{links: []*feeds.AtomLink{{Href: a.feedPageURL(path, 1, f, query), Rel: "first"}}}
+	if page > 1 {
+		paging.links = append(paging.links, &feeds.AtomLink{Href: a.feedPageURL(path, page-1, f, query), Rel: "previous"})
+	}
+	if page < pages {
+		paging.links = append(paging.links, &feeds.AtomLink{Href: a.feedPageURL(path, page+1, f, query), Rel: "next"})
+	}
+	paging.links = append(paging.links, &feeds.AtomLink{Href: a.feedPageURL(path, pages, f, query), Rel: "last"})
+	if total, _ := p.Nums(); page == 1 && int(total) >= size {
+		paging.links = append(paging.links, &feeds.AtomLink{Href: a.feedArchiveURL(path, int(total)/size, f, query), Rel: "prev-archive"})
+	}
+	return paging
+}
+
+func (a *goBlog) feedPageURL(path string, page int, f feedType, query string) string {
+	if page > 1 {
+		path = fmt.Sprintf("%s/page/%d", strings.TrimSuffix(path, "/"), page)
+	}
+	return a.getFullAddress(path+"."+string(f)) + query
+}
+
+func (a *goBlog) feedArchiveURL(path string, archive int, f feedType, query string) string {
+	return a.getFullAddress(fmt.Sprintf("%s/archive/%d.%s", strings.TrimSuffix(path, "/"), archive, f)) + query
+}
+
 func (a *goBlog) serveIndex(w http.ResponseWriter, r *http.Request) {
 	ic := r.Context().Value(indexConfigKey).(*indexConfig)
 	blog, bc := a.getBlog(r)
@@ -316,15 +368,17 @@ func (a *goBlog) serveIndex(w http.ResponseWriter, r *http.Request) {
 		visibility = defaultVisibility
 	}
 	// Parameter filter
-	params, paramValues := []string{}, []string{}
-	paramUrlValues := url.Values{}
-	for param, values := range r.URL.Query() {
-		if strings.HasPrefix(param, "p:") {
-			paramKey := strings.TrimPrefix(param, "p:")
-			for _, value := range values {
-				params, paramValues = append(params, paramKey), append(paramValues, value)
-				paramUrlValues.Add(param, value)
-			}
+	params, paramValues, paramUrlValues := indexParamFilters(r.URL.Query())
+	for param, values := range ic.filterParams {
+		paramUrlValues[param] = values
+	}
+	// Search results are sorted by relevance, feeds always by date
+	ft := feedType(chi.URLParam(r, "feed"))
+	searchDateOrder := false
+	if ic.search != "" && (ft != noFeed || r.URL.Query().Get(searchSortParam) == searchSortDate) {
+		searchDateOrder = true
+		if ft == noFeed {
+			paramUrlValues.Set(searchSortParam, searchSortDate)
 		}
 	}
 	paramUrlQuery := ""
@@ -332,28 +386,74 @@ func (a *goBlog) serveIndex(w http.ResponseWriter, r *http.Request) {
 		paramUrlQuery += "?" + paramUrlValues.Encode()
 	}
 	// Create paginator
-	p := paginator.New(&postPaginationAdapter{config: &postsRequestConfig{
-		blog:           blog,
-		sections:       sections,
-		taxonomy:       ic.tax,
-		taxonomyValue:  ic.taxValue,
-		parameter:      ic.parameter,
-		allParams:      params,
-		allParamValues: paramValues,
-		search:         ic.search,
-		publishedYear:  ic.year,
-		publishedMonth: ic.month,
-		publishedDay:   ic.day,
-		status:         status,
-		visibility:     visibility,
-		priorityOrder:  true,
-	}, a: a}, bc.Pagination)
+	prc := &postsRequestConfig{
+		blog:            blog,
+		sections:        sections,
+		taxonomy:        ic.tax,
+		taxonomyValue:   ic.taxValue,
+		parameter:       ic.parameter,
+		allParams:       params,
+		allParamValues:  paramValues,
+		search:          ic.search,
+		searchDateOrder: searchDateOrder,
+		publishedYear:   ic.year,
+		publishedMonth:  ic.month,
+		publishedDay:    ic.day,
+		status:          status,
+		visibility:      visibility,
+		anyParams:       ic.anyParams,
+		publishedAfter:  ic.publishedAfter,
+		publishedBefore: ic.publishedBefore,
+		priorityOrder:   true,
+	}
+	// Choose the index for searches
+	var searchFuzzy bool
+	if ic.search != "" {
+		fuzzy, err := a.chooseSearchIndex(bc, prc)
+		if err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		searchFuzzy = fuzzy
+	}
+	p := paginator.New(&postPaginationAdapter{config: prc, a: a}, bc.Pagination)
 	p.SetPage(stringToInt(chi.URLParam(r, "page")))
 	var posts []*post
-	err := p.Results(&posts)
-	if err != nil {
-		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
-		return
+	var paging *feedPaging
+	var err error
+	if archiveParam := chi.URLParam(r, "archive"); archiveParam != "" && ft != noFeed {
+		// Archive documents only contain complete pages counted from the oldest post, so new posts don't change them,
+		// but they are only best-effort stable: edited or deleted posts, a changed pagination or blog config do
+		total, _ := p.Nums()
+		archive, complete := stringToInt(archiveParam), int(total)/bc.Pagination
+		if archive < 1 || archive > complete {
+			a.serve404(w, r)
+			return
+		}
+		archivePrc := *prc
+		archivePrc.ascendingOrder = true
+		archivePrc.offset, archivePrc.limit = (archive-1)*bc.Pagination, bc.Pagination
+		if posts, err = a.getPosts(&archivePrc); err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Newest posts first like in the other feeds
+		posts = lo.Reverse(posts)
+		paging = &feedPaging{archive: true, links: []*feeds.AtomLink{{Href: a.feedPageURL(ic.path, 1, ft, paramUrlQuery), Rel: "current"}}}
+		if archive > 1 {
+			paging.links = append(paging.links, &feeds.AtomLink{Href: a.feedArchiveURL(ic.path, archive-1, ft, paramUrlQuery), Rel: "prev-archive"})
+		}
+		if archive < complete {
+			paging.links = append(paging.links, &feeds.AtomLink{Href: a.feedArchiveURL(ic.path, archive+1, ft, paramUrlQuery), Rel: "next-archive"})
+		}
+	} else {
+		if err = p.Results(&posts); err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if ft != noFeed {
+			paging = a.pagedFeedLinks(p, bc.Pagination, ic.path, ft, paramUrlQuery)
+		}
 	}
 	// Title
 	var title string
@@ -375,8 +475,8 @@ func (a *goBlog) serveIndex(w http.ResponseWriter, r *http.Request) {
 		description = ic.section.Description
 	}
 	// Check if feed
-	if ft := feedType(chi.URLParam(r, "feed")); ft != noFeed {
-		a.generateFeed(blog, ft, w, r, posts, title, description, ic.path, paramUrlQuery)
+	if ft != noFeed {
+		a.generateFeed(blog, ft, w, r, posts, title, description, ic.path, paramUrlQuery, ic.section, paging)
 		return
 	}
 	// Navigation
@@ -405,19 +505,46 @@ func (a *goBlog) serveIndex(w http.ResponseWriter, r *http.Request) {
 	if summaryTemplate == "" {
 		summaryTemplate = defaultSummary
 	}
+	// Highlight matches of search results
+	searchHighlights, err := a.db.searchHighlights(ic.search, prc.searchIndex, posts)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Facets to filter search results and suggestion if there are no results
+	var searchFacets []*searchFacet
+	var searchSuggestion string
+	if ic.search != "" {
+		if searchFacets, err = a.searchFacets(r, bc, prc); err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if total, _ := p.Nums(); total == 0 {
+			if searchSuggestion, err = a.db.searchSuggestion(ic.search); err != nil {
+				a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
 	a.render(w, r, a.renderIndex, &renderData{
 		Canonical: a.getFullAddress(ic.path) + paramUrlQuery,
 		Data: &indexRenderData{
-			title:           title,
-			description:     description,
-			posts:           posts,
-			hasPrev:         hasPrev,
-			hasNext:         hasNext,
-			first:           ic.path,
-			prev:            prevPath,
-			next:            nextPath,
-			summaryTemplate: summaryTemplate,
-			paramUrlQuery:   paramUrlQuery,
+			title:            title,
+			description:      description,
+			posts:            posts,
+			hasPrev:          hasPrev,
+			hasNext:          hasNext,
+			first:            ic.path,
+			prev:             prevPath,
+			next:             nextPath,
+			summaryTemplate:  summaryTemplate,
+			paramUrlQuery:    paramUrlQuery,
+			search:           ic.search,
+			searchDateOrder:  searchDateOrder,
+			searchHighlights: searchHighlights,
+			searchFacets:     searchFacets,
+			searchFuzzy:      searchFuzzy,
+			searchSuggestion: searchSuggestion,
 		},
 	})
 }
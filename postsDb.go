@@ -242,8 +242,6 @@ func (db *database) savePost(p *post, o *postCreationOptions) error {
 		}
 		return err
 	}
-	// Update FTS index
-	db.rebuildFTSIndex()
 	return nil
 }
 
@@ -268,8 +266,6 @@ func (a *goBlog) deletePost(path string) error {
 		); err != nil {
 			return err
 		}
-		// Rebuild FTS index
-		a.db.rebuildFTSIndex()
 		// Purge cache
 		a.cache.purge()
 		a.deleteReactionsCache(p.Path)
@@ -289,8 +285,6 @@ func (a *goBlog) deletePost(path string) error {
 		); err != nil {
 			return err
 		}
-		// Rebuild FTS index
-		a.db.rebuildFTSIndex()
 		// Purge cache
 		a.cache.purge()
 		// Trigger hooks
@@ -322,8 +316,6 @@ func (a *goBlog) undeletePost(path string) error {
 	); err != nil {
 		return err
 	}
-	// Rebuild FTS index
-	a.db.rebuildFTSIndex()
 	// Purge cache
 	a.cache.purge()
 	// Trigger hooks
@@ -358,13 +350,13 @@ func (db *database) replacePostParam(path, param string, values []string) error
 	if err != nil {
 		return err
 	}
-	// Update FTS index
-	db.rebuildFTSIndex()
 	return nil
 }
 
 type postsRequestConfig struct {
 	search                                      string
+	searchDateOrder                             bool   // order search results by date instead of relevance
+	searchIndex                                 string // full-text search index to use, default is posts_fts
 	blog                                        string
 	path                                        string
 	limit                                       int
@@ -383,8 +375,10 @@ type postsRequestConfig struct {
 	excludeParameterValue                       string   // ... with exactly this value
 	publishedYear, publishedMonth, publishedDay int
 	publishedBefore                             time.Time
+	publishedAfter                              time.Time
 	randomOrder                                 bool
 	priorityOrder                               bool
+	ascendingOrder                              bool     // oldest posts first
 	fetchWithoutParams                          bool     // fetch posts without parameters
 	fetchParams                                 []string // only fetch these parameters
 	withoutRenderedTitle                        bool     // fetch posts without rendered title
@@ -399,7 +393,9 @@ func buildPostsQuery(c *postsRequestConfig, selection string) (query string, arg
 	queryBuilder.WriteString(" from ")
 	// Table
 	if c.search != "" {
-		queryBuilder.WriteString("(select p.* from posts_fts(@search) ps, posts p where ps.path = p.path)")
+		// Rank with bm25, matches in the title are more important
+		searchIndex := defaultIfEmpty(c.searchIndex, ftsIndexDefault)
+		queryBuilder.WriteString("(select p.*, bm25(" + searchIndex + ", 0.0, 10.0, 1.0) as searchrank from " + searchIndex + " join posts p on " + searchIndex + ".rowid = p.rowid where " + searchIndex + " match @search)")
 		args = append(args, sql.Named("search", c.search))
 	} else {
 		queryBuilder.WriteString("posts")
@@ -476,7 +472,7 @@ func buildPostsQuery(c *postsRequestConfig, selection string) (query string, arg
 			named := "anyparam" + strconv.Itoa(i)
 			queryBuilder.WriteString("@")
 			queryBuilder.WriteString(named)
-			args = append(args, param)
+			args = append(args, sql.Named(named, param))
 		}
 		queryBuilder.WriteString(") and length(coalesce(value, '')) > 0)")
 	}
@@ -522,10 +518,18 @@ func buildPostsQuery(c *postsRequestConfig, selection string) (query string, arg
 		queryBuilder.WriteString(" and toutc(published) < @publishedbefore")
 		args = append(args, sql.Named("publishedbefore", c.publishedBefore.UTC().Format(time.RFC3339)))
 	}
+	if !c.publishedAfter.IsZero() {
+		queryBuilder.WriteString(" and toutc(published) >= @publishedafter")
+		args = append(args, sql.Named("publishedafter", c.publishedAfter.UTC().Format(time.RFC3339)))
+	}
 	// Order
 	queryBuilder.WriteString(" order by ")
 	if c.randomOrder {
 		queryBuilder.WriteString("random()")
+	} else if c.ascendingOrder {
+		queryBuilder.WriteString("published asc")
+	} else if c.search != "" && !c.searchDateOrder {
+		queryBuilder.WriteString("searchrank asc, published desc")
 	} else if c.priorityOrder {
 		queryBuilder.WriteString("priority desc, published desc")
 	} else {
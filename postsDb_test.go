@@ -509,3 +509,69 @@ func Test_checkPost(t *testing.T) {
 	})
 
 }
+
+func Test_ftsIndexUpdates(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	_ = app.initConfig(false)
+	_ = app.initCache()
+	app.initMarkdown()
+
+	search := func(query string) []string {
+		ps, err := app.getPosts(&postsRequestConfig{search: query})
+		require.NoError(t, err)
+		return lo.Map(ps, func(p *post, _ int) string { return p.Path })
+	}
+	consistent := func() {
+		inconsistent, err := app.db.checkFTSIndex()
+		require.NoError(t, err)
+		assert.Equal(t, 0, inconsistent)
+	}
+
+	err := app.createPost(&post{
+		Path:       "/test",
+		Content:    "Apple",
+		Parameters: map[string][]string{"title": {"Banana"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/test"}, search("apple"))
+	assert.Equal(t, []string{"/test"}, search("banana"))
+	consistent()
+
+	// Update content, title and path
+	p, err := app.getPost("/test")
+	require.NoError(t, err)
+	p.Path = "/moved"
+	p.Content = "Cherry"
+	p.Parameters["title"] = []string{"Date"}
+	require.NoError(t, app.replacePost(p, "/test", statusPublished, visibilityPublic))
+	assert.Empty(t, search("apple"))
+	assert.Empty(t, search("banana"))
+	assert.Equal(t, []string{"/moved"}, search("cherry"))
+	assert.Equal(t, []string{"/moved"}, search("date"))
+	consistent()
+
+	// Replace the title parameter only
+	require.NoError(t, app.db.replacePostParam("/moved", "title", []string{"Elderberry"}))
+	assert.Empty(t, search("date"))
+	assert.Equal(t, []string{"/moved"}, search("elderberry"))
+	consistent()
+
+	// Delete post completely
+	require.NoError(t, app.deletePost("/moved"))
+	require.NoError(t, app.deletePost("/moved"))
+	assert.Empty(t, search("cherry"))
+	consistent()
+
+	// Detect outdated index and rebuild it
+	require.NoError(t, app.createPost(&post{Path: "/other", Content: "Fig"}))
+	_, err = app.db.Exec("delete from posts_fts")
+	require.NoError(t, err)
+	inconsistent, err := app.db.checkFTSIndex()
+	require.NoError(t, err)
+	assert.Equal(t, 1, inconsistent)
+	require.NoError(t, app.db.rebuildFTSIndex())
+	assert.Equal(t, []string{"/other"}, search("fig"))
+	consistent()
+}
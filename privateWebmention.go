@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/google/uuid"
+)
+
+// Private Webmention (https://indieweb.org/Private-Webmention)
+const (
+	privateWebmentionCodeExpiration  = 7 * 24 * time.Hour
+	privateWebmentionTokenExpiration = time.Hour
+)
+
+// Create a one-time code for a webmention of a private post, it can be redeemed at our token endpoint
+func (db *database) createPrivateWebmentionCode(path string) (string, error) {
+	code := uuid.NewString()
+	_, err := db.Exec(
+		"insert into privatewebmentioncodes (code, path, expires) values (@code, @path, @expires)",
+		sql.Named("code", code), sql.Named("path", path), sql.Named("expires", time.Now().Add(privateWebmentionCodeExpiration).Unix()),
+	)
+	return code, err
+}
+
+// Redeem a code and create an access token that allows to read the private post, returns an empty token if the code is unknown
+func (db *database) redeemPrivateWebmentionCode(code string) (token string, err error) {
+	row, err := db.QueryRow(
+		"select path from privatewebmentioncodes where code = @code and expires > @now",
+		sql.Named("code", code), sql.Named("now", time.Now().Unix()),
+	)
+	if err != nil {
+		return "", err
+	}
+	var path string
+	if err = row.Scan(&path); errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	// Codes can only be used once
+	result, err := db.Exec("delete from privatewebmentioncodes where code = @code", sql.Named("code", code))
+	if err != nil {
+		return "", err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return "", err
+	}
+	token = uuid.NewString()
+	_, err = db.Exec(
+		"insert into privatewebmentiontokens (token, path, expires) values (@token, @path, @expires)",
+		sql.Named("token", token), sql.Named("path", path), sql.Named("expires", time.Now().Add(privateWebmentionTokenExpiration).Unix()),
+	)
+	return token, err
+}
+
+// Check if the request has a valid access token for the private post
+func (a *goBlog) checkPrivateWebmentionToken(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return false
+	}
+	row, err := a.db.QueryRow(
+		"select exists(select 1 from privatewebmentiontokens where token = @token and lowerunescaped(path) = lowerunescaped(@path) and expires > @now)",
+		sql.Named("token", token), sql.Named("path", r.URL.Path), sql.Named("now", time.Now().Unix()),
+	)
+	if err != nil {
+		return false
+	}
+	var valid bool
+	_ = row.Scan(&valid)
+	return valid
+}
+
+// Token endpoint response for private webmention codes, returns false if the code isn't one
+func (a *goBlog) privateWebmentionTokenResponse(w http.ResponseWriter, r *http.Request) bool {
+	if grantType := r.Form.Get("grant_type"); grantType != "" && grantType != "authorization_code" {
+		return false
+	}
+	token, err := a.db.redeemPrivateWebmentionCode(r.Form.Get("code"))
+	if err != nil || token == "" {
+		return false
+	}
+	a.respondWithMinifiedJson(w, map[string]any{
+		"token_type":   "Bearer",
+		"access_token": token,
+		"expires_in":   int(privateWebmentionTokenExpiration.Seconds()),
+	})
+	return true
+}
+
+// Exchange the code of a received private webmention for an access token at the token endpoint of the source
+func (a *goBlog) privateWebmentionAccessToken(source, code string) (string, error) {
+	endpoint, _, err := a.discoverEndpointRel(source, "token_endpoint")
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = requests.URL(endpoint).Client(a.httpClient).Method(http.MethodPost).
+		BodyForm(url.Values{
+			"grant_type": []string{"authorization_code"},
+			"code":       []string{code},
+			"client_id":  []string{a.getInstanceRootURL()},
+		}).
+		ToJSON(&res).
+		Fetch(ctx); err != nil {
+		return "", err
+	}
+	if res.AccessToken == "" {
+		return "", fmt.Errorf("no access token from %s", endpoint)
+	}
+	return res.AccessToken, nil
+}
+
+func (a *goBlog) deleteExpiredPrivateWebmentionCodes() {
+	now := time.Now().Unix()
+	if _, err := a.db.Exec(
+		"delete from privatewebmentioncodes where expires <= ?; delete from privatewebmentiontokens where expires <= ?;",
+		now, now,
+	); err != nil {
+		log.Println("Failed to delete expired private webmention codes:", err.Error())
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goblog.app/app/pkgs/contenttype"
+)
+
+func Test_privateWebmentionCodes(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initIndieAuth()
+	app.initSessions()
+
+	code, err := app.db.createPrivateWebmentionCode("/private")
+	require.NoError(t, err)
+
+	redeem := func() *httptest.ResponseRecorder {
+		data := url.Values{}
+		data.Set("grant_type", "authorization_code")
+		data.Set("code", code)
+		data.Set("client_id", "https://example.org/")
+		req := httptest.NewRequest(http.MethodPost, "/indieauth/token", strings.NewReader(data.Encode()))
+		req.Header.Set(contentType, contenttype.WWWForm)
+		rec := httptest.NewRecorder()
+		app.indieAuthVerificationToken(rec, req)
+		return rec
+	}
+
+	rec := redeem()
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	token, _ := res["access_token"].(string)
+	require.NotEmpty(t, token)
+	assert.Equal(t, "Bearer", res["token_type"])
+
+	// Codes can only be used once
+	rec = redeem()
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// The token only allows to read the private post
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	assert.True(t, app.checkPrivateWebmentionToken(req))
+	req = httptest.NewRequest(http.MethodGet, "/other", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	assert.False(t, app.checkPrivateWebmentionToken(req))
+	req = httptest.NewRequest(http.MethodGet, "/private", nil)
+	assert.False(t, app.checkPrivateWebmentionToken(req))
+}
+
+func Test_privateWebmentionSendAndReceive(t *testing.T) {
+	fc := newFakeHttpClient()
+
+	app := &goBlog{
+		httpClient: fc.Client,
+		cfg:        createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+
+	// Exchange a received code for an access token
+	fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private":
+			rw.Header().Set("Link", `</token>; rel="token_endpoint"`)
+			rw.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			assert.Equal(t, "received", r.FormValue("code"))
+			assert.Equal(t, "https://example.com/", r.FormValue("client_id"))
+			rw.Header().Set(contentType, contenttype.JSON)
+			_, _ = rw.Write([]byte(`{"access_token":"abc","token_type":"Bearer"}`))
+		}
+	}))
+	token, err := app.privateWebmentionAccessToken("https://example.org/private", "received")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token)
+
+	// Send a code for private posts
+	var sentCode, sentRealm string
+	fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			rw.Header().Set("Link", `</webmention>; rel="webmention"`)
+		case "/webmention":
+			sentCode, sentRealm = r.FormValue("code"), r.FormValue("realm")
+			rw.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	app.processWebmentionSendRequest(&webmentionSendRequest{Path: "/private", Source: "https://example.com/private", Target: "https://example.org/post", Private: true, Realm: "Blog"})
+	assert.NotEmpty(t, sentCode)
+	assert.Equal(t, "Blog", sentRealm)
+
+	redeemed, err := app.db.redeemPrivateWebmentionCode(sentCode)
+	require.NoError(t, err)
+	assert.NotEmpty(t, redeemed)
+
+	// Public posts don't send a code
+	app.processWebmentionSendRequest(&webmentionSendRequest{Path: "/public", Source: "https://example.com/public", Target: "https://example.org/post"})
+	assert.Empty(t, sentCode)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Wait a moment before sending salmentions, so multiple new replies result in just one update
+const salmentionDelay = 5 * time.Minute
+
+var salmentionCommentPathRegex = regexp.MustCompile(regexp.QuoteMeta(commentPath) + `/(\d+)$`)
+
+func (a *goBlog) initSalmentionQueue() {
+	a.listenOnQueue("salmention", time.Minute, func(qi *queueItem, dequeue func(), _ func(time.Duration)) {
+		defer dequeue()
+		p, err := a.getPost(string(qi.content))
+		if err != nil {
+			log.Println("salmention queue:", err.Error())
+			return
+		}
+		if err = a.sendWebmentions(p); err != nil {
+			log.Println("Failed to send salmentions:", err.Error())
+		}
+	})
+}
+
+// Approve a webmention and propagate new replies to the sites the post links to (Salmention)
+func (a *goBlog) approveWebmention(id int) error {
+	m, err := a.db.getWebmentions(&webmentionsRequestConfig{
+		id:    id,
+		limit: 1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(m) == 0 {
+		return errors.New("webmention not found")
+	}
+	if err = a.db.approveWebmentionId(id); err != nil {
+		return err
+	}
+	// Only new replies and comments are shown in the post and result in a salmention
+	if m[0].Status == webmentionStatusApproved || !a.isSalmentionReply(m[0]) {
+		return nil
+	}
+	return a.queueSalmention(m[0])
+}
+
+func (a *goBlog) isSalmentionReply(m *mention) bool {
+	if m.Type == mentionTypeReply {
+		return true
+	}
+	// Local comments
+	return lo.SomeBy(lo.Values(a.cfg.Blogs), func(bc *configBlog) bool {
+		return strings.HasPrefix(m.Source, a.getFullAddress(bc.getRelativePath(commentPath))+"/")
+	})
+}
+
+func (a *goBlog) queueSalmention(m *mention) error {
+	path, err := a.salmentionPostPath(m.Target)
+	if err != nil || path == "" {
+		return err
+	}
+	// Loop protection: ignore replies from sites the post links to itself
+	sent, err := a.db.getSentWebmentions(path)
+	if err != nil {
+		return err
+	}
+	if lo.SomeBy(sent, func(s *sentWebmention) bool {
+		return lowerUnescapedPath(s.Target) == lowerUnescapedPath(m.Source)
+	}) {
+		return nil
+	}
+	// Loop protection: don't queue another salmention for the same post
+	if queued, err := a.db.queueContains("salmention", []byte(path)); err != nil || queued {
+		return err
+	}
+	return a.enqueue("salmention", []byte(path), time.Now().Add(salmentionDelay))
+}
+
+// Get the path of the post the mention belongs to, replies to comments belong to the commented post
+func (a *goBlog) salmentionPostPath(target string) (string, error) {
+	target = strings.TrimPrefix(target, a.cfg.Server.PublicAddress)
+	if matches := salmentionCommentPathRegex.FindStringSubmatch(target); matches != nil {
+		id, _ := strconv.Atoi(matches[1])
+		c, err := a.db.getComment(id)
+		if err != nil || c == nil {
+			return "", err
+		}
+		target = c.Target
+	}
+	row, err := a.db.QueryRow("select path from posts where lowerunescaped(path) = lowerunescaped(@path) limit 1", sql.Named("path", target))
+	if err != nil {
+		return "", err
+	}
+	var path string
+	if err = row.Scan(&path); errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return path, err
+}
+
+func (db *database) queueContains(name string, content []byte) (bool, error) {
+	row, err := db.QueryRow("select exists(select 1 from queue where name = @name and content = @content)", sql.Named("name", name), sql.Named("content", content))
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	err = row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"willnorris.com/go/microformats"
+)
+
+func Test_salmention(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initSessions()
+
+	err = app.createPost(&post{
+		Path:    "/Post",
+		Content: "[Reply](https://example.org/original)",
+	})
+	require.NoError(t, err)
+
+	queued := func() bool {
+		q, err := app.db.queueContains("salmention", []byte("/Post"))
+		require.NoError(t, err)
+		return q
+	}
+
+	// Likes don't result in a salmention
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://example.net/like", Target: "https://example.com/post", Type: mentionTypeLike}, webmentionStatusVerified))
+	require.NoError(t, app.approveWebmention(1))
+	assert.False(t, queued())
+
+	// Replies of the upstream post don't result in a salmention
+	require.NoError(t, app.db.saveSentWebmention(&sentWebmention{Path: "/Post", Target: "https://example.org/original"}))
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://example.org/original", Target: "https://example.com/post", Type: mentionTypeReply}, webmentionStatusVerified))
+	require.NoError(t, app.approveWebmention(2))
+	assert.False(t, queued())
+
+	// New reply
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://example.net/reply", Target: "https://example.com/post", Type: mentionTypeReply}, webmentionStatusVerified))
+	require.NoError(t, app.approveWebmention(3))
+	assert.True(t, queued())
+
+	// Only queued once
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://example.net/reply2", Target: "https://example.com/post", Type: mentionTypeReply}, webmentionStatusVerified))
+	require.NoError(t, app.approveWebmention(4))
+	row, err := app.db.QueryRow("select count(*) from queue where name = 'salmention'")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, row.Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// Replies to comments belong to the commented post
+	_, err = app.db.Exec("insert into comments (target, comment, name, website, parent, status) values ('/Post', 'Comment', 'Name', '', 0, 'approved')")
+	require.NoError(t, err)
+	path, err := app.salmentionPostPath("https://example.com/comment/1")
+	require.NoError(t, err)
+	assert.Equal(t, "/Post", path)
+	path, err = app.salmentionPostPath("https://example.com/unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "", path)
+}
+
+func Test_salmentionMicroformats(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+
+	require.NoError(t, app.initConfig(false))
+	require.NoError(t, app.initTemplateStrings())
+	require.NoError(t, app.initCache())
+	app.initMarkdown()
+	app.initSessions()
+
+	app.cfg.Blogs[app.cfg.DefaultBlog].Comments = &configComments{Enabled: true}
+
+	app.d = app.buildRouter()
+
+	require.NoError(t, app.createPost(&post{Path: "/posts/mf", Section: "posts", Content: "Post"}))
+
+	for _, m := range []*mention{
+		{Source: "https://example.net/reply", Target: "http://localhost:8080/posts/mf", Author: "Replier", AuthorPhoto: "/-/avatar/abc", Content: "Great *post*", Type: mentionTypeReply, Created: 1700000000},
+		{Source: "https://example.org/reply", Target: "https://example.net/reply", Author: "Nested", Content: "Agreed", Type: mentionTypeReply, Created: 1700000100},
+		{Source: "https://example.net/like", Target: "http://localhost:8080/posts/mf", Author: "Liker", AuthorPhoto: "/-/avatar/def", Type: mentionTypeLike, Created: 1700000200},
+	} {
+		require.NoError(t, app.db.insertWebmention(m, webmentionStatusApproved))
+	}
+
+	rec := httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts/mf", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	baseURL, _ := url.Parse("http://localhost:8080/posts/mf")
+	data := microformats.Parse(rec.Body, baseURL)
+
+	var entry *microformats.Microformat
+	for _, item := range data.Items {
+		if len(item.Type) > 0 && item.Type[0] == "h-entry" {
+			entry = item
+		}
+	}
+	require.NotNil(t, entry)
+	// Likes aren't comments and their avatars aren't photos of the post
+	assert.NotContains(t, entry.Properties, "photo")
+
+	require.Len(t, entry.Properties["comment"], 1)
+	reply, ok := entry.Properties["comment"][0].(*microformats.Microformat)
+	require.True(t, ok)
+	assert.Equal(t, []string{"h-cite"}, reply.Type)
+	assert.Equal(t, []any{"https://example.net/reply"}, reply.Properties["url"])
+	assert.Equal(t, []any{"2023-11-14T22:13:20Z"}, reply.Properties["published"])
+	require.Len(t, reply.Properties["content"], 1)
+	assert.Contains(t, reply.Properties["content"][0].(map[string]string)["html"], "<em>post</em>")
+
+	require.Len(t, reply.Properties["author"], 1)
+	author, ok := reply.Properties["author"][0].(*microformats.Microformat)
+	require.True(t, ok)
+	assert.Equal(t, []string{"h-card"}, author.Type)
+	assert.Equal(t, []any{"Replier"}, author.Properties["name"])
+	assert.Len(t, author.Properties["photo"], 1)
+
+	// Replies to replies are nested
+	require.Len(t, reply.Properties["comment"], 1)
+	nested, ok := reply.Properties["comment"][0].(*microformats.Microformat)
+	require.True(t, ok)
+	assert.Equal(t, []any{"https://example.org/reply"}, nested.Properties["url"])
+}
@@ -2,16 +2,45 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
+	"html"
 	"net/http"
+	"net/url"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/samber/lo"
+	"github.com/vcraescu/go-paginator/v2"
+	"go.goblog.app/app/pkgs/builderpool"
 )
 
 const defaultSearchPath = "/search"
 const searchPlaceholder = "{search}"
 
+const (
+	searchSortParam = "sort"
+	searchSortDate  = "date"
+
+	// Markers for matched terms, replaced after escaping the snippets
+	searchMatchStart = "\x02"
+	searchMatchEnd   = "\x03"
+
+	// Maximum number of indexed terms compared to a word of the search for suggestions
+	searchSuggestionCandidates = 1000
+)
+
+// Highlighted title and content snippet of a search result, already escaped HTML
+type searchHighlight struct {
+	title, snippet string
+}
+
 func (a *goBlog) serveSearch(w http.ResponseWriter, r *http.Request) {
 	servePath := r.Context().Value(pathKey).(string)
 	err := r.ParseForm()
@@ -22,8 +51,21 @@ func (a *goBlog) serveSearch(w http.ResponseWriter, r *http.Request) {
 	if q := r.Form.Get("q"); q != "" {
 		// Clean query
 		q = cleanHTMLText(q)
+		// Keep filters
+		filters := url.Values{}
+		for _, param := range []string{searchSectionParam, searchTypeParam, searchFromParam, searchToParam, searchStatusParam, searchVisibilityParam} {
+			for _, value := range r.Form[param] {
+				if value != "" {
+					filters.Add(param, value)
+				}
+			}
+		}
+		resultPath := path.Join(servePath, searchEncode(q))
+		if len(filters) > 0 {
+			resultPath += "?" + filters.Encode()
+		}
 		// Redirect to results
-		http.Redirect(w, r, path.Join(servePath, searchEncode(q)), http.StatusFound)
+		http.Redirect(w, r, resultPath, http.StatusFound)
 		return
 	}
 	a.render(w, r, a.renderSearch, &renderData{
@@ -39,11 +81,116 @@ func (a *goBlog) serveSearchResult(w http.ResponseWriter, r *http.Request) {
 		// Decode and sanitize search
 		decodedSearch = cleanHTMLText(searchDecode(searchParamValue))
 	}
-	// Serve index
-	a.serveIndex(w, r.WithContext(context.WithValue(r.Context(), indexConfigKey, &indexConfig{
+	ic := &indexConfig{
 		path:   r.Context().Value(pathKey).(string) + "/" + searchParamValue,
 		search: decodedSearch,
-	})))
+	}
+	_, bc := a.getBlog(r)
+	ic.filterParams = a.applySearchFilters(r, bc, ic)
+	// Serve index
+	a.serveIndex(w, r.WithContext(context.WithValue(r.Context(), indexConfigKey, ic)))
+}
+
+const searchAPIPath = "/api"
+
+type searchAPIResponse struct {
+	Query      string             `json:"query"`
+	Page       int                `json:"page"`
+	Pages      int                `json:"pages"`
+	Total      int                `json:"total"`
+	Fuzzy      bool               `json:"fuzzy,omitempty"`      // results from the fuzzy search
+	Suggestion string             `json:"suggestion,omitempty"` // similar search if there are no results
+	Results    []*searchAPIResult `json:"results"`
+}
+
+type searchAPIResult struct {
+	Path      string `json:"path"`
+	URL       string `json:"url"`
+	Title     string `json:"title,omitempty"`
+	Snippet   string `json:"snippet,omitempty"` // HTML with matches in <mark> elements
+	Published string `json:"published,omitempty"`
+	Section   string `json:"section,omitempty"`
+}
+
+// JSON search results, supports the same filters as the search results page
+func (a *goBlog) serveSearchAPI(w http.ResponseWriter, r *http.Request) {
+	blog, bc := a.getBlog(r)
+	query := r.URL.Query()
+	search := cleanHTMLText(query.Get("q"))
+	if search == "" {
+		a.serveError(w, r, "missing search query", http.StatusBadRequest)
+		return
+	}
+	ic := &indexConfig{search: search}
+	a.applySearchFilters(r, bc, ic)
+	status, visibility := a.getDefaultPostStates(r)
+	if len(ic.status) > 0 {
+		status = ic.status
+	}
+	if len(ic.visibility) > 0 {
+		visibility = ic.visibility
+	}
+	params, paramValues, _ := indexParamFilters(query)
+	prc := &postsRequestConfig{
+		blog:            blog,
+		sections:        lo.Map(ic.sections, func(s *configSection, _ int) string { return s.Name }),
+		allParams:       params,
+		allParamValues:  paramValues,
+		anyParams:       ic.anyParams,
+		search:          search,
+		searchDateOrder: query.Get(searchSortParam) == searchSortDate,
+		publishedAfter:  ic.publishedAfter,
+		publishedBefore: ic.publishedBefore,
+		status:          status,
+		visibility:      visibility,
+		priorityOrder:   true,
+	}
+	fuzzy, err := a.chooseSearchIndex(bc, prc)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p := paginator.New(&postPaginationAdapter{config: prc, a: a}, bc.Pagination)
+	p.SetPage(stringToInt(query.Get("page")))
+	var posts []*post
+	if err := p.Results(&posts); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	highlights, err := a.db.searchHighlights(prc.search, prc.searchIndex, posts)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page, _ := p.Page()
+	pages, _ := p.PageNums()
+	total, _ := p.Nums()
+	res := &searchAPIResponse{
+		Query: search, Page: page, Pages: pages, Total: int(total), Fuzzy: fuzzy,
+		Results: []*searchAPIResult{},
+	}
+	if total == 0 {
+		if res.Suggestion, err = a.db.searchSuggestion(search); err != nil {
+			a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	for _, rp := range posts {
+		result := &searchAPIResult{
+			Path:      rp.Path,
+			URL:       a.fullPostURL(rp),
+			Title:     rp.RenderedTitle,
+			Published: rp.Published,
+			Section:   rp.Section,
+		}
+		if hl, ok := highlights[rp.Path]; ok && strings.Contains(hl.snippet, "<mark>") {
+			result.Snippet = hl.snippet
+		} else {
+			result.Snippet = html.EscapeString(a.postSummary(rp))
+		}
+		res.Results = append(res.Results, result)
+	}
+	a.respondWithMinifiedJson(w, res)
 }
 
 func searchEncode(search string) string {
@@ -57,3 +204,407 @@ func searchDecode(encoded string) string {
 	}
 	return string(db)
 }
+
+// Get highlighted titles and content snippets for the search results
+func (db *database) searchHighlights(search, index string, posts []*post) (map[string]*searchHighlight, error) {
+	highlights := map[string]*searchHighlight{}
+	if search == "" || len(posts) == 0 {
+		return highlights, nil
+	}
+	queryBuilder := builderpool.Get()
+	defer builderpool.Put(queryBuilder)
+	index = defaultIfEmpty(index, ftsIndexDefault)
+	if index == ftsIndexStemmed {
+		if search, index = searchStemPrefixQuery(search), ftsIndexDefault; search == "" {
+			return highlights, nil
+		}
+	}
+	queryBuilder.WriteString("select path, highlight(" + index + ", 1, @start, @end), snippet(" + index + ", 2, @start, @end, '…', 32) from " + index + " where " + index + " match @search and path in (")
+	args := []any{sql.Named("start", searchMatchStart), sql.Named("end", searchMatchEnd), sql.Named("search", search)}
+	for i, p := range posts {
+		if i > 0 {
+			queryBuilder.WriteString(", ")
+		}
+		named := "path" + strconv.Itoa(i)
+		queryBuilder.WriteString("@")
+		queryBuilder.WriteString(named)
+		args = append(args, sql.Named(named, p.Path))
+	}
+	queryBuilder.WriteString(")")
+	rows, err := db.Query(queryBuilder.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path, title, snippet string
+		if err = rows.Scan(&path, &title, &snippet); err != nil {
+			return nil, err
+		}
+		highlights[path] = &searchHighlight{
+			title:   highlightSearchMatches(title),
+			snippet: highlightSearchMatches(snippet),
+		}
+	}
+	return highlights, rows.Err()
+}
+
+// Escape the text and highlight the matched terms
+func highlightSearchMatches(text string) string {
+	return strings.NewReplacer(searchMatchStart, "<mark>", searchMatchEnd, "</mark>").Replace(html.EscapeString(text))
+}
+
+// Query parameters to filter search results, taxonomies use the generic "p:" parameters
+const (
+	searchSectionParam    = "section"
+	searchTypeParam       = "type"
+	searchFromParam       = "from"
+	searchToParam         = "to"
+	searchStatusParam     = "status"
+	searchVisibilityParam = "visibility"
+
+	searchFacetValuesLimit = 10
+)
+
+// Status and visibility logged in users can filter search results by
+var (
+	searchStatuses     = []postStatus{statusPublished, statusDraft, statusScheduled}
+	searchVisibilities = []postVisibility{visibilityPublic, visibilityUnlisted, visibilityPrivate}
+)
+
+// Post type to filter search results, identified by a non-empty parameter
+type searchPostType struct {
+	name, param string
+}
+
+func (a *goBlog) searchPostTypes() []*searchPostType {
+	mc := a.cfg.Micropub
+	return lo.Filter([]*searchPostType{
+		{"reply", mc.ReplyParam},
+		{"like", mc.LikeParam},
+		{"repost", mc.RepostParam},
+		{"bookmark", mc.BookmarkParam},
+		{"photo", mc.PhotoParam},
+		{"audio", mc.AudioParam},
+	}, func(t *searchPostType, _ int) bool { return t.param != "" })
+}
+
+// Apply the filters of the search request to the index config and return the query parameters of the valid filters
+func (a *goBlog) applySearchFilters(r *http.Request, bc *configBlog, ic *indexConfig) url.Values {
+	query := r.URL.Query()
+	filters := url.Values{}
+	for _, section := range query[searchSectionParam] {
+		if s, ok := bc.Sections[section]; ok {
+			ic.sections = append(ic.sections, s)
+			filters.Add(searchSectionParam, section)
+		}
+	}
+	postTypes := a.searchPostTypes()
+	for _, typ := range query[searchTypeParam] {
+		if pt, ok := lo.Find(postTypes, func(t *searchPostType) bool { return t.name == typ }); ok {
+			ic.anyParams = append(ic.anyParams, pt.param)
+			filters.Add(searchTypeParam, typ)
+		}
+	}
+	if from, err := time.ParseInLocation(time.DateOnly, query.Get(searchFromParam), time.Local); err == nil {
+		ic.publishedAfter = from
+		filters.Set(searchFromParam, from.Format(time.DateOnly))
+	}
+	if to, err := time.ParseInLocation(time.DateOnly, query.Get(searchToParam), time.Local); err == nil {
+		// Including the whole day
+		ic.publishedBefore = to.AddDate(0, 0, 1)
+		filters.Set(searchToParam, to.Format(time.DateOnly))
+	}
+	if a.isLoggedIn(r) {
+		for _, status := range query[searchStatusParam] {
+			if s := postStatus(status); lo.Contains(searchStatuses, s) {
+				ic.status = append(ic.status, s)
+				filters.Add(searchStatusParam, status)
+			}
+		}
+		for _, visibility := range query[searchVisibilityParam] {
+			if v := postVisibility(visibility); lo.Contains(searchVisibilities, v) {
+				ic.visibility = append(ic.visibility, v)
+				filters.Add(searchVisibilityParam, visibility)
+			}
+		}
+	}
+	return filters
+}
+
+// Filter option of search results with the number of matching posts
+type searchFacet struct {
+	param, title string
+	values       []*searchFacetValue
+}
+
+type searchFacetValue struct {
+	value, title string
+	count        int
+}
+
+// Get the facets for the search results, the counts of each facet ignore the filter of the facet itself
+func (a *goBlog) searchFacets(r *http.Request, bc *configBlog, c *postsRequestConfig) ([]*searchFacet, error) {
+	facets := []*searchFacet{}
+	// Sections
+	sc := *c
+	sc.sections = nil
+	sectionCounts, err := a.db.countSearchFacetColumn(&sc, "section")
+	if err != nil {
+		return nil, err
+	}
+	sectionFacet := &searchFacet{param: searchSectionParam, title: a.ts.GetTemplateStringVariant(bc.Lang, "searchsection")}
+	for _, sectionCount := range sectionCounts {
+		if section, ok := bc.Sections[sectionCount.value]; ok {
+			sectionCount.title = a.renderMdTitle(defaultIfEmpty(section.Title, section.Name))
+			sectionFacet.values = append(sectionFacet.values, sectionCount)
+		}
+	}
+	facets = append(facets, sectionFacet)
+	// Post types
+	tc := *c
+	tc.anyParams = nil
+	postTypes := a.searchPostTypes()
+	typeCounts, err := a.db.countSearchFacetParams(&tc, lo.Map(postTypes, func(t *searchPostType, _ int) string { return t.param }), false)
+	if err != nil {
+		return nil, err
+	}
+	typeFacet := &searchFacet{param: searchTypeParam, title: a.ts.GetTemplateStringVariant(bc.Lang, "searchtype")}
+	for _, pt := range postTypes {
+		if count := typeCounts[pt.param][""]; count > 0 {
+			typeFacet.values = append(typeFacet.values, &searchFacetValue{
+				value: pt.name, title: a.ts.GetTemplateStringVariant(bc.Lang, "searchtype"+pt.name), count: count,
+			})
+		}
+	}
+	facets = append(facets, typeFacet)
+	// Taxonomies
+	taxCounts, err := a.db.countSearchFacetParams(c, lo.Map(bc.Taxonomies, func(t *configTaxonomy, _ int) string { return t.Name }), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, tax := range bc.Taxonomies {
+		taxFacet := &searchFacet{param: "p:" + tax.Name, title: a.renderMdTitle(tax.Title)}
+		for value, count := range taxCounts[tax.Name] {
+			taxFacet.values = append(taxFacet.values, &searchFacetValue{value: value, title: a.renderMdTitle(value), count: count})
+		}
+		sortSearchFacetValues(taxFacet.values)
+		if len(taxFacet.values) > searchFacetValuesLimit {
+			taxFacet.values = taxFacet.values[:searchFacetValuesLimit]
+		}
+		facets = append(facets, taxFacet)
+	}
+	// Status and visibility only for logged in users
+	if a.isLoggedIn(r) {
+		for _, column := range []string{searchStatusParam, searchVisibilityParam} {
+			cc := *c
+			if column == searchStatusParam {
+				cc.status = searchStatuses
+			} else {
+				cc.visibility = searchVisibilities
+			}
+			counts, err := a.db.countSearchFacetColumn(&cc, column)
+			if err != nil {
+				return nil, err
+			}
+			for _, count := range counts {
+				count.title = count.value
+			}
+			facets = append(facets, &searchFacet{param: column, title: a.ts.GetTemplateStringVariant(bc.Lang, column), values: counts})
+		}
+	}
+	// Remove facets without values
+	return lo.Filter(facets, func(f *searchFacet, _ int) bool { return len(f.values) > 0 }), nil
+}
+
+// Count the search results grouped by a column of the posts table
+func (db *database) countSearchFacetColumn(c *postsRequestConfig, column string) ([]*searchFacetValue, error) {
+	query, args, err := buildPostsQuery(c, "path, "+column)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("select "+column+", count(distinct path) from ("+query+") group by "+column+" order by 2 desc, 1", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := []*searchFacetValue{}
+	for rows.Next() {
+		value := &searchFacetValue{}
+		if err = rows.Scan(&value.value, &value.count); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// Count the search results that have the parameters, grouped by parameter and optionally also by value
+func (db *database) countSearchFacetParams(c *postsRequestConfig, params []string, byValue bool) (map[string]map[string]int, error) {
+	counts := map[string]map[string]int{}
+	if len(params) == 0 {
+		return counts, nil
+	}
+	query, args, err := buildPostsQuery(c, "path")
+	if err != nil {
+		return nil, err
+	}
+	queryBuilder := builderpool.Get()
+	defer builderpool.Put(queryBuilder)
+	queryBuilder.WriteString("select parameter, ")
+	if byValue {
+		queryBuilder.WriteString("min(value)")
+	} else {
+		queryBuilder.WriteString("''")
+	}
+	queryBuilder.WriteString(", count(distinct path) from post_parameters where length(coalesce(value, '')) > 0 and parameter in (")
+	for i, param := range params {
+		if i > 0 {
+			queryBuilder.WriteString(", ")
+		}
+		named := "facetparam" + strconv.Itoa(i)
+		queryBuilder.WriteString("@")
+		queryBuilder.WriteString(named)
+		args = append(args, sql.Named(named, param))
+	}
+	queryBuilder.WriteString(") and path in (select path from (")
+	queryBuilder.WriteString(query)
+	queryBuilder.WriteString(")) group by parameter")
+	if byValue {
+		queryBuilder.WriteString(", lowerx(value)")
+	}
+	rows, err := db.Query(queryBuilder.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var param, value string
+		var count int
+		if err = rows.Scan(&param, &value, &count); err != nil {
+			return nil, err
+		}
+		if counts[param] == nil {
+			counts[param] = map[string]int{}
+		}
+		counts[param][value] = count
+	}
+	return counts, rows.Err()
+}
+
+func sortSearchFacetValues(values []*searchFacetValue) {
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].count != values[j].count {
+			return values[i].count > values[j].count
+		}
+		return values[i].value < values[j].value
+	})
+}
+
+// Choose the full-text search index: the stemmed index with a stemmed search if there's a stemmer
+// for the blog language, and the trigram index if fuzzy search is enabled and there are no other results.
+// Returns true if the results are fuzzy.
+func (a *goBlog) chooseSearchIndex(bc *configBlog, c *postsRequestConfig) (fuzzy bool, err error) {
+	search := c.search
+	c.searchIndex = ftsIndexDefault
+	if lang := searchStemLanguage(bc.Lang); lang != "" {
+		c.searchIndex = ftsIndexStemmed
+		c.search = stemSearchWords(search, lang, true)
+	}
+	if bc.Search == nil || !bc.Search.Fuzzy {
+		return false, nil
+	}
+	count, err := a.db.countPosts(c)
+	if err != nil || count > 0 {
+		return false, err
+	}
+	c.searchIndex, c.search = ftsIndexTrigram, search
+	return true, nil
+}
+
+// Suggest a search with unknown words replaced by similar words from the indexed vocabulary,
+// returns an empty string if there's no suggestion
+func (db *database) searchSuggestion(search string) (string, error) {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	changed := false
+	for i, word := range words {
+		if lo.Contains(searchOperators, word) {
+			// Keep FTS5 operators
+			continue
+		}
+		word = strings.ToLower(word)
+		words[i] = word
+		length := utf8.RuneCountInString(word)
+		if length < 3 {
+			continue
+		}
+		// Allow one typo for short words and two for longer ones
+		maxDistance := lo.Ternary(length > 5, 2, 1)
+		// Only check the most common terms with the same first letter, the vocabulary can use the term range as index
+		first, _ := utf8.DecodeRuneInString(word)
+		rows, err := db.Query(
+			"select term from posts_fts_vocab where term >= @from and term < @to and length(term) between @min and @max order by doc desc limit @limit",
+			sql.Named("from", string(first)), sql.Named("to", string(first+1)),
+			sql.Named("min", length-maxDistance), sql.Named("max", length+maxDistance),
+			sql.Named("limit", searchSuggestionCandidates),
+		)
+		if err != nil {
+			return "", err
+		}
+		best, bestDistance := "", maxDistance+1
+		for rows.Next() {
+			var term string
+			if err = rows.Scan(&term); err != nil {
+				_ = rows.Close()
+				return "", err
+			}
+			if distance := editDistance(word, term); distance < bestDistance {
+				// Terms are sorted by the number of posts, so the most common one wins
+				best, bestDistance = term, distance
+				if distance == 0 {
+					break
+				}
+			}
+		}
+		_ = rows.Close()
+		if err = rows.Err(); err != nil {
+			return "", err
+		}
+		if best != "" && bestDistance > 0 {
+			words[i] = best
+			changed = true
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// Edit distance of two strings, swapped adjacent characters count as one edit (optimal string alignment)
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	d := make([][]int, len(ar)+1)
+	for i := range d {
+		d[i] = make([]int, len(br)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ar)][len(br)]
+}
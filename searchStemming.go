@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/arabic"
+	"github.com/blevesearch/snowballstem/danish"
+	"github.com/blevesearch/snowballstem/dutch"
+	"github.com/blevesearch/snowballstem/english"
+	"github.com/blevesearch/snowballstem/finnish"
+	"github.com/blevesearch/snowballstem/french"
+	"github.com/blevesearch/snowballstem/german"
+	"github.com/blevesearch/snowballstem/hungarian"
+	"github.com/blevesearch/snowballstem/irish"
+	"github.com/blevesearch/snowballstem/italian"
+	"github.com/blevesearch/snowballstem/norwegian"
+	"github.com/blevesearch/snowballstem/portuguese"
+	"github.com/blevesearch/snowballstem/romanian"
+	"github.com/blevesearch/snowballstem/russian"
+	"github.com/blevesearch/snowballstem/spanish"
+	"github.com/blevesearch/snowballstem/swedish"
+	"github.com/blevesearch/snowballstem/tamil"
+	"github.com/blevesearch/snowballstem/turkish"
+	"github.com/samber/lo"
+	"go.goblog.app/app/pkgs/builderpool"
+)
+
+// Persistent cache key for the blog languages the stemmed search index was built with
+const searchStemLanguagesCacheKey = "searchstemlanguages"
+
+type searchStemmer func(env *snowballstem.Env) bool
+
+// Snowball stemmers by language code
+var searchStemmers = map[string]searchStemmer{
+	"ar": arabic.Stem, "da": danish.Stem, "de": german.Stem, "en": english.Stem, "es": spanish.Stem,
+	"fi": finnish.Stem, "fr": french.Stem, "ga": irish.Stem, "hu": hungarian.Stem, "it": italian.Stem,
+	"nb": norwegian.Stem, "nl": dutch.Stem, "nn": norwegian.Stem, "no": norwegian.Stem, "pt": portuguese.Stem,
+	"ro": romanian.Stem, "ru": russian.Stem, "sv": swedish.Stem, "ta": tamil.Stem, "tr": turkish.Stem,
+}
+
+// FTS5 operators, they aren't stemmed or used for suggestions
+var searchOperators = []string{"AND", "OR", "NOT", "NEAR"}
+
+// Get the language code of the stemmer for a blog language like "de" or "en-US", empty if there's no stemmer
+func searchStemLanguage(lang string) string {
+	code, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	if _, ok := searchStemmers[code]; !ok {
+		return ""
+	}
+	return code
+}
+
+// Stem the words of a post of the blog, used by the stem() SQL function to fill the stemmed search index
+func (a *goBlog) stemSearchText(text, blog string) string {
+	bc, ok := a.cfg.Blogs[blog]
+	if !ok {
+		return text
+	}
+	return stemSearchWords(text, searchStemLanguage(bc.Lang), false)
+}
+
+func isSearchWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// Replace every word with its lowercase stem, the other characters are kept.
+// Queries keep FTS5 operators, prefix queries ("word*") and column filters ("title:").
+func stemSearchWords(text, lang string, query bool) string {
+	stemmer, ok := searchStemmers[lang]
+	if !ok {
+		return text
+	}
+	env := snowballstem.NewEnv("")
+	b := builderpool.Get()
+	defer builderpool.Put(b)
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isSearchWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && isSearchWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		i = end
+		if query && (lo.Contains(searchOperators, word) || (end < len(runes) && (runes[end] == '*' || runes[end] == ':'))) {
+			b.WriteString(word)
+			continue
+		}
+		env.SetCurrent(strings.ToLower(word))
+		stemmer(env)
+		b.WriteString(defaultIfEmpty(env.Current(), strings.ToLower(word)))
+	}
+	return b.String()
+}
+
+// The stemmed index contains the stems, so the matches are highlighted
+// in the default index with prefix queries of the stems of the search
+func searchStemPrefixQuery(stemmedSearch string) string {
+	words := strings.FieldsFunc(stemmedSearch, func(r rune) bool { return !isSearchWordRune(r) })
+	words = lo.Uniq(lo.Filter(words, func(w string, _ int) bool { return !lo.Contains(searchOperators, w) }))
+	return strings.Join(lo.Map(words, func(w string, _ int) string { return `"` + w + `"*` }), " OR ")
+}
+
+// Get the stemmer languages of all blogs, the stemmed index has to be rebuilt when they change
+func (a *goBlog) searchStemLanguages() string {
+	languages := lo.MapToSlice(a.cfg.Blogs, func(blog string, bc *configBlog) string {
+		return blog + ":" + searchStemLanguage(bc.Lang)
+	})
+	sort.Strings(languages)
+	return strings.Join(languages, ",")
+}
+
+// Rebuild the stemmed search index if the languages of the blogs changed since it was built
+func (a *goBlog) updateStemmedSearchIndex() error {
+	languages := a.searchStemLanguages()
+	cached, err := a.db.retrievePersistentCache(searchStemLanguagesCacheKey)
+	if err != nil {
+		return err
+	}
+	if string(cached) == languages {
+		return nil
+	}
+	if err = a.db.rebuildFTSIndexes(ftsIndexStemmed); err != nil {
+		return fmt.Errorf("failed to rebuild stemmed search index: %w", err)
+	}
+	return a.db.cachePersistently(searchStemLanguagesCacheKey, []byte(languages))
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_searchEncoding(t *testing.T) {
@@ -13,3 +23,336 @@ func Test_searchEncoding(t *testing.T) {
 	assert.Equal(t, testString, searchDecode(searchEncode(testString)))
 
 }
+
+func Test_searchRankingAndHighlights(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	_ = app.initConfig(false)
+	app.initMarkdown()
+
+	for i, p := range []*post{
+		{Path: "/content", Content: "Something about Go & more"},
+		{Path: "/title", Content: "Nothing", Parameters: map[string][]string{"title": {"Go"}}},
+	} {
+		p.Published = toLocalSafe(time.Now().Add(-time.Duration(i) * time.Hour).String())
+		p.Blog, p.Section, p.Status, p.Visibility = "en", "test", statusPublished, visibilityPublic
+		require.NoError(t, app.db.savePost(p, &postCreationOptions{new: true}))
+	}
+
+	// Matches in the title rank higher
+	ps, err := app.getPosts(&postsRequestConfig{search: "go"})
+	require.NoError(t, err)
+	require.Len(t, ps, 2)
+	assert.Equal(t, "/title", ps[0].Path)
+
+	// Sort by date
+	ps, err = app.getPosts(&postsRequestConfig{search: "go", searchDateOrder: true})
+	require.NoError(t, err)
+	require.Len(t, ps, 2)
+	assert.Equal(t, "/content", ps[0].Path)
+
+	// Highlights are escaped
+	hls, err := app.db.searchHighlights("go", "", ps)
+	require.NoError(t, err)
+	require.Contains(t, hls, "/content")
+	assert.Equal(t, "Something about <mark>Go</mark> &amp; more", hls["/content"].snippet)
+	assert.Equal(t, "<mark>Go</mark>", hls["/title"].title)
+}
+
+func Test_searchFilters(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	_ = app.initConfig(false)
+	_ = app.initTemplateStrings()
+	_ = app.initCache()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Sections["notes"] = &configSection{Name: "notes", Title: "Notes"}
+	bc.Search = &configSearch{Enabled: true, Title: "Search"}
+
+	for i, p := range []*post{
+		{Path: "/a", Section: "posts", Content: "Test", Parameters: map[string][]string{"tags": {"Go", "Web"}}},
+		{Path: "/b", Section: "notes", Content: "Test", Parameters: map[string][]string{"tags": {"go"}, "replylink": {"https://example.org/"}}},
+		{Path: "/c", Section: "notes", Content: "Test"},
+		{Path: "/d", Section: "notes", Content: "Test", Status: statusDraft},
+	} {
+		p.Published = toLocalSafe(time.Date(2023, 1, 1+i, 12, 0, 0, 0, time.Local).String())
+		p.Blog, p.Visibility = app.cfg.DefaultBlog, visibilityPublic
+		if p.Status == statusNil {
+			p.Status = statusPublished
+		}
+		require.NoError(t, app.db.savePost(p, &postCreationOptions{new: true}))
+	}
+
+	// Filters are applied to the index config
+	ic := &indexConfig{search: "test"}
+	req := httptest.NewRequest(http.MethodGet, "/search/abc?section=notes&section=invalid&type=reply&from=2023-01-02&to=2023-01-03&status=draft", nil)
+	filters := app.applySearchFilters(req, bc, ic)
+	assert.Equal(t, url.Values{"section": {"notes"}, "type": {"reply"}, "from": {"2023-01-02"}, "to": {"2023-01-03"}}, filters)
+	assert.Equal(t, []string{"replylink"}, ic.anyParams)
+	assert.Nil(t, ic.status)
+
+	ps, err := app.getPosts(&postsRequestConfig{
+		search: "test", sections: []string{"notes"}, anyParams: ic.anyParams,
+		publishedAfter: ic.publishedAfter, publishedBefore: ic.publishedBefore,
+		status: []postStatus{statusPublished},
+	})
+	require.NoError(t, err)
+	require.Len(t, ps, 1)
+	assert.Equal(t, "/b", ps[0].Path)
+
+	// Facets count the results without the filter of the facet itself
+	req = httptest.NewRequest(http.MethodGet, "/search/abc", nil)
+	facets, err := app.searchFacets(req, bc, &postsRequestConfig{
+		search: "test", sections: []string{"notes"}, status: []postStatus{statusPublished},
+	})
+	require.NoError(t, err)
+	require.Len(t, facets, 3)
+	assert.Equal(t, "section", facets[0].param)
+	assert.Equal(t, []*searchFacetValue{{value: "notes", title: "Notes", count: 2}, {value: "posts", title: "Posts", count: 1}}, facets[0].values)
+	assert.Equal(t, "type", facets[1].param)
+	require.Len(t, facets[1].values, 1)
+	assert.Equal(t, 1, facets[1].values[0].count)
+	assert.Equal(t, "p:tags", facets[2].param)
+	require.Len(t, facets[2].values, 1)
+	assert.Equal(t, "go", facets[2].values[0].value)
+
+	// Search form keeps filters
+	form := url.Values{"q": {"test"}, "section": {"notes"}, "type": {""}}
+	req = httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(context.WithValue(req.Context(), pathKey, "/search"))
+	rec := httptest.NewRecorder()
+	app.serveSearch(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/search/"+searchEncode("test")+"?section=notes", rec.Header().Get("Location"))
+
+	// Filtered results and feeds
+	app.d = app.buildRouter()
+	rec = httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/"+searchEncode("test")+"?section=notes", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `href=/c>`)
+	assert.NotContains(t, body, `href=/a>`)
+	assert.Contains(t, body, `.rss?section=notes`)
+	assert.Contains(t, body, `<a href="/search/dGVzdA==" rel=nofollow><mark>✕ Notes`)
+
+	rec = httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/"+searchEncode("test")+".rss?section=posts", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/a</link>")
+	assert.NotContains(t, rec.Body.String(), "/c</link>")
+}
+
+func Test_searchAPI(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	_ = app.initConfig(false)
+	_ = app.initTemplateStrings()
+	_ = app.initCache()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Search = &configSearch{Enabled: true, Title: "Search"}
+	bc.Pagination = 2
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, app.createPost(&post{
+			Path:       fmt.Sprintf("/post%d", i),
+			Section:    "posts",
+			Status:     statusPublished,
+			Published:  toLocalSafe(time.Date(2023, 1, 1+i, 12, 0, 0, 0, time.Local).String()),
+			Parameters: map[string][]string{"title": {fmt.Sprintf("Post %d", i)}},
+			Content:    "Search <b>test</b>",
+		}))
+	}
+
+	app.d = app.buildRouter()
+
+	var res searchAPIResponse
+	rec := httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/api?q=test&sort=date&page=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, "test", res.Query)
+	assert.Equal(t, 2, res.Page)
+	assert.Equal(t, 2, res.Pages)
+	assert.Equal(t, 3, res.Total)
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "/post0", res.Results[0].Path)
+	assert.Equal(t, "http://localhost:8080/post0", res.Results[0].URL)
+	assert.Equal(t, "Post 0", res.Results[0].Title)
+	assert.Equal(t, "posts", res.Results[0].Section)
+	assert.Equal(t, "Search &lt;b&gt;<mark>test</mark>&lt;/b&gt;", res.Results[0].Snippet)
+
+	// Query is required
+	rec = httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/api", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Search page uses the API for instant search
+	rec = httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+	assert.Contains(t, rec.Body.String(), "data-instantsearch=/search/api")
+}
+
+func Test_searchFuzzyAndSuggestion(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	_ = app.initConfig(false)
+	_ = app.initTemplateStrings()
+	_ = app.initCache()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Search = &configSearch{Enabled: true, Title: "Search", Fuzzy: true}
+	require.NoError(t, app.db.setTrigramIndex(true))
+
+	require.NoError(t, app.createPost(&post{
+		Path:       "/golang",
+		Section:    "posts",
+		Status:     statusPublished,
+		Parameters: map[string][]string{"title": {"Learning Golang"}},
+		Content:    "Running a blog",
+	}))
+
+	search := func(query string) ([]string, bool) {
+		prc := &postsRequestConfig{search: query}
+		fuzzy, err := app.chooseSearchIndex(bc, prc)
+		require.NoError(t, err)
+		ps, err := app.getPosts(prc)
+		require.NoError(t, err)
+		return lo.Map(ps, func(p *post, _ int) string { return p.Path }), fuzzy
+	}
+
+	// English blogs use stemming
+	paths, fuzzy := search("run")
+	assert.Equal(t, []string{"/golang"}, paths)
+	assert.False(t, fuzzy)
+
+	// Partial words are found with the trigram index
+	paths, fuzzy = search("golan")
+	assert.Equal(t, []string{"/golang"}, paths)
+	assert.True(t, fuzzy)
+
+	// Suggestion for typos
+	paths, _ = search("golnag")
+	assert.Empty(t, paths)
+	suggestion, err := app.db.searchSuggestion("Golnag AND blgo")
+	require.NoError(t, err)
+	assert.Equal(t, "golang AND blog", suggestion)
+	suggestion, err = app.db.searchSuggestion("golang")
+	require.NoError(t, err)
+	assert.Empty(t, suggestion)
+	// Only terms with the same first letter are candidates
+	suggestion, err = app.db.searchSuggestion("bolang")
+	require.NoError(t, err)
+	assert.Empty(t, suggestion)
+
+	app.d = app.buildRouter()
+	rec := httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/"+searchEncode("golnag"), nil))
+	assert.Contains(t, rec.Body.String(), "/search/"+searchEncode("golang"))
+
+	// Trigram index is kept up to date and can be removed
+	inconsistent, err := app.db.checkFTSIndex()
+	require.NoError(t, err)
+	assert.Equal(t, 0, inconsistent)
+	require.NoError(t, app.db.setTrigramIndex(false))
+	indexes, err := app.db.ftsIndexes()
+	require.NoError(t, err)
+	assert.Equal(t, []string{ftsIndexDefault, ftsIndexStemmed}, indexes)
+}
+
+func Test_searchStemming(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	_ = app.initConfig(false)
+	_ = app.initTemplateStrings()
+	_ = app.initCache()
+	app.initMarkdown()
+	app.initSessions()
+
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	bc.Search = &configSearch{Enabled: true, Title: "Suche"}
+
+	require.NoError(t, app.createPost(&post{
+		Path:       "/fahrraeder",
+		Section:    "posts",
+		Status:     statusPublished,
+		Parameters: map[string][]string{"title": {"Unsere Fahrräder"}},
+		Content:    "Die Häuser der Stadt",
+	}))
+
+	search := func(query string) []string {
+		prc := &postsRequestConfig{search: query}
+		_, err := app.chooseSearchIndex(bc, prc)
+		require.NoError(t, err)
+		ps, err := app.getPosts(prc)
+		require.NoError(t, err)
+		return lo.Map(ps, func(p *post, _ int) string { return p.Path })
+	}
+
+	// Posts were stemmed in English, changing the language rebuilds the stemmed index
+	assert.Empty(t, search("Fahrrad"))
+	bc.Lang = "de-DE"
+	require.NoError(t, app.updateStemmedSearchIndex())
+	assert.Equal(t, []string{"/fahrraeder"}, search("Fahrrad"))
+	assert.Equal(t, []string{"/fahrraeder"}, search("Haus AND title:Fahrr*"))
+	assert.Empty(t, search("Haus NOT Fahrrad"))
+
+	// New posts are stemmed by the triggers
+	require.NoError(t, app.createPost(&post{
+		Path:    "/katzen",
+		Section: "posts",
+		Status:  statusPublished,
+		Content: "Katzen und Hunde",
+	}))
+	assert.Equal(t, []string{"/katzen"}, search("Hund"))
+
+	inconsistent, err := app.db.checkFTSIndex()
+	require.NoError(t, err)
+	assert.Equal(t, 0, inconsistent)
+
+	// Highlights show the original words
+	app.d = app.buildRouter()
+	rec := httptest.NewRecorder()
+	app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/api?q=Haus", nil))
+	var res searchAPIResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "Die <mark>Häuser</mark> der Stadt", res.Results[0].Snippet)
+
+	// Languages without stemmer use the default index
+	bc.Lang = "xx"
+	prc := &postsRequestConfig{search: "Katzen"}
+	_, err = app.chooseSearchIndex(bc, prc)
+	require.NoError(t, err)
+	assert.Equal(t, ftsIndexDefault, prc.searchIndex)
+	assert.Equal(t, "Katzen", prc.search)
+}
+
+func Test_stemSearchWords(t *testing.T) {
+	assert.Equal(t, "run (blog OR \"learn golang\") NOT Running* title:learn", stemSearchWords("Running (blogs OR \"learning Golang\") NOT Running* title:Learning", "en", true))
+	assert.Equal(t, "haus der stadt, fahrrad", stemSearchWords("Häuser der Stadt, Fahrräder", "de", false))
+	assert.Equal(t, "Häuser", stemSearchWords("Häuser", "", false))
+	assert.Equal(t, `"run"* OR "blog"*`, searchStemPrefixQuery("run AND (blog OR run)"))
+}
+
+func Test_editDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("golang", "golang"))
+	assert.Equal(t, 1, editDistance("golan", "golang"))
+	assert.Equal(t, 1, editDistance("golnag", "golang"))
+	assert.Equal(t, 2, editDistance("glonag", "golang"))
+	assert.Equal(t, 1, editDistance("über", "uber"))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/gob"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -19,6 +20,10 @@ const (
 	sessionCreatedOn  = "created"
 	sessionModifiedOn = "modified"
 	sessionExpiresOn  = "expires"
+	sessionUserAgent  = "useragent"
+
+	// Only update the last seen time of a session every 5 minutes
+	sessionTouchInterval = 5 * time.Minute
 )
 
 func (a *goBlog) initSessions() {
@@ -175,6 +180,75 @@ func (s *dbSessionStore) save(session *sessions.Session) (err error) {
 	return nil
 }
 
+// Update the modified date to track when the session was last seen
+func (s *dbSessionStore) touch(session *sessions.Session) {
+	if modified, ok := session.Values[sessionModifiedOn].(time.Time); ok && time.Since(modified) < sessionTouchInterval {
+		return
+	}
+	now := time.Now()
+	if _, err := s.db.Exec(
+		"update sessions set modified = @modified where id = @id",
+		sql.Named("modified", now.UTC().Format(time.RFC3339)),
+		sql.Named("id", session.ID),
+	); err != nil {
+		log.Println("Failed to update session:", err.Error())
+		return
+	}
+	session.Values[sessionModifiedOn] = now
+}
+
+type sessionInfo struct {
+	ID        string
+	UserAgent string
+	Created   time.Time
+	LastSeen  time.Time
+}
+
+// Get all unexpired sessions with the given name that have the value set to true
+func (s *dbSessionStore) getAll(name, value string) ([]*sessionInfo, error) {
+	rows, err := s.db.Query(
+		"select id, data, created, modified from sessions where id like @prefix and expires > @now order by modified desc",
+		sql.Named("prefix", name+"-%"),
+		sql.Named("now", utcNowString()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	infos := []*sessionInfo{}
+	for rows.Next() {
+		var id, createdStr, modifiedStr string
+		var data []byte
+		if err = rows.Scan(&id, &data, &createdStr, &modifiedStr); err != nil {
+			return nil, err
+		}
+		values := map[any]any{}
+		if gob.NewDecoder(bytes.NewReader(data)).Decode(&values) != nil {
+			continue
+		}
+		if v, ok := values[value].(bool); !ok || !v {
+			continue
+		}
+		info := &sessionInfo{
+			ID:       id,
+			Created:  noError(dateparse.ParseIn(createdStr, time.UTC)),
+			LastSeen: noError(dateparse.ParseIn(modifiedStr, time.UTC)),
+		}
+		info.UserAgent, _ = values[sessionUserAgent].(string)
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// Delete the session with the given name and ID from the database
+func (s *dbSessionStore) deleteByID(name, id string) error {
+	if !strings.HasPrefix(id, name+"-") {
+		return errors.New("invalid session id")
+	}
+	_, err := s.db.Exec("delete from sessions where id = @id", sql.Named("id", id))
+	return err
+}
+
 func deleteSessionValuesNotNeededForDb(session *sessions.Session) {
 	delete(session.Values, sessionCreatedOn)
 	delete(session.Values, sessionExpiresOn)
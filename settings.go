@@ -15,6 +15,31 @@ func (a *goBlog) serveSettings(w http.ResponseWriter, r *http.Request) {
 	sections := lo.Values(bc.Sections)
 	sort.Slice(sections, func(i, j int) bool { return sections[i].Name < sections[j].Name })
 
+	loginSessions, err := a.loginSessions.getAll("l", "login")
+	if err != nil {
+		a.serveError(w, r, "Failed to get login sessions", http.StatusInternalServerError)
+		return
+	}
+	var currentSession string
+	if ses, err := a.loginSessions.Get(r, "l"); err == nil && ses != nil {
+		currentSession = ses.ID
+	}
+	securityLog, err := a.db.getLoginEvents(20)
+	if err != nil {
+		a.serveError(w, r, "Failed to get security log", http.StatusInternalServerError)
+		return
+	}
+	recoveryCodes, err := a.db.countTOTPRecoveryCodes()
+	if err != nil {
+		a.serveError(w, r, "Failed to count recovery codes", http.StatusInternalServerError)
+		return
+	}
+	appPasswords, err := a.db.getAppPasswords()
+	if err != nil {
+		a.serveError(w, r, "Failed to get app passwords", http.StatusInternalServerError)
+		return
+	}
+
 	a.render(w, r, a.renderSettings, &renderData{
 		Data: &settingsRenderData{
 			blog:                  blog,
@@ -31,6 +56,12 @@ func (a *goBlog) serveSettings(w http.ResponseWriter, r *http.Request) {
 			addRepostContext:      bc.addRepostContext,
 			userNick:              a.cfg.User.Nick,
 			userName:              a.cfg.User.Name,
+			loginSessions:         loginSessions,
+			currentSession:        currentSession,
+			securityLog:           securityLog,
+			totp:                  a.cfg.User.TOTP != "",
+			recoveryCodes:         recoveryCodes,
+			appPasswords:          appPasswords,
 		},
 	})
 }
@@ -108,14 +139,20 @@ func (a *goBlog) settingsUpdateSection(w http.ResponseWriter, r *http.Request) {
 	sectionPathTemplate := r.FormValue("sectionpathtemplate")
 	sectionShowFull := r.FormValue("sectionshowfull") == "on"
 	sectionHideOnStart := r.FormValue("sectionhideonstart") == "on"
+	sectionPodcast := r.FormValue("sectionpodcast") == "on"
+	sectionPodcastCategory := r.FormValue("sectionpodcastcategory")
+	sectionPodcastEmail := r.FormValue("sectionpodcastemail") == "on"
 	// Create section
 	section := &configSection{
-		Name:         sectionName,
-		Title:        sectionTitle,
-		Description:  sectionDescription,
-		PathTemplate: sectionPathTemplate,
-		ShowFull:     sectionShowFull,
-		HideOnStart:  sectionHideOnStart,
+		Name:            sectionName,
+		Title:           sectionTitle,
+		Description:     sectionDescription,
+		PathTemplate:    sectionPathTemplate,
+		ShowFull:        sectionShowFull,
+		HideOnStart:     sectionHideOnStart,
+		Podcast:         sectionPodcast,
+		PodcastCategory: sectionPodcastCategory,
+		PodcastEmail:    sectionPodcastEmail,
 	}
 	err := a.saveSection(blog, section)
 	if err != nil {
@@ -256,3 +293,92 @@ func (a *goBlog) settingsUpdateUser(w http.ResponseWriter, r *http.Request) {
 	a.cache.purge()
 	http.Redirect(w, r, bc.getRelativePath(settingsPath), http.StatusFound)
 }
+
+const settingsRevokeSessionPath = "/revokesession"
+
+func (a *goBlog) settingsRevokeSession(w http.ResponseWriter, r *http.Request) {
+	_, bc := a.getBlog(r)
+	var currentSession string
+	if ses, err := a.loginSessions.Get(r, "l"); err == nil && ses != nil {
+		currentSession = ses.ID
+	}
+	// Get sessions to revoke
+	var revoke []string
+	if r.FormValue("others") == "on" {
+		loginSessions, err := a.loginSessions.getAll("l", "login")
+		if err != nil {
+			a.serveError(w, r, "Failed to get login sessions", http.StatusInternalServerError)
+			return
+		}
+		for _, ls := range loginSessions {
+			if ls.ID != currentSession {
+				revoke = append(revoke, ls.ID)
+			}
+		}
+	} else if id := r.FormValue("sessionid"); id != "" {
+		revoke = append(revoke, id)
+	}
+	// Revoke
+	for _, id := range revoke {
+		if err := a.loginSessions.deleteByID("l", id); err != nil {
+			a.serveError(w, r, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+		a.logLoginEvent(r, loginEventSessionRevoked, a.cfg.User.Nick)
+	}
+	http.Redirect(w, r, bc.getRelativePath(settingsPath), http.StatusFound)
+}
+
+const settingsRecoveryCodesPath = "/recoverycodes"
+
+func (a *goBlog) settingsGenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if a.cfg.User.TOTP == "" {
+		a.serveError(w, r, "TOTP is not configured", http.StatusBadRequest)
+		return
+	}
+	codes, err := a.db.generateTOTPRecoveryCodes()
+	if err != nil {
+		a.serveError(w, r, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+	a.logLoginEvent(r, loginEventRecoveryCodeGen, a.cfg.User.Nick)
+	// Show the codes only once
+	w.Header().Set(cacheControl, "no-store,max-age=0")
+	a.render(w, r, a.renderRecoveryCodes, &renderData{
+		Data: codes,
+	})
+}
+
+const settingsCreateAppPasswordPath = "/createapppassword"
+
+func (a *goBlog) settingsCreateAppPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	username := r.FormValue("apppasswordusername")
+	password, err := a.db.createAppPassword(username, r.Form["apppasswordscopes"])
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Show the password only once
+	w.Header().Set(cacheControl, "no-store,max-age=0")
+	a.render(w, r, a.renderAppPasswordCreated, &renderData{
+		Data: &configAppPassword{
+			Username: username,
+			Password: password,
+		},
+	})
+}
+
+const settingsDeleteAppPasswordPath = "/deleteapppassword"
+
+func (a *goBlog) settingsDeleteAppPassword(w http.ResponseWriter, r *http.Request) {
+	_, bc := a.getBlog(r)
+	if err := a.db.deleteAppPassword(stringToInt(r.FormValue("apppasswordid"))); err != nil {
+		a.serveError(w, r, "Failed to delete app password", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, bc.getRelativePath(settingsPath), http.StatusFound)
+}
@@ -80,14 +80,14 @@ func (a *goBlog) loadSections() error {
 }
 
 func (a *goBlog) getSections(blog string) (map[string]*configSection, error) {
-	rows, err := a.db.Query("select name, title, description, pathtemplate, showfull, hideonstart from sections where blog = @blog", sql.Named("blog", blog))
+	rows, err := a.db.Query("select name, title, description, pathtemplate, showfull, hideonstart, podcast, podcastcategory, podcastemail from sections where blog = @blog", sql.Named("blog", blog))
 	if err != nil {
 		return nil, err
 	}
 	sections := map[string]*configSection{}
 	for rows.Next() {
 		section := &configSection{}
-		err = rows.Scan(&section.Name, &section.Title, &section.Description, &section.PathTemplate, &section.ShowFull, &section.HideOnStart)
+		err = rows.Scan(&section.Name, &section.Title, &section.Description, &section.PathTemplate, &section.ShowFull, &section.HideOnStart, &section.Podcast, &section.PodcastCategory, &section.PodcastEmail)
 		if err != nil {
 			return nil, err
 		}
@@ -111,8 +111,8 @@ func (a *goBlog) saveAllSections() error {
 func (a *goBlog) saveSection(blog string, section *configSection) error {
 	_, err := a.db.Exec(
 		`
-		insert into sections (blog, name, title, description, pathtemplate, showfull, hideonstart) values (@blog, @name, @title, @description, @pathtemplate, @showfull, @hideonstart)
-		on conflict (blog, name) do update set title = @title2, description = @description2, pathtemplate = @pathtemplate2, showfull = @showfull2, hideonstart = @hideonstart2
+		insert into sections (blog, name, title, description, pathtemplate, showfull, hideonstart, podcast, podcastcategory, podcastemail) values (@blog, @name, @title, @description, @pathtemplate, @showfull, @hideonstart, @podcast, @podcastcategory, @podcastemail)
+		on conflict (blog, name) do update set title = @title2, description = @description2, pathtemplate = @pathtemplate2, showfull = @showfull2, hideonstart = @hideonstart2, podcast = @podcast2, podcastcategory = @podcastcategory2, podcastemail = @podcastemail2
 		`,
 		sql.Named("blog", blog),
 		sql.Named("name", section.Name),
@@ -121,11 +121,17 @@ func (a *goBlog) saveSection(blog string, section *configSection) error {
 		sql.Named("pathtemplate", section.PathTemplate),
 		sql.Named("showfull", section.ShowFull),
 		sql.Named("hideonstart", section.HideOnStart),
+		sql.Named("podcast", section.Podcast),
+		sql.Named("podcastcategory", section.PodcastCategory),
+		sql.Named("podcastemail", section.PodcastEmail),
 		sql.Named("title2", section.Title),
 		sql.Named("description2", section.Description),
 		sql.Named("pathtemplate2", section.PathTemplate),
 		sql.Named("showfull2", section.ShowFull),
 		sql.Named("hideonstart2", section.HideOnStart),
+		sql.Named("podcast2", section.Podcast),
+		sql.Named("podcastcategory2", section.PodcastCategory),
+		sql.Named("podcastemail2", section.PodcastEmail),
 	)
 	return err
 }
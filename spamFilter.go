@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+	"go.goblog.app/app/pkgs/builderpool"
+)
+
+const (
+	defaultSpamHoldThreshold   = 0.9
+	defaultSpamRejectThreshold = 0.99
+
+	spamTokenMinLength = 2
+	spamTokenMaxLength = 40
+	// Only use the first tokens of long texts
+	spamMaxTokens = 500
+
+	// Prefix for notifications of contact submissions held as possible spam
+	spamNotificationPrefix = "Possible spam: "
+)
+
+type spamVerdict int
+
+const (
+	spamVerdictNone spamVerdict = iota
+	spamVerdictHold
+	spamVerdictReject
+)
+
+func (a *goBlog) spamFilterEnabled() bool {
+	return a.cfg.SpamFilter != nil && a.cfg.SpamFilter.Enabled
+}
+
+// Score the text parts and decide if the submission should be held for moderation or rejected
+func (a *goBlog) checkSpam(parts ...string) spamVerdict {
+	if !a.spamFilterEnabled() {
+		return spamVerdictNone
+	}
+	probability, trained, err := a.db.spamProbability(strings.Join(parts, " "))
+	if err != nil {
+		log.Println("Failed to check for spam:", err.Error())
+		return spamVerdictNone
+	}
+	if !trained {
+		return spamVerdictNone
+	}
+	sf := a.cfg.SpamFilter
+	if probability >= lo.If(sf.RejectThreshold > 0, sf.RejectThreshold).Else(defaultSpamRejectThreshold) {
+		return spamVerdictReject
+	}
+	if probability >= lo.If(sf.HoldThreshold > 0, sf.HoldThreshold).Else(defaultSpamHoldThreshold) {
+		return spamVerdictHold
+	}
+	return spamVerdictNone
+}
+
+// Train the spam filter with the text parts of a submission,
+// the item (like "comment/1") is used to only train each submission once
+func (a *goBlog) trainSpamFilter(item string, spam bool, parts ...string) {
+	if !a.spamFilterEnabled() {
+		return
+	}
+	if err := a.db.trainSpamFilter(item, strings.Join(parts, " "), spam); err != nil {
+		log.Println("Failed to train spam filter:", err.Error())
+	}
+}
+
+// Get the unique lowercase words of the text
+func spamTokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	words = lo.Filter(words, func(w string, _ int) bool {
+		return len(w) >= spamTokenMinLength && len(w) <= spamTokenMaxLength
+	})
+	words = lo.Uniq(words)
+	if len(words) > spamMaxTokens {
+		words = words[:spamMaxTokens]
+	}
+	return words
+}
+
+func (db *database) trainSpamFilter(item, text string, spam bool) error {
+	// Check if the item was already trained with the same text, the hash also detects reused IDs
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(text)))
+	row, err := db.QueryRow("select spam from spamtrained where item = @item and hash = @hash", sql.Named("item", item), sql.Named("hash", hash))
+	if err != nil {
+		return err
+	}
+	var trainedSpam bool
+	trained := true
+	if err = row.Scan(&trainedSpam); errors.Is(err, sql.ErrNoRows) {
+		trained = false
+	} else if err != nil {
+		return err
+	}
+	if trained && trainedSpam == spam {
+		// Already trained with the same verdict
+		return nil
+	}
+	tokens := spamTokens(text)
+	column := lo.If(spam, "spam").Else("ham")
+	// Build SQL
+	sqlBuilder := builderpool.Get()
+	defer builderpool.Put(sqlBuilder)
+	sqlArgs := []any{dbNoCache}
+	sqlBuilder.WriteString("begin;")
+	if trained {
+		// The verdict was flipped, undo the old counts
+		oldColumn := lo.If(trainedSpam, "spam").Else("ham")
+		for _, token := range tokens {
+			sqlBuilder.WriteString("update spamtokens set " + oldColumn + " = max(" + oldColumn + " - 1, 0) where token = ?;")
+			sqlArgs = append(sqlArgs, token)
+		}
+		sqlBuilder.WriteString("update spammessages set " + oldColumn + " = max(" + oldColumn + " - 1, 0);")
+	}
+	for _, token := range tokens {
+		sqlBuilder.WriteString("insert into spamtokens (token, " + column + ") values (?, 1) on conflict (token) do update set " + column + " = " + column + " + 1;")
+		sqlArgs = append(sqlArgs, token)
+	}
+	sqlBuilder.WriteString("update spammessages set " + column + " = " + column + " + 1;")
+	sqlBuilder.WriteString("insert or replace into spamtrained (item, hash, spam) values (?, ?, ?);commit;")
+	sqlArgs = append(sqlArgs, item, hash, spam)
+	_, err = db.Exec(sqlBuilder.String(), sqlArgs...)
+	return err
+}
+
+// Calculate the spam probability using naive Bayes,
+// returns false if the filter isn't trained with both spam and ham yet
+func (db *database) spamProbability(text string) (float64, bool, error) {
+	row, err := db.QueryRow("select spam, ham from spammessages")
+	if err != nil {
+		return 0, false, err
+	}
+	var spamMessages, hamMessages float64
+	if err = row.Scan(&spamMessages, &hamMessages); err != nil {
+		return 0, false, err
+	}
+	if spamMessages == 0 || hamMessages == 0 {
+		return 0, false, nil
+	}
+	logSpam := math.Log(spamMessages / (spamMessages + hamMessages))
+	logHam := math.Log(hamMessages / (spamMessages + hamMessages))
+	tokens := spamTokens(text)
+	if len(tokens) > 0 {
+		rows, err := db.Query(
+			"select spam, ham from spamtokens where token in ("+strings.TrimSuffix(strings.Repeat("?,", len(tokens)), ",")+")",
+			append([]any{dbNoCache}, lo.ToAnySlice(tokens)...)...,
+		)
+		if err != nil {
+			return 0, false, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var spam, ham float64
+			if err = rows.Scan(&spam, &ham); err != nil {
+				return 0, false, err
+			}
+			// Laplace smoothing, so tokens only seen in one class don't dominate
+			logSpam += math.Log((spam + 1) / (spamMessages + 2))
+			logHam += math.Log((ham + 1) / (hamMessages + 2))
+		}
+		if err = rows.Err(); err != nil {
+			return 0, false, err
+		}
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam)), true, nil
+}
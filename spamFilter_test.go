@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goblog.app/app/pkgs/contenttype"
+)
+
+func Test_spamTokens(t *testing.T) {
+	assert.Equal(t, []string{"buy", "cheap", "pills", "at", "https", "example", "com"}, spamTokens("Buy cheap PILLS! Buy at https://example.com a"))
+}
+
+func Test_spamFilter(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+	app.cfg.SpamFilter = &configSpamFilter{
+		Enabled: true,
+	}
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	app.initMarkdown()
+	app.initSessions()
+
+	// Untrained filter doesn't do anything
+	_, trained, err := app.db.spamProbability("Buy cheap pills")
+	require.NoError(t, err)
+	assert.False(t, trained)
+	assert.Equal(t, spamVerdictNone, app.checkSpam("Buy cheap pills"))
+
+	// Train
+	for i := 0; i < 5; i++ {
+		app.trainSpamFilter(fmt.Sprintf("test/spam%d", i), true, "Buy cheap pills and watches now, best casino offers")
+		app.trainSpamFilter(fmt.Sprintf("test/ham%d", i), false, "Great article, thanks for sharing your thoughts on Go")
+	}
+
+	probability, trained, err := app.db.spamProbability("Cheap pills and casino offers")
+	require.NoError(t, err)
+	assert.True(t, trained)
+	assert.Greater(t, probability, 0.99)
+	assert.Equal(t, spamVerdictReject, app.checkSpam("Cheap pills and casino offers"))
+
+	probability, _, err = app.db.spamProbability("Thanks for the great article")
+	require.NoError(t, err)
+	assert.Less(t, probability, 0.1)
+	assert.Equal(t, spamVerdictNone, app.checkSpam("Thanks for the great article"))
+
+	// Hold instead of reject with a higher reject threshold
+	app.cfg.SpamFilter.RejectThreshold = 1.1
+	assert.Equal(t, spamVerdictHold, app.checkSpam("Cheap pills and casino offers"))
+	app.cfg.SpamFilter.RejectThreshold = 0
+
+	// Comments are rejected
+	bc := app.cfg.Blogs[app.cfg.DefaultBlog]
+	_, status, err := app.createComment(bc, "https://example.com/abc", "Cheap pills and casino offers", "Name", "", "", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	_, status, err = app.createComment(bc, "https://example.com/abc", "Thanks for the great article", "Name", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, status)
+}
+
+func Test_spamFilterTrainOnce(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.SpamFilter = &configSpamFilter{
+		Enabled: true,
+	}
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	app.initMarkdown()
+	app.initSessions()
+
+	counts := func(token string) (spamTokens, hamTokens, spamMessages, hamMessages int) {
+		row, err := app.db.QueryRow("select coalesce((select spam from spamtokens where token = @token), 0), coalesce((select ham from spamtokens where token = @token), 0), spam, ham from spammessages", sql.Named("token", token))
+		require.NoError(t, err)
+		require.NoError(t, row.Scan(&spamTokens, &hamTokens, &spamMessages, &hamMessages))
+		return
+	}
+
+	require.NoError(t, app.db.saveNotification(&notification{Time: time.Now().Unix(), Text: spamNotificationPrefix + "Cheap pills"}))
+	notifications, err := app.db.getNotifications(&notificationsRequestConfig{})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	id := notifications[0].ID
+
+	h := app.notificationsAdminTrainSpam(false)
+	click := func() {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"notificationid": {strconv.Itoa(id)}}.Encode()))
+		req.Header.Set(contentType, contenttype.WWWForm)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusFound, rec.Code)
+	}
+
+	// Clicking "not spam" twice only counts once
+	click()
+	spamCount, hamCount, spamMessages, hamMessages := counts("pills")
+	assert.Equal(t, []int{0, 1, 0, 1}, []int{spamCount, hamCount, spamMessages, hamMessages})
+	click()
+	spamCount, hamCount, spamMessages, hamMessages = counts("pills")
+	assert.Equal(t, []int{0, 1, 0, 1}, []int{spamCount, hamCount, spamMessages, hamMessages})
+
+	// Flipping the verdict undoes the old counts
+	app.trainSpamFilter(fmt.Sprintf("notification/%d", id), true, "Cheap pills")
+	spamCount, hamCount, spamMessages, hamMessages = counts("pills")
+	assert.Equal(t, []int{1, 0, 1, 0}, []int{spamCount, hamCount, spamMessages, hamMessages})
+}
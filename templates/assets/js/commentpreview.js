@@ -0,0 +1,36 @@
+(() => {
+    const textarea = document.querySelector('textarea[data-preview]');
+    if (!textarea) return;
+
+    const previewContainer = document.createElement('div');
+    previewContainer.classList.add('preview', 'hide');
+    const previewLabel = document.createElement('strong');
+    previewLabel.textContent = textarea.dataset.previewlabel;
+    const previewContent = document.createElement('div');
+    previewContainer.append(previewLabel, previewContent);
+    textarea.after(previewContainer);
+
+    let timeout;
+
+    const updatePreview = async () => {
+        if (textarea.value.trim() === '') {
+            previewContainer.classList.add('hide');
+            return;
+        }
+        const data = new FormData();
+        data.append('comment', textarea.value);
+        try {
+            const response = await fetch(textarea.dataset.preview, { method: 'POST', body: data });
+            // The server only returns sanitized HTML
+            previewContent.innerHTML = await response.text();
+            previewContainer.classList.remove('hide');
+        } catch (error) {
+            console.error(error);
+        }
+    };
+
+    textarea.addEventListener('input', () => {
+        clearTimeout(timeout);
+        timeout = setTimeout(updatePreview, 500);
+    });
+})();
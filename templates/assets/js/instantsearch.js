@@ -0,0 +1,106 @@
+(() => {
+    const input = document.querySelector('input[data-instantsearch]');
+    if (!input) return;
+    const form = input.form;
+
+    const list = document.createElement('ul');
+    list.id = 'instantsearch-results';
+    list.setAttribute('role', 'listbox');
+    list.classList.add('hide');
+    form.after(list);
+
+    input.setAttribute('role', 'combobox');
+    input.setAttribute('aria-controls', list.id);
+    input.setAttribute('aria-expanded', 'false');
+    input.setAttribute('aria-autocomplete', 'list');
+
+    let timeout, controller, active = -1;
+
+    const items = () => Array.from(list.querySelectorAll('li'));
+
+    const setActive = (index) => {
+        const all = items();
+        if (all.length === 0) return;
+        active = (index + all.length) % all.length;
+        all.forEach((item, i) => item.setAttribute('aria-selected', i === active ? 'true' : 'false'));
+        input.setAttribute('aria-activedescendant', all[active].id);
+        all[active].scrollIntoView({ block: 'nearest' });
+    };
+
+    const hide = () => {
+        list.classList.add('hide');
+        input.setAttribute('aria-expanded', 'false');
+        input.removeAttribute('aria-activedescendant');
+        active = -1;
+    };
+
+    const search = async () => {
+        const query = input.value.trim();
+        if (query.length < 2) {
+            hide();
+            return;
+        }
+        // Use the filters of the form too
+        const params = new URLSearchParams();
+        for (const [key, value] of new FormData(form)) {
+            if (value !== '') params.append(key, value);
+        }
+        controller?.abort();
+        controller = new AbortController();
+        try {
+            const response = await fetch(input.dataset.instantsearch + '?' + params.toString(), { signal: controller.signal });
+            if (!response.ok) return;
+            const data = await response.json();
+            list.replaceChildren(...data.results.map((result, i) => {
+                const item = document.createElement('li');
+                item.id = 'instantsearch-result-' + i;
+                item.setAttribute('role', 'option');
+                const link = document.createElement('a');
+                link.href = result.path;
+                link.textContent = result.title || result.path;
+                const snippet = document.createElement('p');
+                // The server only returns escaped snippets with mark elements
+                snippet.innerHTML = result.snippet || '';
+                item.append(link, snippet);
+                return item;
+            }));
+            active = -1;
+            if (data.results.length > 0) {
+                list.classList.remove('hide');
+                input.setAttribute('aria-expanded', 'true');
+            } else {
+                hide();
+            }
+        } catch (error) {
+            if (error.name !== 'AbortError') console.error(error);
+        }
+    };
+
+    input.addEventListener('input', () => {
+        clearTimeout(timeout);
+        timeout = setTimeout(search, 300);
+    });
+
+    input.addEventListener('keydown', (event) => {
+        if (list.classList.contains('hide')) return;
+        switch (event.key) {
+            case 'ArrowDown':
+                event.preventDefault();
+                setActive(active + 1);
+                break;
+            case 'ArrowUp':
+                event.preventDefault();
+                setActive(active - 1);
+                break;
+            case 'Enter':
+                if (active >= 0) {
+                    event.preventDefault();
+                    window.location.href = items()[active].querySelector('a').href;
+                }
+                break;
+            case 'Escape':
+                hide();
+                break;
+        }
+    });
+})();
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"go.goblog.app/app/pkgs/builderpool"
+)
+
+const (
+	totpRecoveryCodesCount  = 10
+	totpRecoveryCodeLength  = 15
+	totpRecoveryCodeGroup   = 5
+	totpRecoveryCodeDigits  = "0123456789"
+	totpRecoveryCodeAllowed = totpRecoveryCodeDigits + "- "
+)
+
+func hashTOTPRecoveryCode(code string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(normalizeTOTPRecoveryCode(code))))
+}
+
+// Remove separators, so codes can be entered with or without them
+func normalizeTOTPRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(totpRecoveryCodeDigits, r) {
+			return r
+		}
+		return -1
+	}, code)
+}
+
+func generateTOTPRecoveryCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(totpRecoveryCodeDigits)))
+	for i := 0; i < totpRecoveryCodeLength; i++ {
+		if i > 0 && i%totpRecoveryCodeGroup == 0 {
+			sb.WriteRune('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(totpRecoveryCodeDigits[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// Replace all existing recovery codes with new ones and return them in plain text
+func (db *database) generateTOTPRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, totpRecoveryCodesCount)
+	for len(codes) < totpRecoveryCodesCount {
+		code, err := generateTOTPRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	// Build SQL
+	sqlBuilder := builderpool.Get()
+	defer builderpool.Put(sqlBuilder)
+	sqlArgs := []any{dbNoCache}
+	sqlBuilder.WriteString("begin;delete from totprecoverycodes;")
+	for _, code := range codes {
+		sqlBuilder.WriteString("insert or ignore into totprecoverycodes (hash) values (?);")
+		sqlArgs = append(sqlArgs, hashTOTPRecoveryCode(code))
+	}
+	sqlBuilder.WriteString("commit;")
+	if _, err := db.Exec(sqlBuilder.String(), sqlArgs...); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (db *database) countTOTPRecoveryCodes() (count int, err error) {
+	row, err := db.QueryRow("select count(*) from totprecoverycodes")
+	if err != nil {
+		return 0, err
+	}
+	err = row.Scan(&count)
+	return
+}
+
+// Check the recovery code and delete it, so it can't be used again
+func (db *database) useTOTPRecoveryCode(code string) bool {
+	if strings.Trim(code, totpRecoveryCodeAllowed) != "" || len(normalizeTOTPRecoveryCode(code)) != totpRecoveryCodeLength {
+		return false
+	}
+	res, err := db.Exec("delete from totprecoverycodes where hash = @hash", sql.Named("hash", hashTOTPRecoveryCode(code)))
+	if err != nil {
+		return false
+	}
+	deleted, err := res.RowsAffected()
+	return err == nil && deleted > 0
+}
@@ -215,7 +215,7 @@ func (a *goBlog) renderLogin(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			hb.WriteElementOpen("input", "type", "password", "name", "password", "autocomplete", "current-password", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "password"), "required", "")
 			// TOTP
 			if data.totp {
-				hb.WriteElementOpen("input", "type", "text", "inputmode", "numeric", "pattern", "[0-9]*", "name", "token", "autocomplete", "one-time-code", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "totp"), "required", "")
+				hb.WriteElementOpen("input", "type", "text", "inputmode", "numeric", "pattern", "[0-9 -]*", "name", "token", "autocomplete", "one-time-code", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "totp"), "required", "")
 			}
 			// Submit
 			hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "login"))
@@ -256,14 +256,22 @@ func (a *goBlog) renderSearch(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			// Form
 			hb.WriteElementOpen("form", "class", "fw p", "method", "post")
 			// Search
-			args := []any{"type", "text", "name", "q", "required", ""}
+			args := []any{
+				"type", "text", "name", "q", "required", "", "autocomplete", "off",
+				"data-instantsearch", rd.Blog.getRelativePath(defaultIfEmpty(sc.Path, defaultSearchPath) + searchAPIPath),
+			}
 			if sc.Placeholder != "" {
 				args = append(args, "placeholder", a.renderMdTitle(sc.Placeholder))
 			}
 			hb.WriteElementOpen("input", args...)
+			// Filters
+			a.renderSearchFormFilters(hb, rd)
 			// Submit
 			hb.WriteElementOpen("input", "type", "submit", "value", "🔍 "+a.ts.GetTemplateStringVariant(rd.Blog.Lang, "search"))
 			hb.WriteElementClose("form")
+			// Instant search
+			hb.WriteElementOpen("script", "src", a.assetFileName("js/instantsearch.js"), "defer", "")
+			hb.WriteElementClose("script")
 			hb.WriteElementClose("main")
 		},
 	)
@@ -285,10 +293,15 @@ func (a *goBlog) renderComment(h *htmlbuilder.HtmlBuilder, rd *renderData) {
 		},
 		func(hb *htmlbuilder.HtmlBuilder) {
 			hb.WriteElementOpen("main", "class", "h-entry")
-			// Target
+			// Target (the parent comment for replies)
+			replyTarget := a.commentReplyTarget(rd.Blog, c.Target, c.Parent)
 			hb.WriteElementOpen("p")
-			hb.WriteElementOpen("a", "class", "u-in-reply-to", "href", a.getFullAddress(c.Target))
-			hb.WriteEscaped(a.getFullAddress(c.Target))
+			if c.Parent != 0 {
+				hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "replyto"))
+				hb.WriteUnescaped(" ")
+			}
+			hb.WriteElementOpen("a", "class", "u-in-reply-to", "href", replyTarget)
+			hb.WriteEscaped(replyTarget)
 			hb.WriteElementClose("a")
 			hb.WriteElementClose("p")
 			// Author
@@ -307,9 +320,9 @@ func (a *goBlog) renderComment(h *htmlbuilder.HtmlBuilder, rd *renderData) {
 			hb.WriteEscaped(":")
 			hb.WriteElementClose("p")
 			// Content
-			hb.WriteElementOpen("p", "class", "e-content")
-			hb.WriteUnescaped(c.Comment) // Already escaped
-			hb.WriteElementClose("p")
+			hb.WriteElementOpen("div", "class", "e-content")
+			hb.WriteUnescaped(a.renderCommentContent(c))
+			hb.WriteElementClose("div")
 			// Original
 			if c.Original != "" {
 				hb.WriteElementOpen("p", "class", "")
@@ -318,19 +331,33 @@ func (a *goBlog) renderComment(h *htmlbuilder.HtmlBuilder, rd *renderData) {
 				hb.WriteElementClose("a")
 				hb.WriteElementClose("p")
 			}
-			hb.WriteElementClose("main")
-			// Editor
-			if rd.LoggedIn() {
+			// Moderation
+			if rd.LoggedIn() && c.Status != commentStatusApproved {
+				a.renderCommentActions(hb, rd, c)
+			}
+			// Actions
+			if rd.Blog.commentsEnabled() || rd.LoggedIn() {
 				hb.WriteElementOpen("div", "class", "actions")
-				hb.WriteElementOpen("a", "class", "button", "href", rd.Blog.getRelativePath(fmt.Sprintf("%s%s?id=%d", commentPath, commentEditSubPath, c.ID)))
-				hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "edit"))
-				hb.WriteElementClose("a")
+				// Reply
+				if rd.Blog.commentsEnabled() {
+					hb.WriteElementOpen("a", "class", "button", "href", "#interactions")
+					hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "reply"))
+					hb.WriteElementClose("a")
+				}
+				// Editor
+				if rd.LoggedIn() {
+					hb.WriteElementOpen("a", "class", "button", "href", rd.Blog.getRelativePath(fmt.Sprintf("%s%s?id=%d", commentPath, commentEditSubPath, c.ID)))
+					hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "edit"))
+					hb.WriteElementClose("a")
+				}
 				hb.WriteElementClose("div")
 			}
 			// Interactions
 			if rd.Blog.commentsEnabled() {
 				a.renderInteractions(hb, rd)
 			}
+			// Close the h-entry after the interactions, so replies are comments of the comment
+			hb.WriteElementClose("main")
 		},
 	)
 }
@@ -342,6 +369,12 @@ type indexRenderData struct {
 	first, prev, next  string
 	paramUrlQuery      string
 	summaryTemplate    summaryTyp
+	search             string
+	searchDateOrder    bool
+	searchHighlights   map[string]*searchHighlight
+	searchFacets       []*searchFacet
+	searchFuzzy        bool
+	searchSuggestion   string
 }
 
 func (a *goBlog) renderIndex(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
@@ -363,6 +396,11 @@ func (a *goBlog) renderIndex(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			hb.WriteElementOpen("link", "rel", "alternate", "type", "application/rss+xml", "title", "RSS"+feedTitle, "href", a.getFullAddress(id.first+".rss")+id.paramUrlQuery)
 			hb.WriteElementOpen("link", "rel", "alternate", "type", "application/atom+xml", "title", "ATOM"+feedTitle, "href", a.getFullAddress(id.first+".atom")+id.paramUrlQuery)
 			hb.WriteElementOpen("link", "rel", "alternate", "type", "application/feed+json", "title", "JSON Feed"+feedTitle, "href", a.getFullAddress(id.first+".json")+id.paramUrlQuery)
+			// WebSub
+			if a.webSubEnabled() {
+				hb.WriteElementOpen("link", "rel", "hub", "href", a.webSubHubURL())
+				hb.WriteElementOpen("link", "rel", "self", "href", a.getFullAddress(id.first)+id.paramUrlQuery)
+			}
 		},
 		func(hb *htmlbuilder.HtmlBuilder) {
 			hb.WriteElementOpen("main", "class", "h-feed")
@@ -382,9 +420,21 @@ func (a *goBlog) renderIndex(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			if titleOrDesc {
 				hb.WriteElementOpen("hr")
 			}
+			if id.search != "" {
+				// Sort options for search results
+				a.renderSearchSort(hb, rd, id)
+				// Filters for search results
+				a.renderSearchFilters(hb, rd, id)
+				// Notes about fuzzy results and suggestion
+				a.renderSearchNotes(hb, rd, id)
+			}
 			if id.posts != nil && len(id.posts) > 0 {
 				// Posts
 				for _, p := range id.posts {
+					if hl, ok := id.searchHighlights[p.Path]; ok {
+						a.renderSearchSummary(hb, rd, p, hl)
+						continue
+					}
 					a.renderSummary(hb, rd, rd.Blog, p, id.summaryTemplate)
 				}
 			} else {
@@ -730,6 +780,75 @@ func (a *goBlog) renderContact(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 	)
 }
 
+type commentCreatedRenderData struct {
+	target, address string
+	pending         bool
+	// Path with the edit token, only shown once
+	editPath string
+}
+
+func (a *goBlog) renderCommentCreated(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	cd, ok := rd.Data.(*commentCreatedRenderData)
+	if !ok {
+		return
+	}
+	a.renderBase(
+		hb, rd, nil,
+		func(hb *htmlbuilder.HtmlBuilder) {
+			hb.WriteElementsOpen("main", "p")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, lo.If(cd.pending, "commentpending").Else("commentpublished")))
+			hb.WriteElementClose("p")
+			// Link to the comment or the target if it's not visible yet
+			if link := lo.If(cd.pending, cd.target).Else(a.getFullAddress(cd.address)); link != "" {
+				hb.WriteElementOpen("p")
+				hb.WriteElementOpen("a", "href", link)
+				hb.WriteEscaped(link)
+				hb.WriteElementClose("a")
+				hb.WriteElementClose("p")
+			}
+			if cd.editPath != "" {
+				hb.WriteElementOpen("p")
+				hb.WriteEscaped(fmt.Sprintf(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "commenteditlink"), int(rd.Blog.commentEditWindow().Minutes())))
+				hb.WriteElementClose("p")
+				hb.WriteElementOpen("p")
+				hb.WriteElementOpen("a", "href", cd.editPath)
+				hb.WriteEscaped(a.getFullAddress(cd.editPath))
+				hb.WriteElementClose("a")
+				hb.WriteElementClose("p")
+			}
+			hb.WriteElementClose("main")
+		},
+	)
+}
+
+func (a *goBlog) renderCommentSubscription(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	message, _ := rd.Data.(string)
+	a.renderBase(
+		hb, rd, nil,
+		func(hb *htmlbuilder.HtmlBuilder) {
+			hb.WriteElementsOpen("main", "p")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, message))
+			hb.WriteElementsClose("p", "main")
+		},
+	)
+}
+
+func (a *goBlog) renderCommentSubscriptionUnsubscribe(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	token, _ := rd.Data.(string)
+	a.renderBase(
+		hb, rd, nil,
+		func(hb *htmlbuilder.HtmlBuilder) {
+			hb.WriteElementsOpen("main", "p")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "unsubscribeconfirm"))
+			hb.WriteElementClose("p")
+			hb.WriteElementOpen("form", "class", "fw p", "method", "post", "action", rd.Blog.getRelativePath(commentPath+commentSubscriptionUnsubscribeSubPath))
+			hb.WriteElementOpen("input", "type", "hidden", "name", "token", "value", token)
+			hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "unsubscribe"))
+			hb.WriteElementsClose("form", "main")
+		},
+	)
+}
+
 func (a *goBlog) renderContactSent(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 	a.renderBase(
 		hb, rd, nil,
@@ -849,6 +968,9 @@ func (a *goBlog) renderPost(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			if su := a.shortPostURL(p); su != "" {
 				hb.WriteElementOpen("link", "rel", "shortlink", "href", su)
 			}
+			if a.commentsEnabledForPost(p) {
+				hb.WriteElementOpen("link", "rel", "alternate", "type", "application/rss+xml", "title", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "interactions"), "href", a.interactionsFeedURL(rd.Blog, p, rssFeed))
+			}
 		},
 		func(origHb *htmlbuilder.HtmlBuilder) {
 			// Wrap plugins
@@ -902,7 +1024,6 @@ func (a *goBlog) renderPost(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			hb.WriteElementClose("article")
 			// Author
 			a.renderAuthor(hb)
-			hb.WriteElementClose("main")
 			// Reactions
 			a.renderPostReactions(hb, p)
 			// Post edit actions
@@ -936,14 +1057,24 @@ func (a *goBlog) renderPost(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 					hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "gentts"))
 					hb.WriteElementClose("form")
 				}
+				// Resend webmentions
+				hb.WriteElementOpen("form", "method", "post", "action", rd.Blog.getRelativePath("/editor"))
+				hb.WriteElementOpen("input", "type", "hidden", "name", "editoraction", "value", "sendwebmentions")
+				hb.WriteElementOpen("input", "type", "hidden", "name", "url", "value", rd.Canonical)
+				hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "resendwebmentions"))
+				hb.WriteElementClose("form")
 				hb.WriteElementOpen("script", "defer", "", "src", a.assetFileName("js/formconfirm.js"))
 				hb.WriteElementClose("script")
 				hb.WriteElementClose("div")
+				// Sent webmentions
+				a.renderSentWebmentions(hb, rd, p)
 			}
 			// Comments
 			if a.commentsEnabledForPost(p) {
 				a.renderInteractions(hb, rd)
 			}
+			// Close the h-entry after the interactions, so replies are comments of the post
+			hb.WriteElementClose("main")
 		},
 	)
 }
@@ -1154,6 +1285,11 @@ func (a *goBlog) renderNotificationsAdmin(hb *htmlbuilder.HtmlBuilder, rd *rende
 				hb.WriteElementOpen("form", "class", "actions", "method", "post", "action", "/notifications/delete")
 				hb.WriteElementOpen("input", "type", "hidden", "name", "notificationid", "value", n.ID)
 				hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "delete"))
+				// Train spam filter
+				if a.spamFilterEnabled() {
+					hb.WriteElementOpen("input", "type", "submit", "formaction", "/notifications/spam", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "spam"))
+					hb.WriteElementOpen("input", "type", "submit", "formaction", "/notifications/notspam", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "notspam"))
+				}
 				hb.WriteElementClose("form")
 				hb.WriteElementClose("div")
 			}
@@ -1168,6 +1304,7 @@ type commentsRenderData struct {
 	comments         []*comment
 	hasPrev, hasNext bool
 	prev, next       string
+	pending          int
 }
 
 func (a *goBlog) renderCommentsAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
@@ -1186,6 +1323,14 @@ func (a *goBlog) renderCommentsAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderData
 			hb.WriteElementOpen("h1")
 			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "comments"))
 			hb.WriteElementClose("h1")
+			// Comments awaiting moderation
+			if crd.pending > 0 {
+				hb.WriteElementOpen("p")
+				hb.WriteElementOpen("a", "href", rd.Blog.getRelativePath(commentPath)+"?status="+string(commentStatusPending))
+				hb.WriteEscaped(fmt.Sprintf(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "pendingcomments"), crd.pending))
+				hb.WriteElementClose("a")
+				hb.WriteElementClose("p")
+			}
 			// Comments
 			for _, c := range crd.comments {
 				hb.WriteElementOpen("div", "class", "p")
@@ -1194,6 +1339,9 @@ func (a *goBlog) renderCommentsAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderData
 				hb.WriteEscaped("ID: ")
 				hb.WriteEscaped(fmt.Sprintf("%d", c.ID))
 				hb.WriteElementOpen("br")
+				hb.WriteEscaped("Status: ")
+				hb.WriteEscaped(string(c.Status))
+				hb.WriteElementOpen("br")
 				hb.WriteEscaped("Target: ")
 				hb.WriteElementOpen("a", "href", c.Target, "target", "_blank")
 				hb.WriteEscaped(c.Target)
@@ -1216,14 +1364,11 @@ func (a *goBlog) renderCommentsAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderData
 				}
 				hb.WriteElementClose("p")
 				// Comment
-				hb.WriteElementOpen("p")
-				hb.WriteUnescaped(c.Comment)
-				hb.WriteElementClose("p")
-				// Delete form
-				hb.WriteElementOpen("form", "class", "actions", "method", "post", "action", rd.Blog.getRelativePath(commentPath+commentDeleteSubPath))
-				hb.WriteElementOpen("input", "type", "hidden", "name", "commentid", "value", c.ID)
-				hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "delete"))
-				hb.WriteElementClose("form")
+				hb.WriteElementOpen("div")
+				hb.WriteUnescaped(a.renderCommentContent(c))
+				hb.WriteElementClose("div")
+				// Actions
+				a.renderCommentActions(hb, rd, c)
 				hb.WriteElementClose("div")
 			}
 			// Pagination
@@ -1284,6 +1429,13 @@ func (a *goBlog) renderWebmentionAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderDa
 				hb.WriteEscaped("Created: ")
 				hb.WriteEscaped(timediff.TimeDiff(time.Unix(m.Created, 0), timediff.WithLocale(tdLocale)))
 				hb.WriteElementOpen("br")
+				// Type
+				hb.WriteEscaped("Type: ")
+				hb.WriteEscaped(string(m.Type))
+				if m.Private {
+					hb.WriteEscaped(" (private)")
+				}
+				hb.WriteElementOpen("br")
 				hb.WriteElementOpen("br")
 				// Author
 				if m.Author != "" {
@@ -1297,14 +1449,13 @@ func (a *goBlog) renderWebmentionAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderDa
 					hb.WriteElementClose("strong")
 					hb.WriteElementOpen("br")
 				}
+				hb.WriteElementClose("p")
 				// Content
 				if m.Content != "" {
-					hb.WriteElementOpen("i")
-					hb.WriteEscaped(m.Content)
-					hb.WriteElementClose("i")
-					hb.WriteElementOpen("br")
+					hb.WriteElementOpen("div")
+					hb.WriteUnescaped(a.renderCommentMarkdown(m.Content))
+					hb.WriteElementClose("div")
 				}
-				hb.WriteElementClose("p")
 				// Actions
 				hb.WriteElementOpen("form", "method", "post", "class", "actions")
 				hb.WriteElementOpen("input", "type", "hidden", "name", "mentionid", "value", m.ID)
@@ -1317,6 +1468,11 @@ func (a *goBlog) renderWebmentionAdmin(hb *htmlbuilder.HtmlBuilder, rd *renderDa
 				hb.WriteElementOpen("input", "type", "submit", "formaction", "/webmention/delete", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "delete"))
 				// Reverify mention
 				hb.WriteElementOpen("input", "type", "submit", "formaction", "/webmention/reverify", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "reverify"))
+				// Train spam filter
+				if a.spamFilterEnabled() {
+					hb.WriteElementOpen("input", "type", "submit", "formaction", "/webmention/spam", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "spam"))
+					hb.WriteElementOpen("input", "type", "submit", "formaction", "/webmention/notspam", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "notspam"))
+				}
 				hb.WriteElementClose("form")
 			}
 			// Pagination
@@ -1493,6 +1649,12 @@ type settingsRenderData struct {
 	addRepostContext      bool
 	userNick              string
 	userName              string
+	loginSessions         []*sessionInfo
+	currentSession        string
+	securityLog           []*loginEvent
+	totp                  bool
+	recoveryCodes         int
+	appPasswords          []*appPassword
 }
 
 func (a *goBlog) renderSettings(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
@@ -1586,6 +1748,9 @@ func (a *goBlog) renderSettings(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 			// User settings
 			a.renderUserSettings(hb, rd, srd)
 
+			// Security settings
+			a.renderSecuritySettings(hb, rd, srd)
+
 			// Post sections
 			a.renderPostSectionSettings(hb, rd, srd)
 
@@ -1600,6 +1765,105 @@ func (a *goBlog) renderSettings(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
 	)
 }
 
+func (a *goBlog) renderRecoveryCodes(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	codes, ok := rd.Data.([]string)
+	if !ok {
+		return
+	}
+	a.renderBase(
+		hb, rd,
+		func(hb *htmlbuilder.HtmlBuilder) {
+			a.renderTitleTag(hb, rd.Blog, a.ts.GetTemplateStringVariant(rd.Blog.Lang, "recoverycodes"))
+		},
+		func(hb *htmlbuilder.HtmlBuilder) {
+			hb.WriteElementOpen("main")
+
+			// Title
+			hb.WriteElementOpen("h1")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "recoverycodes"))
+			hb.WriteElementClose("h1")
+
+			// Info
+			hb.WriteElementOpen("p")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "recoverycodesinfo"))
+			hb.WriteElementClose("p")
+
+			// Codes
+			hb.WriteElementOpen("ul")
+			for _, code := range codes {
+				hb.WriteElementOpen("li")
+				hb.WriteElementOpen("code")
+				hb.WriteEscaped(code)
+				hb.WriteElementClose("code")
+				hb.WriteElementClose("li")
+			}
+			hb.WriteElementClose("ul")
+
+			// Back to settings
+			hb.WriteElementOpen("p")
+			hb.WriteElementOpen("a", "href", rd.Blog.getRelativePath(settingsPath))
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "settings"))
+			hb.WriteElementClose("a")
+			hb.WriteElementClose("p")
+
+			hb.WriteElementClose("main")
+		},
+	)
+}
+
+func (a *goBlog) renderAppPasswordCreated(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	apw, ok := rd.Data.(*configAppPassword)
+	if !ok {
+		return
+	}
+	a.renderBase(
+		hb, rd,
+		func(hb *htmlbuilder.HtmlBuilder) {
+			a.renderTitleTag(hb, rd.Blog, a.ts.GetTemplateStringVariant(rd.Blog.Lang, "apppasswords"))
+		},
+		func(hb *htmlbuilder.HtmlBuilder) {
+			hb.WriteElementOpen("main")
+
+			// Title
+			hb.WriteElementOpen("h1")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "apppasswords"))
+			hb.WriteElementClose("h1")
+
+			// Info
+			hb.WriteElementOpen("p")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "apppasswordinfo"))
+			hb.WriteElementClose("p")
+
+			// Credentials
+			hb.WriteElementOpen("ul")
+			hb.WriteElementOpen("li")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "username"))
+			hb.WriteEscaped(": ")
+			hb.WriteElementOpen("code")
+			hb.WriteEscaped(apw.Username)
+			hb.WriteElementClose("code")
+			hb.WriteElementClose("li")
+			hb.WriteElementOpen("li")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "password"))
+			hb.WriteEscaped(": ")
+			hb.WriteElementOpen("code")
+			hb.WriteEscaped(apw.Password)
+			hb.WriteElementClose("code")
+			hb.WriteElementClose("li")
+			hb.WriteElementClose("ul")
+
+			// Back to settings
+			hb.WriteElementOpen("p")
+			hb.WriteElementOpen("a", "href", rd.Blog.getRelativePath(settingsPath))
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "settings"))
+			hb.WriteElementClose("a")
+			hb.WriteElementClose("p")
+
+			hb.WriteElementClose("main")
+		},
+	)
+}
+
 type activityPubFollowersRenderData struct {
 	apUser    string
 	followers []*apFollower
@@ -1677,9 +1941,14 @@ func (a *goBlog) renderCommentEditor(h *htmlbuilder.HtmlBuilder, rd *renderData)
 			a.renderTitleTag(hb, rd.Blog, a.ts.GetTemplateStringVariant(rd.Blog.Lang, "editcommenttitle"))
 		},
 		func(hb *htmlbuilder.HtmlBuilder) {
+			// Commenters edit their own comment with a token
+			token, _ := rd.req.Context().Value(commentEditTokenKey).(string)
 			// Form
 			hb.WriteElementOpen("form", "class", "fw p", "method", "post")
 			hb.WriteElementOpen("input", "type", "hidden", "name", "id", "value", c.ID)
+			if token != "" {
+				hb.WriteElementOpen("input", "type", "hidden", "name", "token", "value", token)
+			}
 			hb.WriteElementOpen("input", "type", "text", "disabled", "", "value", c.Target)
 			if c.Original != "" {
 				hb.WriteElementOpen("input", "type", "text", "disabled", "", "value", c.Original)
@@ -1695,6 +1964,19 @@ func (a *goBlog) renderCommentEditor(h *htmlbuilder.HtmlBuilder, rd *renderData)
 			hb.WriteElementClose("textarea")
 			hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "update"))
 			hb.WriteElementClose("form")
+			if token != "" {
+				// Delete
+				hb.WriteElementOpen("form", "class", "fw p", "method", "post", "action", rd.Blog.getRelativePath(commentPath+commentAuthorDeleteSubPath))
+				hb.WriteElementOpen("input", "type", "hidden", "name", "id", "value", c.ID)
+				hb.WriteElementOpen("input", "type", "hidden", "name", "token", "value", token)
+				hb.WriteElementOpen(
+					"input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "delete"),
+					"class", "confirm", "data-confirmmessage", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "confirmdelete"),
+				)
+				hb.WriteElementClose("form")
+				hb.WriteElementOpen("script", "defer", "", "src", a.assetFileName("js/formconfirm.js"))
+				hb.WriteElementClose("script")
+			}
 		},
 	)
 }
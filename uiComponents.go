@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/mergestat/timediff"
 	"github.com/samber/lo"
 	"go.goblog.app/app/pkgs/htmlbuilder"
 	"go.goblog.app/app/pkgs/plugintypes"
@@ -97,6 +102,215 @@ func (a *goBlog) renderSummary(origHb *htmlbuilder.HtmlBuilder, rd *renderData,
 	hb.WriteElementClose("article")
 }
 
+// search result with highlighted title and content snippet
+func (a *goBlog) renderSearchSummary(hb *htmlbuilder.HtmlBuilder, rd *renderData, p *post, hl *searchHighlight) {
+	if p == nil || hl == nil {
+		return
+	}
+	hb.WriteElementOpen("article", "class", "h-entry")
+	title := hl.title
+	if !strings.Contains(title, "<mark>") {
+		title = html.EscapeString(p.RenderedTitle)
+	}
+	if title != "" {
+		hb.WriteElementOpen("h2", "class", "p-name")
+		hb.WriteElementOpen("a", "class", "u-url", "href", p.Path)
+		hb.WriteUnescaped(title)
+		hb.WriteElementClose("a")
+		hb.WriteElementClose("h2")
+	}
+	a.renderPostMeta(hb, p, rd.Blog, "summary")
+	hb.WriteElementOpen("div", "class", "e-content")
+	if strings.Contains(hl.snippet, "<mark>") {
+		hb.WriteUnescaped(hl.snippet)
+	} else {
+		hb.WriteEscaped(a.postSummary(p))
+	}
+	hb.WriteElementClose("div")
+	hb.WriteElementOpen("p", "class", "hide")
+	hb.WriteElementOpen("a", "class", "permalink u-url", "href", p.Path)
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "view"))
+	hb.WriteElementClose("a")
+	hb.WriteElementClose("p")
+	hb.WriteElementClose("article")
+}
+
+// links to sort search results by relevance or date
+func (a *goBlog) renderSearchSort(hb *htmlbuilder.HtmlBuilder, rd *renderData, id *indexRenderData) {
+	values, _ := url.ParseQuery(strings.TrimPrefix(id.paramUrlQuery, "?"))
+	values.Del(searchSortParam)
+	relevanceLink := id.first
+	if len(values) > 0 {
+		relevanceLink += "?" + values.Encode()
+	}
+	values.Set(searchSortParam, searchSortDate)
+	dateLink := id.first + "?" + values.Encode()
+	hb.WriteElementOpen("p")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "sortby"))
+	hb.WriteUnescaped(" ")
+	for i, option := range []struct {
+		link, key string
+		active    bool
+	}{
+		{relevanceLink, "sortrelevance", !id.searchDateOrder},
+		{dateLink, "sortdate", id.searchDateOrder},
+	} {
+		if i > 0 {
+			hb.WriteUnescaped(" | ")
+		}
+		if option.active {
+			hb.WriteElementOpen("strong")
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, option.key))
+			hb.WriteElementClose("strong")
+			continue
+		}
+		hb.WriteElementOpen("a", "href", option.link)
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, option.key))
+		hb.WriteElementClose("a")
+	}
+	hb.WriteElementClose("p")
+}
+
+// facets and date range to filter search results
+func (a *goBlog) renderSearchFilters(hb *htmlbuilder.HtmlBuilder, rd *renderData, id *indexRenderData) {
+	values, _ := url.ParseQuery(strings.TrimPrefix(id.paramUrlQuery, "?"))
+	// Link to the results with a filter value added or removed
+	filterLink := func(param, value string, active bool) string {
+		newValues := url.Values{}
+		for p, v := range values {
+			newValues[p] = append([]string{}, v...)
+		}
+		if active {
+			if newValues[param] = lo.Without(newValues[param], value); len(newValues[param]) == 0 {
+				newValues.Del(param)
+			}
+		} else {
+			newValues.Add(param, value)
+		}
+		if len(newValues) == 0 {
+			return id.first
+		}
+		return id.first + "?" + newValues.Encode()
+	}
+	// Facets
+	for _, facet := range id.searchFacets {
+		hb.WriteElementOpen("p")
+		hb.WriteElementOpen("strong")
+		hb.WriteEscaped(facet.title)
+		hb.WriteElementClose("strong")
+		hb.WriteUnescaped(": ")
+		for i, fv := range facet.values {
+			if i > 0 {
+				hb.WriteUnescaped(", ")
+			}
+			active := lo.Contains(values[facet.param], fv.value)
+			hb.WriteElementOpen("a", "href", filterLink(facet.param, fv.value, active), "rel", "nofollow")
+			if active {
+				hb.WriteElementOpen("mark")
+				hb.WriteEscaped("✕ ")
+			}
+			hb.WriteEscaped(fv.title)
+			if active {
+				hb.WriteElementClose("mark")
+			}
+			hb.WriteElementClose("a")
+			hb.WriteEscaped(fmt.Sprintf(" (%d)", fv.count))
+		}
+		hb.WriteElementClose("p")
+	}
+	// Date range
+	hb.WriteElementOpen("form", "class", "fw p", "method", "get", "action", id.first)
+	for param, paramValues := range values {
+		if param == searchFromParam || param == searchToParam {
+			continue
+		}
+		for _, value := range paramValues {
+			hb.WriteElementOpen("input", "type", "hidden", "name", param, "value", value)
+		}
+	}
+	a.renderSearchDateRange(hb, rd, values.Get(searchFromParam), values.Get(searchToParam))
+	hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "searchfilter"))
+	hb.WriteElementClose("form")
+}
+
+// notes about fuzzy search results and a suggestion for a similar search
+func (a *goBlog) renderSearchNotes(hb *htmlbuilder.HtmlBuilder, rd *renderData, id *indexRenderData) {
+	if id.searchFuzzy && len(id.posts) > 0 {
+		hb.WriteElementOpen("p")
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "searchfuzzy"))
+		hb.WriteElementClose("p")
+	}
+	if id.searchSuggestion != "" {
+		hb.WriteElementOpen("p")
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "searchsuggestion"))
+		hb.WriteUnescaped(" ")
+		hb.WriteElementOpen("a", "href", path.Dir(id.first)+"/"+searchEncode(id.searchSuggestion)+id.paramUrlQuery)
+		hb.WriteEscaped(id.searchSuggestion)
+		hb.WriteElementClose("a")
+		hb.WriteEscaped("?")
+		hb.WriteElementClose("p")
+	}
+}
+
+// filters in the search form
+func (a *goBlog) renderSearchFormFilters(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	lang := rd.Blog.Lang
+	all := a.ts.GetTemplateStringVariant(lang, "searchall")
+	type searchSelect struct {
+		param, title string
+		options      [][2]string // value and title
+	}
+	selects := []searchSelect{
+		{
+			searchSectionParam, a.ts.GetTemplateStringVariant(lang, "searchsection"),
+			lo.Map(sortedStrings(lo.Keys(rd.Blog.Sections)), func(name string, _ int) [2]string {
+				return [2]string{name, a.renderMdTitle(defaultIfEmpty(rd.Blog.Sections[name].Title, name))}
+			}),
+		},
+		{
+			searchTypeParam, a.ts.GetTemplateStringVariant(lang, "searchtype"),
+			lo.Map(a.searchPostTypes(), func(t *searchPostType, _ int) [2]string {
+				return [2]string{t.name, a.ts.GetTemplateStringVariant(lang, "searchtype"+t.name)}
+			}),
+		},
+	}
+	if rd.LoggedIn() {
+		selects = append(selects, searchSelect{
+			searchStatusParam, a.ts.GetTemplateStringVariant(lang, "status"),
+			lo.Map(searchStatuses, func(s postStatus, _ int) [2]string { return [2]string{string(s), string(s)} }),
+		}, searchSelect{
+			searchVisibilityParam, a.ts.GetTemplateStringVariant(lang, "visibility"),
+			lo.Map(searchVisibilities, func(v postVisibility, _ int) [2]string { return [2]string{string(v), string(v)} }),
+		})
+	}
+	for _, sel := range selects {
+		hb.WriteElementOpen("label", "for", "search-"+sel.param)
+		hb.WriteEscaped(sel.title)
+		hb.WriteElementClose("label")
+		hb.WriteElementOpen("select", "id", "search-"+sel.param, "name", sel.param)
+		hb.WriteElementOpen("option", "value", "")
+		hb.WriteEscaped(all)
+		hb.WriteElementClose("option")
+		for _, option := range sel.options {
+			hb.WriteElementOpen("option", "value", option[0])
+			hb.WriteEscaped(option[1])
+			hb.WriteElementClose("option")
+		}
+		hb.WriteElementClose("select")
+	}
+	a.renderSearchDateRange(hb, rd, "", "")
+}
+
+// date inputs to filter search results
+func (a *goBlog) renderSearchDateRange(hb *htmlbuilder.HtmlBuilder, rd *renderData, from, to string) {
+	for _, input := range []struct{ param, value string }{{searchFromParam, from}, {searchToParam, to}} {
+		hb.WriteElementOpen("label", "for", "search-"+input.param)
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "search"+input.param))
+		hb.WriteElementClose("label")
+		hb.WriteElementOpen("input", "type", "date", "id", "search-"+input.param, "name", input.param, "value", input.value)
+	}
+}
+
 // list of post taxonomy values (tags, series, etc.)
 func (a *goBlog) renderPostTax(hb *htmlbuilder.HtmlBuilder, p *post, b *configBlog) {
 	if b == nil || p == nil {
@@ -336,7 +550,30 @@ func (a *goBlog) renderTranslateButton(hb *htmlbuilder.HtmlBuilder, p *post, b *
 	hb.WriteElementClose("script")
 }
 
+// Approve (if awaiting moderation), delete and spam actions for the comments admin
+func (a *goBlog) renderCommentActions(hb *htmlbuilder.HtmlBuilder, rd *renderData, c *comment) {
+	hb.WriteElementOpen("form", "class", "actions", "method", "post")
+	hb.WriteElementOpen("input", "type", "hidden", "name", "commentid", "value", c.ID)
+	if c.Status != commentStatusApproved {
+		hb.WriteElementOpen("input", "type", "submit", "formaction", rd.Blog.getRelativePath(commentPath+commentApproveSubPath), "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "approve"))
+	}
+	hb.WriteElementOpen(
+		"input", "type", "submit", "formaction", rd.Blog.getRelativePath(commentPath+commentDeleteSubPath),
+		"value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, lo.If(c.Status != commentStatusApproved, "reject").Else("delete")),
+	)
+	if a.spamFilterEnabled() {
+		hb.WriteElementOpen("input", "type", "submit", "formaction", rd.Blog.getRelativePath(commentPath+commentSpamSubPath), "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "spam"))
+		hb.WriteElementOpen("input", "type", "submit", "formaction", rd.Blog.getRelativePath(commentPath+commentNotSpamSubPath), "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "notspam"))
+	}
+	hb.WriteElementClose("form")
+}
+
 func (a *goBlog) renderInteractions(hb *htmlbuilder.HtmlBuilder, rd *renderData) {
+	// On comment pages, interactions (replies) target the local comment address
+	target := rd.Canonical
+	if c, ok := rd.Data.(*comment); ok {
+		target = a.getFullAddress(rd.Blog.getRelativePath(fmt.Sprintf("%s/%d", commentPath, c.ID)))
+	}
 	// Start accordion
 	hb.WriteElementOpen("details", "id", "interactions", "open", "")
 	hb.WriteElementOpen("summary")
@@ -345,28 +582,138 @@ func (a *goBlog) renderInteractions(hb *htmlbuilder.HtmlBuilder, rd *renderData)
 	hb.WriteElementClose("strong")
 	hb.WriteElementClose("summary")
 	// Render mentions
+	commentsPrefix := a.getFullAddress(rd.Blog.getRelativePath(commentPath)) + "/"
+	renderAvatar := func(mention *mention, photoClass string) {
+		name := defaultIfEmpty(mention.Author, mention.Url)
+		// Only show locally cached avatars, hotlinking would leak the visitor's IP
+		if strings.HasPrefix(mention.AuthorPhoto, mentionAvatarPath) {
+			hb.WriteElementOpen("img", "class", strings.TrimSpace("mention-avatar "+photoClass), "src", mention.AuthorPhoto, "alt", name, "title", name, "loading", "lazy")
+			return
+		}
+		// Fallback to the first letter of the name
+		hb.WriteElementOpen("span", "class", "mention-avatar", "title", name)
+		if initial := []rune(strings.TrimPrefix(strings.TrimPrefix(name, "https://"), "http://")); len(initial) > 0 {
+			hb.WriteEscaped(strings.ToUpper(string(initial[0])))
+		}
+		hb.WriteElementClose("span")
+	}
+	mentions := a.db.getWebmentionsByAddress(target)
+	// Load the local comments of all mentions at once
+	var commentIds []int
+	var collectCommentIds func(m []*mention)
+	collectCommentIds = func(m []*mention) {
+		for _, mention := range m {
+			if id, err := strconv.Atoi(strings.TrimPrefix(mention.Source, commentsPrefix)); err == nil && strings.HasPrefix(mention.Source, commentsPrefix) {
+				commentIds = append(commentIds, id)
+			}
+			collectCommentIds(mention.Submentions)
+		}
+	}
+	collectCommentIds(mentions)
+	localComments := map[string]*comment{}
+	if len(commentIds) > 0 {
+		if comments, err := a.db.getComments(&commentsRequestConfig{ids: commentIds}); err == nil {
+			for _, c := range comments {
+				localComments[commentsPrefix+strconv.Itoa(c.ID)] = c
+			}
+		}
+	}
 	var renderMentions func(m []*mention)
 	renderMentions = func(m []*mention) {
+		// Private webmentions are only visible for logged-in users
+		m = lo.Filter(m, func(mention *mention, _ int) bool {
+			return !mention.Private || rd.LoggedIn()
+		})
+		// Likes, reposts and bookmarks as facepiles
+		for _, typ := range []mentionType{mentionTypeLike, mentionTypeRepost, mentionTypeBookmark} {
+			faces := lo.Filter(m, func(mention *mention, _ int) bool {
+				return mention.Type == typ && !strings.HasPrefix(mention.Source, commentsPrefix)
+			})
+			if len(faces) == 0 {
+				continue
+			}
+			hb.WriteElementOpen("div", "class", "facepile")
+			hb.WriteElementOpen("strong")
+			hb.WriteEscaped(fmt.Sprintf("%s (%d)", a.ts.GetTemplateStringVariant(rd.Blog.Lang, string(typ)+"s"), len(faces)))
+			hb.WriteElementClose("strong")
+			for _, mention := range faces {
+				hb.WriteElementOpen("a", "href", mention.Url, "target", "_blank", "rel", "nofollow noopener noreferrer ugc")
+				renderAvatar(mention, "")
+				hb.WriteElementClose("a")
+			}
+			hb.WriteElementClose("div")
+		}
+		// Replies as cards, other mentions compact
+		m = lo.Filter(m, func(mention *mention, _ int) bool {
+			return !lo.Contains([]mentionType{mentionTypeLike, mentionTypeRepost, mentionTypeBookmark}, mention.Type) || strings.HasPrefix(mention.Source, commentsPrefix)
+		})
 		if len(m) == 0 {
 			return
 		}
 		hb.WriteElementOpen("ul")
 		for _, mention := range m {
-			hb.WriteElementOpen("li")
-			hb.WriteElementOpen("a", "href", mention.Url, "target", "_blank", "rel", "nofollow noopener noreferrer ugc")
+			// Local comments are always replies
+			isReply := mention.Type == mentionTypeReply || strings.HasPrefix(mention.Source, commentsPrefix)
+			if !isReply {
+				hb.WriteElementOpen("li", "class", "mention-"+string(mention.Type))
+				renderAvatar(mention, "")
+			} else {
+				// Replies are comments of the post (microformats), so salmentions carry them
+				hb.WriteElementOpen("li", "class", "mention-reply u-comment h-cite")
+				hb.WriteElementOpen("span", "class", "u-author h-card")
+				renderAvatar(mention, "u-photo")
+				hb.WriteElementOpen("data", "value", defaultIfEmpty(mention.Author, mention.Url), "class", "p-name hide")
+				hb.WriteElementClose("data")
+				hb.WriteElementClose("span")
+				if mention.Created > 0 {
+					hb.WriteElementOpen("data", "value", time.Unix(mention.Created, 0).UTC().Format(time.RFC3339), "class", "dt-published hide")
+					hb.WriteElementClose("data")
+				}
+			}
+			hb.WriteUnescaped(" ")
+			linkAttrs := []any{"href", mention.Url, "target", "_blank", "rel", "nofollow noopener noreferrer ugc"}
+			if isReply {
+				linkAttrs = append(linkAttrs, "class", "u-url")
+			}
+			hb.WriteElementOpen("a", linkAttrs...)
 			hb.WriteEscaped(defaultIfEmpty(mention.Author, mention.Url))
 			hb.WriteElementClose("a")
+			if mention.Type == mentionTypeRSVP {
+				hb.WriteUnescaped(" ")
+				hb.WriteElementOpen("em")
+				hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "rsvp"))
+				hb.WriteElementClose("em")
+			}
 			if mention.Title != "" {
 				hb.WriteUnescaped(" ")
 				hb.WriteElementOpen("strong")
 				hb.WriteEscaped(mention.Title)
 				hb.WriteElementClose("strong")
 			}
-			if mention.Content != "" {
+			if !isReply {
+				// Compact display without content
+				if len(mention.Submentions) > 0 {
+					renderMentions(mention.Submentions)
+				}
+				hb.WriteElementClose("li")
+				continue
+			}
+			contentHtml := a.renderCommentMarkdown(mention.Content)
+			if c, ok := localComments[mention.Source]; ok {
+				// Use the Markdown source of local comments, the webmention only has the plain text
+				contentHtml = a.renderCommentContent(c)
+			}
+			if contentHtml != "" {
+				hb.WriteElementOpen("div", "class", "mention-content e-content")
+				hb.WriteUnescaped(contentHtml)
+				hb.WriteElementClose("div")
+			}
+			if strings.HasPrefix(mention.Source, commentsPrefix) {
+				// Link to reply to the comment
 				hb.WriteUnescaped(" ")
-				hb.WriteElementOpen("i")
-				hb.WriteEscaped(mention.Content)
-				hb.WriteElementClose("i")
+				hb.WriteElementOpen("a", "href", mention.Source+"#interactions", "class", "reply")
+				hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "reply"))
+				hb.WriteElementClose("a")
 			}
 			if len(mention.Submentions) > 0 {
 				renderMentions(mention.Submentions)
@@ -375,23 +722,40 @@ func (a *goBlog) renderInteractions(hb *htmlbuilder.HtmlBuilder, rd *renderData)
 		}
 		hb.WriteElementClose("ul")
 	}
-	renderMentions(a.db.getWebmentionsByAddress(rd.Canonical))
+	renderMentions(mentions)
 	// Show form to send a webmention
 	hb.WriteElementOpen("form", "class", "fw p", "method", "post", "action", "/webmention")
 	hb.WriteElementOpen("label", "for", "wm-source", "class", "p")
 	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "interactionslabel"))
 	hb.WriteElementClose("label")
 	hb.WriteElementOpen("input", "id", "wm-source", "type", "url", "name", "source", "placeholder", "URL", "required", "")
-	hb.WriteElementOpen("input", "type", "hidden", "name", "target", "value", rd.Canonical)
+	hb.WriteElementOpen("input", "type", "hidden", "name", "target", "value", target)
 	hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "send"))
 	hb.WriteElementClose("form")
-	Show form to create a new comment
+	// Show form to create a new comment
 	hb.WriteElementOpen("form", "class", "fw p", "method", "post", "action", rd.Blog.getRelativePath(commentPath))
-	hb.WriteElementOpen("input", "type", "hidden", "name", "target", "value", rd.Canonical)
+	hb.WriteElementOpen("input", "type", "hidden", "name", "target", "value", target)
 	hb.WriteElementOpen("input", "type", "text", "name", "name", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "nameopt"))
 	hb.WriteElementOpen("input", "type", "url", "name", "website", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "websiteopt"))
-	hb.WriteElementOpen("textarea", "name", "comment", "required", "", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "comment"))
+	if rd.Blog.commentEmailsEnabled() {
+		hb.WriteElementOpen("input", "type", "email", "name", "email", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "emailoptprivate"))
+	}
+	hb.WriteElementOpen(
+		"textarea", "name", "comment", "required", "", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "comment"),
+		"data-preview", rd.Blog.getRelativePath(commentPath+commentPreviewSubPath), "data-previewlabel", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "preview"),
+	)
 	hb.WriteElementClose("textarea")
+	hb.WriteElementOpen("p", "class", "comment-markdown")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "commentmarkdown"))
+	hb.WriteElementClose("p")
+	hb.WriteElementOpen("script", "defer", "", "src", a.assetFileName("js/commentpreview.js"))
+	hb.WriteElementClose("script")
+	if rd.Blog.commentEmailsEnabled() {
+		hb.WriteElementOpen("input", "type", "checkbox", "name", "subscribe", "id", "comment-subscribe")
+		hb.WriteElementOpen("label", "for", "comment-subscribe")
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "notifycomments"))
+		hb.WriteElementClose("label")
+	}
 	hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "docomment"))
 	hb.WriteElementClose("form")
 	// Finish accordion
@@ -553,6 +917,52 @@ func (a *goBlog) renderPostReactions(hb *htmlbuilder.HtmlBuilder, p *post) {
 	hb.WriteElementClose("script")
 }
 
+// Show the results of sending webmentions to external targets (only for logged-in users)
+func (a *goBlog) renderSentWebmentions(hb *htmlbuilder.HtmlBuilder, rd *renderData, p *post) {
+	sent, err := a.db.getSentWebmentions(p.Path)
+	if err != nil || len(sent) == 0 {
+		return
+	}
+	hb.WriteElementOpen("details", "id", "sentwebmentions")
+	hb.WriteElementOpen("summary")
+	hb.WriteElementOpen("strong")
+	hb.WriteEscaped(fmt.Sprintf("%s (%d)", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "webmentionssent"), len(sent)))
+	hb.WriteElementClose("strong")
+	hb.WriteElementClose("summary")
+	hb.WriteElementOpen("ul")
+	for _, m := range sent {
+		hb.WriteElementOpen("li")
+		hb.WriteElementOpen("a", "href", m.Target, "target", "_blank", "rel", "noopener noreferrer")
+		hb.WriteEscaped(m.Target)
+		hb.WriteElementClose("a")
+		hb.WriteUnescaped(" &rarr; ")
+		switch {
+		case m.Endpoint == "" && m.Error == "":
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "noendpoint"))
+		case m.Endpoint == "":
+			// The endpoint discovery failed
+			hb.WriteEscaped(m.Error)
+		case m.Error != "":
+			hb.WriteEscaped(fmt.Sprintf("%s (%s)", m.Error, m.Endpoint))
+		default:
+			hb.WriteEscaped(fmt.Sprintf("HTTP %d (%s)", m.Status, m.Endpoint))
+		}
+		if m.Location != "" {
+			hb.WriteEscaped(", ")
+			hb.WriteElementOpen("a", "href", m.Location, "target", "_blank", "rel", "noopener noreferrer")
+			hb.WriteEscaped(m.Location)
+			hb.WriteElementClose("a")
+		}
+		hb.WriteEscaped(", ")
+		hb.WriteElementOpen("time", "datetime", m.Updated)
+		hb.WriteEscaped(m.Updated)
+		hb.WriteElementClose("time")
+		hb.WriteElementClose("li")
+	}
+	hb.WriteElementClose("ul")
+	hb.WriteElementClose("details")
+}
+
 func (a *goBlog) renderPostVideo(hb *htmlbuilder.HtmlBuilder, p *post) {
 	if !p.hasVideoPlaylist() {
 		return
@@ -624,6 +1034,18 @@ func (a *goBlog) renderPostSectionSettings(hb *htmlbuilder.HtmlBuilder, rd *rend
 		hb.WriteElementOpen("label", "for", "hideonstart-"+section.Name)
 		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "sectionhideonstart"))
 		hb.WriteElementClose("label")
+		hb.WriteElementsClose("br")
+		// Podcast
+		hb.WriteElementOpen("input", "type", "checkbox", "name", "sectionpodcast", "id", "podcast-"+section.Name, lo.If(section.Podcast, "checked").Else(""), "")
+		hb.WriteElementOpen("label", "for", "podcast-"+section.Name)
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "sectionpodcast"))
+		hb.WriteElementClose("label")
+		hb.WriteElementOpen("input", "type", "text", "name", "sectionpodcastcategory", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "sectionpodcastcategory"), "value", section.PodcastCategory)
+		hb.WriteElementOpen("input", "type", "checkbox", "name", "sectionpodcastemail", "id", "podcastemail-"+section.Name, lo.If(section.PodcastEmail, "checked").Else(""), "")
+		hb.WriteElementOpen("label", "for", "podcastemail-"+section.Name)
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "sectionpodcastemail"))
+		hb.WriteElementClose("label")
+		hb.WriteElementsClose("br")
 
 		// Actions
 		hb.WriteElementOpen("div", "class", "p")
@@ -713,6 +1135,194 @@ func (a *goBlog) renderUserSettings(hb *htmlbuilder.HtmlBuilder, rd *renderData,
 	hb.WriteElementClose("form")
 }
 
+func (a *goBlog) renderSecuritySettings(hb *htmlbuilder.HtmlBuilder, rd *renderData, srd *settingsRenderData) {
+	hb.WriteElementOpen("h2")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "security"))
+	hb.WriteElementClose("h2")
+
+	tdLocale := matchTimeDiffLocale(rd.Blog.Lang)
+
+	// Active sessions
+	hb.WriteElementOpen("h3")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "activesessions"))
+	hb.WriteElementClose("h3")
+
+	hb.WriteElementOpen("table")
+	hb.WriteElementOpen("thead")
+	hb.WriteElementOpen("tr")
+	for _, th := range []string{"device", "created", "lastseen", ""} {
+		hb.WriteElementOpen("th")
+		if th != "" {
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, th))
+		}
+		hb.WriteElementClose("th")
+	}
+	hb.WriteElementClose("tr")
+	hb.WriteElementClose("thead")
+	hb.WriteElementOpen("tbody")
+	for _, ls := range srd.loginSessions {
+		hb.WriteElementOpen("tr")
+		// Device
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(defaultIfEmpty(ls.UserAgent, a.ts.GetTemplateStringVariant(rd.Blog.Lang, "unknowndevice")))
+		hb.WriteElementClose("td")
+		// Created
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(timediff.TimeDiff(ls.Created, timediff.WithLocale(tdLocale)))
+		hb.WriteElementClose("td")
+		// Last seen
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(timediff.TimeDiff(ls.LastSeen, timediff.WithLocale(tdLocale)))
+		hb.WriteElementClose("td")
+		// Revoke
+		hb.WriteElementOpen("td")
+		if ls.ID == srd.currentSession {
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "currentsession"))
+		} else {
+			hb.WriteElementOpen("form", "method", "post", "action", rd.Blog.getRelativePath(settingsPath+settingsRevokeSessionPath))
+			hb.WriteElementOpen("input", "type", "hidden", "name", "sessionid", "value", ls.ID)
+			hb.WriteElementOpen("input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "revoke"))
+			hb.WriteElementClose("form")
+		}
+		hb.WriteElementClose("td")
+		hb.WriteElementClose("tr")
+	}
+	hb.WriteElementClose("tbody")
+	hb.WriteElementClose("table")
+
+	hb.WriteElementOpen("form", "class", "fw p", "method", "post")
+	hb.WriteElementOpen("input", "type", "hidden", "name", "others", "value", "on")
+	hb.WriteElementOpen(
+		"input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "revokeothersessions"),
+		"formaction", rd.Blog.getRelativePath(settingsPath+settingsRevokeSessionPath),
+		"class", "confirm", "data-confirmmessage", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "revokeothersessions"),
+	)
+	hb.WriteElementClose("form")
+
+	// TOTP recovery codes
+	if srd.totp {
+		hb.WriteElementOpen("h3")
+		hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "recoverycodes"))
+		hb.WriteElementClose("h3")
+
+		hb.WriteElementOpen("p")
+		hb.WriteEscaped(fmt.Sprintf(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "recoverycodesdesc"), srd.recoveryCodes))
+		hb.WriteElementClose("p")
+
+		hb.WriteElementOpen("form", "class", "fw p", "method", "post")
+		hb.WriteElementOpen(
+			"input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "generaterecoverycodes"),
+			"formaction", rd.Blog.getRelativePath(settingsPath+settingsRecoveryCodesPath),
+			"class", "confirm", "data-confirmmessage", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "generaterecoverycodes"),
+		)
+		hb.WriteElementClose("form")
+	}
+
+	// App passwords
+	hb.WriteElementOpen("h3")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "apppasswords"))
+	hb.WriteElementClose("h3")
+
+	hb.WriteElementOpen("table")
+	hb.WriteElementOpen("thead")
+	hb.WriteElementOpen("tr")
+	for _, th := range []string{"username", "scopes", "created", "lastused", ""} {
+		hb.WriteElementOpen("th")
+		if th != "" {
+			hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, th))
+		}
+		hb.WriteElementClose("th")
+	}
+	hb.WriteElementClose("tr")
+	hb.WriteElementClose("thead")
+	hb.WriteElementOpen("tbody")
+	for _, apw := range srd.appPasswords {
+		hb.WriteElementOpen("tr")
+		// Username
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(apw.Username)
+		hb.WriteElementClose("td")
+		// Scopes
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(strings.Join(apw.Scopes, ", "))
+		hb.WriteElementClose("td")
+		// Created
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(timediff.TimeDiff(apw.Created, timediff.WithLocale(tdLocale)))
+		hb.WriteElementClose("td")
+		// Last used
+		hb.WriteElementOpen("td")
+		if !apw.LastUsed.IsZero() {
+			hb.WriteEscaped(timediff.TimeDiff(apw.LastUsed, timediff.WithLocale(tdLocale)))
+		}
+		hb.WriteElementClose("td")
+		// Delete
+		hb.WriteElementOpen("td")
+		hb.WriteElementOpen("form", "method", "post", "action", rd.Blog.getRelativePath(settingsPath+settingsDeleteAppPasswordPath))
+		hb.WriteElementOpen("input", "type", "hidden", "name", "apppasswordid", "value", apw.ID)
+		hb.WriteElementOpen(
+			"input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "revoke"),
+			"class", "confirm", "data-confirmmessage", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "confirmdelete"),
+		)
+		hb.WriteElementClose("form")
+		hb.WriteElementClose("td")
+		hb.WriteElementClose("tr")
+	}
+	hb.WriteElementClose("tbody")
+	hb.WriteElementClose("table")
+
+	// Create app password
+	hb.WriteElementOpen("p")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "apppasswordsdesc"))
+	hb.WriteElementClose("p")
+
+	hb.WriteElementOpen("form", "class", "fw p", "method", "post")
+	hb.WriteElementOpen("input", "type", "text", "name", "apppasswordusername", "required", "", "placeholder", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "username"))
+	for _, scope := range appPasswordScopes {
+		hb.WriteElementOpen("input", "type", "checkbox", "name", "apppasswordscopes", "value", scope, "id", "apppasswordscope-"+scope)
+		hb.WriteElementOpen("label", "for", "apppasswordscope-"+scope)
+		hb.WriteEscaped(scope)
+		hb.WriteElementClose("label")
+		hb.WriteElementsClose("br")
+	}
+	hb.WriteElementOpen(
+		"input", "type", "submit", "value", a.ts.GetTemplateStringVariant(rd.Blog.Lang, "create"),
+		"formaction", rd.Blog.getRelativePath(settingsPath+settingsCreateAppPasswordPath),
+	)
+	hb.WriteElementClose("form")
+
+	// Security log
+	hb.WriteElementOpen("h3")
+	hb.WriteEscaped(a.ts.GetTemplateStringVariant(rd.Blog.Lang, "securitylog"))
+	hb.WriteElementClose("h3")
+
+	hb.WriteElementOpen("table")
+	hb.WriteElementOpen("tbody")
+	for _, e := range srd.securityLog {
+		hb.WriteElementOpen("tr")
+		hb.WriteElementOpen("td")
+		hb.WriteElementOpen("time", "datetime", e.Time.Format(time.RFC3339))
+		hb.WriteEscaped(timediff.TimeDiff(e.Time, timediff.WithLocale(tdLocale)))
+		hb.WriteElementClose("time")
+		hb.WriteElementClose("td")
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(e.Event)
+		hb.WriteElementClose("td")
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(e.Username)
+		hb.WriteElementClose("td")
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(e.IP)
+		hb.WriteElementClose("td")
+		hb.WriteElementOpen("td")
+		hb.WriteEscaped(e.UserAgent)
+		hb.WriteElementClose("td")
+		hb.WriteElementClose("tr")
+	}
+	hb.WriteElementClose("tbody")
+	hb.WriteElementClose("table")
+}
+
 func (a *goBlog) renderFooter(origHb *htmlbuilder.HtmlBuilder, rd *renderData) {
 	// Wrap plugins
 	hb, finish := a.wrapForPlugins(origHb, a.getPlugins(pluginUiFooterType), func(plugin any, doc *goquery.Document) {
@@ -136,6 +136,55 @@ func Test_renderInteractions(t *testing.T) {
 	assert.Equal(t, expected, res)
 }
 
+func Test_renderInteractionsTypes(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	_ = app.initConfig(false)
+	_ = app.initCache()
+	app.initMarkdown()
+	_ = app.initTemplateStrings()
+
+	for i, m := range []*mention{
+		{Source: "https://example.org/like1", Author: "Alice", AuthorPhoto: "/-/avatar/abc", Type: mentionTypeLike},
+		{Source: "https://example.org/like2", Author: "bob", AuthorPhoto: "https://example.org/bob.jpg", Type: mentionTypeLike},
+		{Source: "https://example.org/repost", Author: "Carol", Type: mentionTypeRepost},
+		{Source: "https://example.org/reply", Author: "Dave", Content: "Great *post*", Type: mentionTypeReply},
+		{Source: "https://example.org/mention", Author: "Eve", Title: "Linked", Content: "Not shown", Type: mentionTypeMention},
+	} {
+		m.Target = "https://example.com/post"
+		m.Url = m.Source
+		m.Created = int64(i)
+		require.NoError(t, app.db.insertWebmention(m, webmentionStatusApproved))
+	}
+
+	buf := &bytes.Buffer{}
+	hb := htmlbuilder.NewHtmlBuilder(buf)
+	app.renderInteractions(hb, &renderData{
+		Blog:      app.cfg.Blogs["default"],
+		Canonical: "https://example.com/post",
+	})
+
+	doc, err := goquery.NewDocumentFromReader(buf)
+	require.NoError(t, err)
+
+	facepiles := doc.Find(".facepile")
+	require.Equal(t, 2, facepiles.Length())
+	assert.Contains(t, facepiles.First().Text(), "(2)")
+	assert.Equal(t, "/-/avatar/abc", facepiles.First().Find("img.mention-avatar").AttrOr("src", ""))
+	// Remote photos aren't hotlinked
+	assert.Equal(t, 1, facepiles.First().Find("img").Length())
+	assert.Equal(t, "B", facepiles.First().Find("span.mention-avatar").Text())
+
+	// Only replies show the content
+	assert.Contains(t, doc.Find("li.mention-reply").Text(), "Dave")
+	assert.Equal(t, 1, doc.Find("li.mention-reply em").Length())
+	assert.Contains(t, doc.Find("li.mention-mention").Text(), "Linked")
+	assert.NotContains(t, doc.Find("li.mention-mention").Text(), "Not shown")
+}
+
 func Test_renderAuthor(t *testing.T) {
 	t.SkipNow()
 	// TODO: Add back some checks for image
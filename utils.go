@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -67,6 +69,23 @@ func randomString(n int, allowedChars ...rune) string {
 	return lo.RandomString(n, allowedChars)
 }
 
+// Like randomString, but uses a cryptographically secure random source (for passwords, tokens etc.)
+func secureRandomString(n int, allowedChars ...rune) (string, error) {
+	if len(allowedChars) == 0 {
+		allowedChars = append(allowedChars, defaultLetters...)
+	}
+	max := big.NewInt(int64(len(allowedChars)))
+	result := make([]rune, n)
+	for i := range result {
+		r, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = allowedChars[r.Int64()]
+	}
+	return string(result), nil
+}
+
 func isAbsoluteURL(s string) bool {
 	if u, err := url.Parse(s); err != nil || !u.IsAbs() {
 		return false
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"go.goblog.app/app/pkgs/contenttype"
+)
+
+// Status code of webmention endpoints that require a vouch (https://indieweb.org/Vouch)
+const webmentionStatusVouchRequired = 449
+
+func urlHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// Check if a domain is known, because a webmention from it was approved or a webmention was sent to it
+func (db *database) isKnownDomain(host string) bool {
+	if host == "" {
+		return false
+	}
+	row, err := db.QueryRow(
+		`select exists(
+			select 1 from webmentions where status = @approved and (lower(source) like @https or lower(source) like @http)
+			union all
+			select 1 from webmentionssent where status between 200 and 299 and (lower(target) like @https or lower(target) like @http)
+		)`,
+		sql.Named("approved", webmentionStatusApproved),
+		sql.Named("https", "https://"+host+"/%"), sql.Named("http", "http://"+host+"/%"),
+	)
+	if err != nil {
+		return false
+	}
+	var known bool
+	_ = row.Scan(&known)
+	return known
+}
+
+// Check if the vouch of the mention is on a known domain and links to the domain of the source
+func (a *goBlog) verifyVouch(m *mention) bool {
+	sourceHost, vouchHost := urlHost(defaultIfEmpty(m.NewSource, m.Source)), urlHost(m.Vouch)
+	if sourceHost == "" || vouchHost == "" || sourceHost == vouchHost || !a.db.isKnownDomain(vouchHost) {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.Vouch, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Accept", contenttype.HTMLUTF8)
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false
+	}
+	links, err := allLinksFromHTML(res.Body, m.Vouch)
+	if err != nil {
+		return false
+	}
+	return lo.SomeBy(links, func(link string) bool {
+		return urlHost(link) == sourceHost
+	})
+}
+
+// Find a vouch for a webmention to the target: a page of the target's domain
+// or another known site that mentioned us
+func (a *goBlog) findVouch(target string) string {
+	targetHost := urlHost(target)
+	if targetHost == "" {
+		return ""
+	}
+	row, err := a.db.QueryRow(
+		`select source from webmentions where status = @approved and source not like @local
+		order by (lower(source) like @https or lower(source) like @http) desc, created desc limit 1`,
+		sql.Named("approved", webmentionStatusApproved), sql.Named("local", a.cfg.Server.PublicAddress+"%"),
+		sql.Named("https", "https://"+targetHost+"/%"), sql.Named("http", "http://"+targetHost+"/%"),
+	)
+	if err != nil {
+		return ""
+	}
+	var vouch string
+	_ = row.Scan(&vouch)
+	return vouch
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goblog.app/app/pkgs/contenttype"
+)
+
+func Test_vouch(t *testing.T) {
+	fc := newFakeHttpClient()
+
+	app := &goBlog{
+		httpClient: fc.Client,
+		cfg:        createDefaultTestConfig(t),
+	}
+	app.cfg.Server.PublicAddress = "https://example.com"
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://known.example.org/post", Target: "https://example.com/a"}, webmentionStatusApproved))
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://other.example.org/post", Target: "https://example.com/b", Created: 1}, webmentionStatusApproved))
+	require.NoError(t, app.db.insertWebmention(&mention{Source: "https://held.example.org/post", Target: "https://example.com/c"}, webmentionStatusVerified))
+
+	assert.True(t, app.db.isKnownDomain("known.example.org"))
+	assert.False(t, app.db.isKnownDomain("held.example.org"))
+	assert.False(t, app.db.isKnownDomain("example.org"))
+
+	// Verify vouch
+	fc.setFakeResponse(http.StatusOK, `<a href="https://new.example.net/">New</a>`)
+	assert.True(t, app.verifyVouch(&mention{Source: "https://new.example.net/post", Vouch: "https://known.example.org/blogroll"}))
+	assert.Equal(t, "https://known.example.org/blogroll", fc.req.URL.String())
+	assert.False(t, app.verifyVouch(&mention{Source: "https://new.example.net/post", Vouch: "https://held.example.org/blogroll"}))
+	assert.False(t, app.verifyVouch(&mention{Source: "https://spam.example.net/post", Vouch: "https://known.example.org/blogroll"}))
+
+	// Find vouch, prefer the domain of the target
+	assert.Equal(t, "https://known.example.org/post", app.findVouch("https://known.example.org/other"))
+	assert.Equal(t, "https://other.example.org/post", app.findVouch("https://unknown.example.org/"))
+
+	// Send with vouch if required
+	fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			rw.Header().Set("Link", `</webmention>; rel="webmention"`)
+		case "/webmention":
+			if r.FormValue("vouch") == "" {
+				rw.WriteHeader(webmentionStatusVouchRequired)
+				return
+			}
+			assert.Equal(t, "https://known.example.org/post", r.FormValue("vouch"))
+			rw.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	retry := app.processWebmentionSendRequest(&webmentionSendRequest{Path: "/test", Source: "https://example.com/test", Target: "https://known.example.org/post"})
+	assert.False(t, retry)
+	sent, err := app.db.getSentWebmentions("/test")
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, http.StatusAccepted, sent[0].Status)
+}
+
+func Test_extractMentionVouch(t *testing.T) {
+	app := &goBlog{
+		cfg: createDefaultTestConfig(t),
+	}
+
+	extract := func(vouch string) (*mention, error) {
+		data := url.Values{}
+		data.Set("source", "https://example.org/post")
+		data.Set("target", "https://example.com/post")
+		data.Set("vouch", vouch)
+		req := httptest.NewRequest(http.MethodPost, "/webmention", strings.NewReader(data.Encode()))
+		req.Header.Set(contentType, contenttype.WWWForm)
+		return app.extractMention(req)
+	}
+
+	m, err := extract("https://example.net/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.net/", m.Vouch)
+
+	_, err = extract("invalid")
+	assert.Error(t, err)
+}
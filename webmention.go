@@ -5,20 +5,37 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/samber/lo"
 	"go.goblog.app/app/pkgs/builderpool"
 	"go.goblog.app/app/pkgs/contenttype"
 )
 
 type webmentionStatus string
 
+// Type of the mention, detected from the microformats of the source
+type mentionType string
+
+const (
+	mentionTypeMention  mentionType = "mention"
+	mentionTypeReply    mentionType = "reply"
+	mentionTypeLike     mentionType = "like"
+	mentionTypeRepost   mentionType = "repost"
+	mentionTypeBookmark mentionType = "bookmark"
+	mentionTypeRSVP     mentionType = "rsvp"
+)
+
 const (
 	webmentionStatusVerified webmentionStatus = "verified"
 	webmentionStatusApproved webmentionStatus = "approved"
 
 	webmentionPath = "/webmention"
+
+	// Maximum nesting of mentions of mentions (e.g. threaded comments)
+	webmentionMaxSubmentionsDepth = 5
 )
 
 type mention struct {
@@ -32,6 +49,12 @@ type mention struct {
 	Title       string
 	Content     string
 	Author      string
+	AuthorPhoto string
+	Type        mentionType
+	Vouch       string
+	// Private Webmention
+	Code        string
+	Private     bool
 	Status      webmentionStatus
 	Submentions []*mention
 }
@@ -45,8 +68,12 @@ func (a *goBlog) initWebmention() {
 	a.pUpdateHooks = append(a.pUpdateHooks, hookFunc)
 	a.pDeleteHooks = append(a.pDeleteHooks, hookFunc)
 	a.pUndeleteHooks = append(a.pUndeleteHooks, hookFunc)
-	// Start verifier
+	// Start verifier, sender and salmentions
 	a.initWebmentionQueue()
+	a.initWebmentionSendQueue()
+	a.initSalmentionQueue()
+	// Delete expired codes of private webmentions
+	a.hourlyHooks = append(a.hourlyHooks, a.deleteExpiredPrivateWebmentionCodes)
 }
 
 func (a *goBlog) handleWebmention(w http.ResponseWriter, r *http.Request) {
@@ -93,9 +120,17 @@ func (a *goBlog) extractMention(r *http.Request) (*mention, error) {
 		a.debug("Invalid webmention request, source:", source, "target:", target)
 		return nil, errors.New("invalid request")
 	}
+	// The optional realm of private webmentions is only informational and ignored
+	vouch, code := r.Form.Get("vouch"), r.Form.Get("code")
+	if vouch != "" && !isAbsoluteURL(vouch) {
+		a.debug("Invalid webmention vouch:", vouch)
+		return nil, errors.New("invalid vouch")
+	}
 	return &mention{
 		Source:  source,
 		Target:  target,
+		Vouch:   vouch,
+		Code:    code,
 		Created: time.Now().Unix(),
 	}, nil
 }
@@ -135,8 +170,8 @@ func (a *goBlog) createWebmention(source, target string) (err error) {
 func (db *database) insertWebmention(m *mention, status webmentionStatus) error {
 	_, err := db.Exec(
 		`
-		insert into webmentions (source, target, url, created, status, title, content, author) 
-		values (@source, lowerunescaped(@target), @url, @created, @status, @title, @content, @author)
+		insert into webmentions (source, target, url, created, status, title, content, author, authorphoto, type, private) 
+		values (@source, lowerunescaped(@target), @url, @created, @status, @title, @content, @author, @authorphoto, @type, @private)
 		`,
 		sql.Named("source", m.Source),
 		sql.Named("target", m.Target),
@@ -146,6 +181,9 @@ func (db *database) insertWebmention(m *mention, status webmentionStatus) error
 		sql.Named("title", m.Title),
 		sql.Named("content", m.Content),
 		sql.Named("author", m.Author),
+		sql.Named("authorphoto", m.AuthorPhoto),
+		sql.Named("type", defaultIfEmpty(string(m.Type), string(mentionTypeMention))),
+		sql.Named("private", m.Private),
 	)
 	return err
 }
@@ -160,7 +198,10 @@ func (db *database) updateWebmention(m *mention, newStatus webmentionStatus) err
 				status = @status,
 				title = @title,
 				content = @content,
-				author = @author
+				author = @author,
+				authorphoto = @authorphoto,
+				type = @type,
+				private = @private
 			where
 				lowerunescaped(source) in (lowerunescaped(@source), lowerunescaped(@newsource2))
 				and lowerunescaped(target) in (lowerunescaped(@target), lowerunescaped(@newtarget2))
@@ -172,6 +213,9 @@ func (db *database) updateWebmention(m *mention, newStatus webmentionStatus) err
 		sql.Named("title", m.Title),
 		sql.Named("content", m.Content),
 		sql.Named("author", m.Author),
+		sql.Named("authorphoto", m.AuthorPhoto),
+		sql.Named("type", defaultIfEmpty(string(m.Type), string(mentionTypeMention))),
+		sql.Named("private", m.Private),
 		sql.Named("source", m.Source),
 		sql.Named("newsource2", defaultIfEmpty(m.NewSource, m.Source)),
 		sql.Named("target", m.Target),
@@ -215,6 +259,10 @@ func (a *goBlog) reverifyWebmentionId(id int) error {
 		return err
 	}
 	if len(m) > 0 {
+		if m[0].Private {
+			// The code of a private webmention can only be used once
+			return errors.New("private webmentions can't be verified again")
+		}
 		err = a.queueMention(m[0])
 	}
 	return err
@@ -222,24 +270,41 @@ func (a *goBlog) reverifyWebmentionId(id int) error {
 
 type webmentionsRequestConfig struct {
 	target        string
+	targets       []string // one of these targets, used for the submentions
 	status        webmentionStatus
 	sourcelike    string
 	id            int
 	asc           bool
 	offset, limit int
 	submentions   bool
+	public        bool   // only public webmentions
+	excludesource string // exclude sources starting with this prefix
+	targetblog    string // only webmentions of published public posts of this blog ...
+	targetaddress string // ... with this public address
 }
 
 func buildWebmentionsQuery(config *webmentionsRequestConfig) (query string, args []any) {
 	queryBuilder := builderpool.Get()
 	defer builderpool.Put(queryBuilder)
-	queryBuilder.WriteString("select id, source, target, url, created, title, content, author, status from webmentions ")
+	queryBuilder.WriteString("select id, source, target, url, created, title, content, author, authorphoto, type, status, private from webmentions ")
 	if config != nil {
 		queryBuilder.WriteString("where 1")
 		if config.target != "" {
 			queryBuilder.WriteString(" and lowerunescaped(target) = lowerunescaped(@target)")
 			args = append(args, sql.Named("target", config.target))
 		}
+		if len(config.targets) > 0 {
+			queryBuilder.WriteString(" and lowerunescaped(target) in (")
+			for i, target := range config.targets {
+				if i > 0 {
+					queryBuilder.WriteString(", ")
+				}
+				named := "target" + strconv.Itoa(i)
+				queryBuilder.WriteString("lowerunescaped(@" + named + ")")
+				args = append(args, sql.Named(named, target))
+			}
+			queryBuilder.WriteString(")")
+		}
 		if config.status != "" {
 			queryBuilder.WriteString(" and status = @status")
 			args = append(args, sql.Named("status", config.status))
@@ -252,6 +317,17 @@ func buildWebmentionsQuery(config *webmentionsRequestConfig) (query string, args
 			queryBuilder.WriteString(" and id = @id")
 			args = append(args, sql.Named("id", config.id))
 		}
+		if config.public {
+			queryBuilder.WriteString(" and private = 0")
+		}
+		if config.excludesource != "" {
+			queryBuilder.WriteString(" and lowerunescaped(source) not like (lowerunescaped(@excludesource) || '%')")
+			args = append(args, sql.Named("excludesource", config.excludesource))
+		}
+		if config.targetblog != "" {
+			queryBuilder.WriteString(" and lowerunescaped(target) in (select lowerunescaped(@targetaddress || path) from posts where blog = @targetblog and status = @published and visibility = @public)")
+			args = append(args, sql.Named("targetaddress", config.targetaddress), sql.Named("targetblog", config.targetblog), sql.Named("published", statusPublished), sql.Named("public", visibilityPublic))
+		}
 	}
 	queryBuilder.WriteString(" order by created ")
 	if config.asc {
@@ -273,31 +349,50 @@ func (db *database) getWebmentions(config *webmentionsRequestConfig) ([]*mention
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		m := &mention{}
-		err = rows.Scan(&m.ID, &m.Source, &m.Target, &m.Url, &m.Created, &m.Title, &m.Content, &m.Author, &m.Status)
+		err = rows.Scan(&m.ID, &m.Source, &m.Target, &m.Url, &m.Created, &m.Title, &m.Content, &m.Author, &m.AuthorPhoto, &m.Type, &m.Status, &m.Private)
 		if err != nil {
 			return nil, err
 		}
 		if m.Url == "" {
 			m.Url = m.Source
 		}
-		if config.submentions {
-			m.Submentions, err = db.getWebmentions(&webmentionsRequestConfig{
-				target:      m.Source,
-				submentions: false, // prevent infinite recursion
-				asc:         config.asc,
-				status:      config.status,
-			})
-			if err != nil {
-				return nil, err
-			}
-		}
 		mentions = append(mentions, m)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+	if config.submentions {
+		if err = db.getSubmentions(mentions, config); err != nil {
+			return nil, err
+		}
+	}
 	return mentions, nil
 }
 
+// Load the submentions level by level with one query per level, the depth is limited to prevent infinite recursion
+func (db *database) getSubmentions(mentions []*mention, config *webmentionsRequestConfig) error {
+	for depth := 0; depth < webmentionMaxSubmentionsDepth && len(mentions) > 0; depth++ {
+		submentions, err := db.getWebmentions(&webmentionsRequestConfig{
+			targets: lo.Uniq(lo.Map(mentions, func(m *mention, _ int) string { return m.Source })),
+			asc:     config.asc,
+			status:  config.status,
+		})
+		if err != nil {
+			return err
+		}
+		byTarget := lo.GroupBy(submentions, func(m *mention) string { return lowerUnescapedPath(m.Target) })
+		for _, m := range mentions {
+			m.Submentions = byTarget[lowerUnescapedPath(m.Source)]
+		}
+		mentions = submentions
+	}
+	return nil
+}
+
 func (db *database) getWebmentionsByAddress(address string) []*mention {
 	if address == "" {
 		return nil
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -109,7 +110,7 @@ func (a *goBlog) webmentionAdmin(w http.ResponseWriter, r *http.Request) {
 
 func (a *goBlog) webmentionAdminAction(w http.ResponseWriter, r *http.Request) {
 	action := chi.URLParam(r, "action")
-	if action != "delete" && action != "approve" && action != "reverify" {
+	if action != "delete" && action != "approve" && action != "reverify" && action != "spam" && action != "notspam" {
 		a.serveError(w, r, "Invalid action", http.StatusBadRequest)
 		return
 	}
@@ -122,15 +123,17 @@ func (a *goBlog) webmentionAdminAction(w http.ResponseWriter, r *http.Request) {
 	case "delete":
 		err = a.db.deleteWebmentionId(id)
 	case "approve":
-		err = a.db.approveWebmentionId(id)
+		err = a.approveWebmention(id)
 	case "reverify":
 		err = a.reverifyWebmentionId(id)
+	case "spam", "notspam":
+		err = a.trainSpamFilterWithWebmention(id, action == "spam")
 	}
 	if err != nil {
 		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if action == "delete" || action == "approve" {
+	if action != "reverify" {
 		a.cache.purge()
 	}
 	redirectTo := r.FormValue("redir")
@@ -139,3 +142,22 @@ func (a *goBlog) webmentionAdminAction(w http.ResponseWriter, r *http.Request) {
 	}
 	http.Redirect(w, r, redirectTo, http.StatusFound)
 }
+
+// Train the spam filter with the webmention, delete spam and approve ham
+func (a *goBlog) trainSpamFilterWithWebmention(id int, spam bool) error {
+	m, err := a.db.getWebmentions(&webmentionsRequestConfig{
+		id:    id,
+		limit: 1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(m) == 0 {
+		return errors.New("webmention not found")
+	}
+	a.trainSpamFilter(fmt.Sprintf("webmention/%d", id), spam, m[0].Title, m[0].Content, m[0].Author)
+	if spam {
+		return a.db.deleteWebmentionId(id)
+	}
+	return a.approveWebmention(id)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/go-chi/chi/v5"
+	"go.goblog.app/app/pkgs/bufferpool"
+)
+
+const (
+	mentionAvatarPath        = "/-/avatar/"
+	mentionAvatarCachePrefix = "avatar_"
+	mentionAvatarSize        = 96
+	mentionAvatarMaxBytes    = 5 * 1024 * 1024
+	mentionAvatarMaxPixels   = 4096 * 4096
+)
+
+// Download the author photo, downscale it and save it in the persistent cache,
+// returns the local path to the avatar or an empty string if it failed
+func (a *goBlog) cacheMentionAvatar(photoURL string) string {
+	if photoURL == "" || strings.HasPrefix(photoURL, mentionAvatarPath) {
+		return photoURL
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, photoURL, nil)
+	if err != nil {
+		return ""
+	}
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return ""
+	}
+	orig := bufferpool.Get()
+	defer bufferpool.Put(orig)
+	if _, err = io.Copy(orig, io.LimitReader(res.Body, mentionAvatarMaxBytes)); err != nil {
+		return ""
+	}
+	// Check the dimensions first, small files can decode to huge images
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(orig.Bytes()))
+	if err != nil || imgCfg.Width <= 0 || imgCfg.Height <= 0 || imgCfg.Width*imgCfg.Height > mentionAvatarMaxPixels {
+		return ""
+	}
+	img, err := imaging.Decode(bytes.NewReader(orig.Bytes()), imaging.AutoOrientation(true))
+	if err != nil {
+		return ""
+	}
+	img = imaging.Fill(img, mentionAvatarSize, mentionAvatarSize, imaging.Center, imaging.Lanczos)
+	buf := bufferpool.Get()
+	defer bufferpool.Put(buf)
+	if err = imaging.Encode(buf, img, imaging.JPEG, imaging.JPEGQuality(85)); err != nil {
+		return ""
+	}
+	// Use hash of the image, so it can be cached forever
+	hash := fmt.Sprintf("%x", sha256.Sum256(buf.Bytes()))
+	if err = a.db.cachePersistently(mentionAvatarCachePrefix+hash, buf.Bytes()); err != nil {
+		return ""
+	}
+	return mentionAvatarPath + hash
+}
+
+// Delete cached avatars that aren't used by any webmention anymore
+func (db *database) deleteUnusedMentionAvatars() error {
+	_, err := db.Exec(
+		"delete from persistent_cache where key like @prefix and not exists (select 1 from webmentions where authorphoto = @path || substr(key, @offset))",
+		sql.Named("prefix", mentionAvatarCachePrefix+"%"), sql.Named("path", mentionAvatarPath), sql.Named("offset", len(mentionAvatarCachePrefix)+1),
+	)
+	return err
+}
+
+func (a *goBlog) serveMentionAvatar(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	if hash == "" {
+		a.serve404(w, r)
+		return
+	}
+	data, err := a.db.retrievePersistentCache(mentionAvatarCachePrefix + hash)
+	if err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		a.serve404(w, r)
+		return
+	}
+	w.Header().Set(contentType, "image/jpeg")
+	w.Header().Set(cacheControl, "public,max-age=31536000,immutable")
+	_, _ = w.Write(data)
+}
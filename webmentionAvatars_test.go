@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mentionAvatars(t *testing.T) {
+	fc := newFakeHttpClient()
+
+	app := &goBlog{
+		httpClient: fc.Client,
+		cfg:        createDefaultTestConfig(t),
+	}
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+	err = app.initCache()
+	require.NoError(t, err)
+	_ = app.initTemplateStrings()
+	app.initMarkdown()
+	app.initSessions()
+
+	imgBuf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(imgBuf, image.NewRGBA(image.Rect(0, 0, 400, 300))))
+	fc.setFakeResponse(http.StatusOK, imgBuf.String())
+
+	path := app.cacheMentionAvatar("https://example.org/photo.png")
+	require.NotEmpty(t, path)
+	assert.Equal(t, "https://example.org/photo.png", fc.req.URL.String())
+	assert.True(t, len(path) > len(mentionAvatarPath))
+
+	// Already cached paths are kept
+	assert.Equal(t, path, app.cacheMentionAvatar(path))
+
+	// Serve the downscaled avatar
+	mux := chi.NewMux()
+	mux.Get("/-/avatar/{hash}", app.serveMentionAvatar)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get(contentType))
+	cfg, _, err := image.DecodeConfig(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, mentionAvatarSize, cfg.Width)
+	assert.Equal(t, mentionAvatarSize, cfg.Height)
+
+	// Invalid images aren't used
+	fc.setFakeResponse(http.StatusOK, "no image")
+	assert.Empty(t, app.cacheMentionAvatar("https://example.org/invalid.png"))
+	fc.setFakeResponse(http.StatusNotFound, "")
+	assert.Empty(t, app.cacheMentionAvatar("https://example.org/missing.png"))
+
+	// Images with too many pixels aren't decoded
+	imgBuf.Reset()
+	require.NoError(t, png.Encode(imgBuf, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	bomb := imgBuf.Bytes()
+	// Change the dimensions in the IHDR chunk and update the checksum
+	binary.BigEndian.PutUint32(bomb[16:20], 50000)
+	binary.BigEndian.PutUint32(bomb[20:24], 50000)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+	bombCfg, _, err := image.DecodeConfig(bytes.NewReader(bomb))
+	require.NoError(t, err)
+	require.Equal(t, 50000, bombCfg.Width)
+	fc.setFakeResponse(http.StatusOK, string(bomb))
+	assert.Empty(t, app.cacheMentionAvatar("https://example.org/bomb.png"))
+
+	// Unused avatars are deleted
+	require.NoError(t, app.db.deleteUnusedMentionAvatars())
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
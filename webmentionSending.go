@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
@@ -9,14 +12,124 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/carlmjohnson/requests"
 	"github.com/samber/lo"
 	"github.com/tomnomnom/linkheader"
+	"go.goblog.app/app/pkgs/bufferpool"
 )
 
-const postParamWebmention = "webmention"
+const (
+	postParamWebmention = "webmention"
+
+	webmentionSendMaxTries = 5
+)
+
+// Webmention sent to an external target
+type sentWebmention struct {
+	ID       int
+	Path     string
+	Target   string
+	Endpoint string
+	Status   int
+	Location string
+	Error    string
+	Tries    int
+	Updated  string
+}
+
+type webmentionSendRequest struct {
+	Path, Source, Target string
+	Try                  int
+	// Private posts, sent as Private Webmention
+	Private bool
+	Realm   string
+}
+
+func (a *goBlog) initWebmentionSendQueue() {
+	a.listenOnQueue("wmsend", 30*time.Second, func(qi *queueItem, dequeue func(), reschedule func(time.Duration)) {
+		var r webmentionSendRequest
+		if err := gob.NewDecoder(bytes.NewReader(qi.content)).Decode(&r); err != nil {
+			log.Println("webmention send queue:", err.Error())
+			dequeue()
+			return
+		}
+		if retry := a.processWebmentionSendRequest(&r); retry && r.Try < webmentionSendMaxTries {
+			// Try it again later
+			buf := bufferpool.Get()
+			_ = gob.NewEncoder(buf).Encode(&r)
+			qi.content = buf.Bytes()
+			reschedule(time.Duration(r.Try) * 10 * time.Minute)
+			bufferpool.Put(buf)
+			return
+		}
+		dequeue()
+	})
+}
+
+func (a *goBlog) queueWebmentionSend(p *post, target string) error {
+	r := &webmentionSendRequest{Path: p.Path, Source: a.fullPostURL(p), Target: target}
+	if p.Visibility == visibilityPrivate {
+		r.Private = true
+		r.Realm = a.getBlogFromPost(p).Title
+	}
+	buf := bufferpool.Get()
+	defer bufferpool.Put(buf)
+	if err := gob.NewEncoder(buf).Encode(r); err != nil {
+		return err
+	}
+	return a.enqueue("wmsend", buf.Bytes(), time.Now())
+}
+
+// Discover the endpoint and send the webmention, saves the result and returns if it should be retried
+func (a *goBlog) processWebmentionSendRequest(r *webmentionSendRequest) (retry bool) {
+	r.Try++
+	sent := &sentWebmention{Path: r.Path, Target: r.Target, Tries: r.Try}
+	endpoint, discoveryStatus, discoveryErr := a.discoverEndpoint(r.Target)
+	if sent.Endpoint = endpoint; discoveryErr != nil && (discoveryStatus == 0 || discoveryStatus >= 500) {
+		// Retry timeouts and server errors of the target, targets without endpoint aren't retried
+		sent.Error = discoveryErr.Error()
+		retry = true
+		log.Println("Discovering webmention endpoint of " + r.Target + " failed")
+	} else if sent.Endpoint != "" {
+		var err error
+		params := url.Values{}
+		if r.Private {
+			// Private Webmention, the receiver can redeem the code at our token endpoint
+			code, err := a.db.createPrivateWebmentionCode(r.Path)
+			if err != nil {
+				log.Println("Failed to create private webmention code:", err.Error())
+				return false
+			}
+			params.Set("code", code)
+			if r.Realm != "" {
+				params.Set("realm", r.Realm)
+			}
+		}
+		sent.Status, sent.Location, err = a.sendWebmention(sent.Endpoint, r.Source, r.Target, params)
+		if sent.Status == webmentionStatusVouchRequired {
+			// Try again with a vouch
+			if vouch := a.findVouch(r.Target); vouch != "" {
+				params.Set("vouch", vouch)
+				sent.Status, sent.Location, err = a.sendWebmention(sent.Endpoint, r.Source, r.Target, params)
+			}
+		}
+		if err != nil {
+			sent.Error = err.Error()
+			// Retry timeouts and server errors
+			retry = sent.Status == 0 || sent.Status >= 500
+			log.Println("Sending webmention to " + r.Target + " failed")
+		} else {
+			log.Println("Sent webmention to " + r.Target)
+		}
+	}
+	if err := a.db.saveSentWebmention(sent); err != nil {
+		log.Println("Failed to save sent webmention:", err.Error())
+	}
+	return retry
+}
 
 func (a *goBlog) sendWebmentions(p *post) error {
 	if p.Status != statusPublished && p.Visibility != visibilityPublic && p.Visibility != visibilityUnlisted {
@@ -58,86 +171,133 @@ func (a *goBlog) sendWebmentions(p *post) error {
 			// Private mode, don't send external mentions
 			continue
 		}
-		// Send webmention
-		endpoint := a.discoverEndpoint(link)
-		if endpoint == "" {
-			continue
-		}
-		if err = a.sendWebmention(endpoint, a.fullPostURL(p), link); err != nil {
-			log.Println("Sending webmention to " + link + " failed")
-			continue
+		// Send webmention using the queue
+		if err := a.queueWebmentionSend(p, link); err != nil {
+			log.Println("Failed to queue webmention:", err.Error())
 		}
-		log.Println("Sent webmention to " + link)
 	}
 	return nil
 }
 
-func (a *goBlog) sendWebmention(endpoint, source, target string) error {
+// Send the webmention with optional additional parameters (like a vouch),
+// returns the HTTP status code and the location of the created mention
+func (a *goBlog) sendWebmention(endpoint, source, target string, params url.Values) (status int, location string, err error) {
 	// TODO: Pass all tests from https://webmention.rocks/
-	return requests.URL(endpoint).Client(a.httpClient).Method(http.MethodPost).
-		BodyForm(url.Values{
-			"source": []string{source},
-			"target": []string{target},
-		}).
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	form := url.Values{
+		"source": []string{source},
+		"target": []string{target},
+	}
+	for k, v := range params {
+		form[k] = v
+	}
+	err = requests.URL(endpoint).Client(a.httpClient).Method(http.MethodPost).
+		BodyForm(form).
 		AddValidator(func(r *http.Response) error {
+			status = r.StatusCode
 			if r.StatusCode < 200 || 300 <= r.StatusCode {
 				return fmt.Errorf("HTTP %d", r.StatusCode)
 			}
 			return nil
 		}).
-		Fetch(context.Background())
+		Handle(func(r *http.Response) error {
+			if loc := r.Header.Get("Location"); loc != "" {
+				if urls, err := resolveURLReferences(endpoint, loc); err == nil && len(urls) > 0 {
+					location = urls[0]
+				}
+			}
+			return nil
+		}).
+		Fetch(ctx)
+	return
 }
 
-func (a *goBlog) discoverEndpoint(urlStr string) string {
-	doRequest := func(method, urlStr string) string {
-		endpoint := ""
+func (db *database) saveSentWebmention(m *sentWebmention) error {
+	_, err := db.Exec(
+		`insert into webmentionssent (path, target, endpoint, status, location, error, tries, updated) values (@path, @target, @endpoint, @status, @location, @error, @tries, @updated)
+		on conflict (path, target) do update set endpoint = @endpoint, status = @status, location = @location, error = @error, tries = @tries, updated = @updated`,
+		sql.Named("path", m.Path), sql.Named("target", m.Target), sql.Named("endpoint", m.Endpoint), sql.Named("status", m.Status),
+		sql.Named("location", m.Location), sql.Named("error", m.Error), sql.Named("tries", m.Tries), sql.Named("updated", utcNowString()),
+	)
+	return err
+}
+
+func (db *database) getSentWebmentions(path string) ([]*sentWebmention, error) {
+	rows, err := db.Query(
+		"select id, path, target, endpoint, status, location, error, tries, updated from webmentionssent where path = @path order by target",
+		sql.Named("path", path),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sent := []*sentWebmention{}
+	for rows.Next() {
+		m := &sentWebmention{}
+		if err = rows.Scan(&m.ID, &m.Path, &m.Target, &m.Endpoint, &m.Status, &m.Location, &m.Error, &m.Tries, &m.Updated); err != nil {
+			return nil, err
+		}
+		sent = append(sent, m)
+	}
+	return sent, rows.Err()
+}
+
+func (a *goBlog) discoverEndpoint(urlStr string) (string, int, error) {
+	return a.discoverEndpointRel(urlStr, "webmention")
+}
+
+// Discover an endpoint (like "webmention" or "token_endpoint") using the HTTP link headers or the HTML links,
+// if there's no endpoint it returns the HTTP status (0 for timeouts and network errors) and the error of the last request
+func (a *goBlog) discoverEndpointRel(urlStr, rel string) (string, int, error) {
+	doRequest := func(method, urlStr string) (string, int, error) {
+		endpoint, status := "", 0
 		if err := requests.URL(urlStr).Client(a.httpClient).Method(method).
 			AddValidator(func(r *http.Response) error {
-				if r.StatusCode < 200 || 300 <= r.StatusCode {
+				status = r.StatusCode
+				// Private content may respond with 401, but still advertise endpoints
+				if (r.StatusCode < 200 || 300 <= r.StatusCode) && r.StatusCode != http.StatusUnauthorized {
 					return fmt.Errorf("HTTP %d", r.StatusCode)
 				}
 				return nil
 			}).
 			Handle(func(r *http.Response) error {
-				end, err := extractEndpoint(r)
+				end, err := extractEndpoint(r, rel)
 				if err != nil || end == "" {
-					return errors.New("no webmention endpoint found")
+					return errors.New("no " + rel + " endpoint found")
 				}
 				endpoint = end
 				return nil
 			}).
 			Fetch(context.Background()); err != nil {
-			return ""
+			return "", status, err
 		}
 		if urls, err := resolveURLReferences(urlStr, endpoint); err == nil && len(urls) > 0 && urls[0] != "" {
-			return urls[0]
+			return urls[0], status, nil
 		}
-		return ""
+		return "", status, errors.New("no " + rel + " endpoint found")
 	}
-	if headEndpoint := doRequest(http.MethodHead, urlStr); headEndpoint != "" {
-		return headEndpoint
+	if headEndpoint, _, _ := doRequest(http.MethodHead, urlStr); headEndpoint != "" {
+		return headEndpoint, http.StatusOK, nil
 	}
-	if getEndpoint := doRequest(http.MethodGet, urlStr); getEndpoint != "" {
-		return getEndpoint
-	}
-	return ""
+	return doRequest(http.MethodGet, urlStr)
 }
 
-func extractEndpoint(resp *http.Response) (string, error) {
+func extractEndpoint(resp *http.Response, rel string) (string, error) {
 	// first check http link headers
-	if endpoint := wmEndpointHTTPLink(resp.Header); endpoint != "" {
+	if endpoint := endpointHTTPLink(resp.Header, rel); endpoint != "" {
 		return endpoint, nil
 	}
 	// then look in the HTML body
-	endpoint, err := wmEndpointHTMLLink(resp.Body)
+	endpoint, err := endpointHTMLLink(resp.Body, rel)
 	if err != nil {
 		return "", err
 	}
 	return endpoint, nil
 }
 
-func wmEndpointHTTPLink(headers http.Header) string {
-	links := linkheader.ParseMultiple(headers[http.CanonicalHeaderKey("Link")]).FilterByRel("webmention")
+func endpointHTTPLink(headers http.Header, rel string) string {
+	links := linkheader.ParseMultiple(headers[http.CanonicalHeaderKey("Link")]).FilterByRel(rel)
 	for _, link := range links {
 		if u := link.URL; u != "" {
 			return u
@@ -146,11 +306,11 @@ func wmEndpointHTTPLink(headers http.Header) string {
 	return ""
 }
 
-func wmEndpointHTMLLink(r io.Reader) (string, error) {
+func endpointHTMLLink(r io.Reader, rel string) (string, error) {
 	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return "", err
 	}
-	href, _ := doc.Find("a[href][rel=webmention],link[href][rel=webmention]").Attr("href")
+	href, _ := doc.Find(fmt.Sprintf("a[href][rel=%q],link[href][rel=%q]", rel, rel)).Attr("href")
 	return href, nil
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sendWebmentionStatus(t *testing.T) {
+	fc := newFakeHttpClient()
+
+	app := &goBlog{
+		httpClient: fc.Client,
+		cfg:        createDefaultTestConfig(t),
+	}
+
+	err := app.initConfig(false)
+	require.NoError(t, err)
+
+	endpointStatus, targetStatus := http.StatusCreated, http.StatusOK
+	fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			rw.Header().Set("Link", `</webmention>; rel="webmention"`)
+		case "/unavailable":
+			if targetStatus != http.StatusOK {
+				rw.WriteHeader(targetStatus)
+				return
+			}
+			rw.Header().Set("Link", `</webmention>; rel="webmention"`)
+		case "/webmention":
+			if endpointStatus == http.StatusCreated {
+				rw.Header().Set("Location", "/webmention/status/1")
+			}
+			rw.WriteHeader(endpointStatus)
+		}
+	}))
+
+	// Accepted
+	retry := app.processWebmentionSendRequest(&webmentionSendRequest{Path: "/test", Source: "https://example.com/test", Target: "https://example.org/post"})
+	assert.False(t, retry)
+
+	// No endpoint
+	retry = app.processWebmentionSendRequest(&webmentionSendRequest{Path: "/test", Source: "https://example.com/test", Target: "https://example.org/other"})
+	assert.False(t, retry)
+
+	sent, err := app.db.getSentWebmentions("/test")
+	require.NoError(t, err)
+	require.Len(t, sent, 2)
+	assert.Equal(t, "https://example.org/other", sent[0].Target)
+	assert.Equal(t, "", sent[0].Endpoint)
+	assert.Equal(t, "https://example.org/post", sent[1].Target)
+	assert.Equal(t, "https://example.org/webmention", sent[1].Endpoint)
+	assert.Equal(t, http.StatusCreated, sent[1].Status)
+	assert.Equal(t, "https://example.org/webmention/status/1", sent[1].Location)
+	assert.Equal(t, 1, sent[1].Tries)
+
+	// Server errors are retried and update the existing entry
+	endpointStatus = http.StatusServiceUnavailable
+	r := &webmentionSendRequest{Path: "/test", Source: "https://example.com/test", Target: "https://example.org/post", Try: 1}
+	retry = app.processWebmentionSendRequest(r)
+	assert.True(t, retry)
+	assert.Equal(t, 2, r.Try)
+
+	// Client errors are not retried
+	endpointStatus = http.StatusBadRequest
+	retry = app.processWebmentionSendRequest(&webmentionSendRequest{Path: "/test", Source: "https://example.com/test", Target: "https://example.org/post"})
+	assert.False(t, retry)
+
+	sent, err = app.db.getSentWebmentions("/test")
+	require.NoError(t, err)
+	require.Len(t, sent, 2)
+	assert.Equal(t, http.StatusBadRequest, sent[1].Status)
+	assert.Contains(t, sent[1].Error, "HTTP 400")
+	assert.Equal(t, "", sent[1].Location)
+
+	// Failed endpoint discovery is retried for server errors
+	endpointStatus, targetStatus = http.StatusCreated, http.StatusServiceUnavailable
+	r = &webmentionSendRequest{Path: "/unavailable", Source: "https://example.com/unavailable", Target: "https://example.org/unavailable"}
+	retry = app.processWebmentionSendRequest(r)
+	assert.True(t, retry)
+
+	sent, err = app.db.getSentWebmentions("/unavailable")
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "", sent[0].Endpoint)
+	assert.Contains(t, sent[0].Error, "HTTP 503")
+
+	// And the retry sends the webmention once the target advertises the endpoint
+	targetStatus = http.StatusOK
+	retry = app.processWebmentionSendRequest(r)
+	assert.False(t, retry)
+
+	sent, err = app.db.getSentWebmentions("/unavailable")
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "https://example.org/webmention", sent[0].Endpoint)
+	assert.Equal(t, http.StatusCreated, sent[0].Status)
+	assert.Equal(t, "", sent[0].Error)
+	assert.Equal(t, 2, sent[0].Tries)
+}
@@ -77,8 +77,9 @@ func (a *goBlog) verifyMention(m *mention) error {
 	}
 	sourceReq.Header.Set("Accept", contenttype.HTMLUTF8)
 	var sourceResp *http.Response
-	if strings.HasPrefix(m.Source, a.cfg.Server.PublicAddress) ||
-		(a.cfg.Server.ShortPublicAddress != "" && strings.HasPrefix(m.Source, a.cfg.Server.ShortPublicAddress)) {
+	localSource := strings.HasPrefix(m.Source, a.cfg.Server.PublicAddress) ||
+		(a.cfg.Server.ShortPublicAddress != "" && strings.HasPrefix(m.Source, a.cfg.Server.ShortPublicAddress))
+	if localSource {
 		setLoggedIn(sourceReq, true)
 		sourceResp, err = doHandlerRequest(sourceReq, a.getAppRouter())
 		if err != nil {
@@ -86,6 +87,18 @@ func (a *goBlog) verifyMention(m *mention) error {
 		}
 		defer sourceResp.Body.Close()
 	} else {
+		if m.Code != "" {
+			// Private Webmention, get an access token to read the source
+			token, err := a.privateWebmentionAccessToken(m.Source, m.Code)
+			if err != nil {
+				if a.cfg.Debug {
+					a.debug(fmt.Sprintf("Failed to get access token for private webmention from %s: %s", m.Source, err.Error()))
+				}
+				return err
+			}
+			sourceReq.Header.Set("Authorization", "Bearer "+token)
+			m.Private = true
+		}
 		sourceResp, err = a.httpClient.Do(sourceReq)
 		if err != nil {
 			return err
@@ -113,7 +126,23 @@ func (a *goBlog) verifyMention(m *mention) error {
 		}
 		return a.db.deleteWebmention(m)
 	}
+	// Check external mentions for spam (they are held for approval anyway)
+	if !localSource && a.checkSpam(m.Title, m.Content, m.Author) == spamVerdictReject {
+		if a.cfg.Debug {
+			a.debug(fmt.Sprintf("Delete webmention because it was rejected as spam: %s", m.Source))
+		}
+		return a.db.deleteWebmention(m)
+	}
+	// Download the author photo, so it's served from our own domain (also refreshes it when reverified)
+	m.AuthorPhoto = a.cacheMentionAvatar(m.AuthorPhoto)
 	newStatus := webmentionStatusVerified
+	// Approve mentions from unknown domains with a valid vouch, others are held for approval
+	if !localSource && m.Vouch != "" && !a.db.isKnownDomain(urlHost(defaultIfEmpty(m.NewSource, m.Source))) && a.verifyVouch(m) {
+		if a.cfg.Debug {
+			a.debug(fmt.Sprintf("Approve webmention because of valid vouch: %s", m.Vouch))
+		}
+		newStatus = webmentionStatusApproved
+	}
 	// Update or insert webmention
 	if a.db.webmentionExists(m) {
 		if a.cfg.Debug {
@@ -136,8 +165,15 @@ func (a *goBlog) verifyMention(m *mention) error {
 			return err
 		}
 		a.sendNotification(fmt.Sprintf("New webmention from %s to %s", defaultIfEmpty(m.NewSource, m.Source), defaultIfEmpty(m.NewTarget, m.Target)))
+		if newStatus == webmentionStatusApproved {
+			a.cache.purge()
+			if a.isSalmentionReply(m) {
+				_ = a.queueSalmention(m)
+			}
+		}
 	}
-	return err
+	// Remove avatars that were replaced
+	return a.db.deleteUnusedMentionAvatars()
 }
 
 func (a *goBlog) verifyReader(m *mention, body io.Reader) error {
@@ -185,6 +221,7 @@ func (a *goBlog) verifyReader(m *mention, body io.Reader) error {
 	if err != nil {
 		return err
 	}
-	m.Title, m.Content, m.Author, m.Url = mf.Title, mf.Content, mf.Author, defaultIfEmpty(mf.Url, m.Source)
+	m.Title, m.Content, m.Author, m.AuthorPhoto, m.Url = mf.Title, mf.Content, mf.Author, mf.AuthorPhoto, defaultIfEmpty(mf.Url, m.Source)
+	m.Type = mf.mentionType(defaultIfEmpty(m.NewTarget, m.Target))
 	return nil
 }
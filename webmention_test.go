@@ -74,4 +74,27 @@ func Test_webmentions(t *testing.T) {
 	mentions = app.db.getWebmentionsByAddress("https://example.com/täst")
 	assert.Len(t, mentions, 0)
 
+	// Submentions are loaded level by level, cycles stop at the maximum depth
+	for i, m := range []*mention{
+		{Source: "https://example.net/reply1", Target: "https://example.com/post"},
+		{Source: "https://example.net/reply2", Target: "https://example.com/post"},
+		{Source: "https://example.org/reply", Target: "https://example.net/reply1"},
+		{Source: "https://example.net/reply1", Target: "https://example.org/reply"},
+	} {
+		m.Created = time.Now().Unix() + int64(i)
+		require.NoError(t, app.db.insertWebmention(m, webmentionStatusApproved))
+	}
+
+	mentions = app.db.getWebmentionsByAddress("https://example.com/post")
+	if assert.Len(t, mentions, 2) {
+		depth, sub := 0, mentions[0]
+		for len(sub.Submentions) > 0 {
+			assert.Len(t, sub.Submentions, 1)
+			sub = sub.Submentions[0]
+			depth++
+		}
+		assert.Equal(t, webmentionMaxSubmentionsDepth, depth)
+		assert.Empty(t, mentions[1].Submentions)
+	}
+
 }
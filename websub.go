@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"go.goblog.app/app/pkgs/bufferpool"
+)
+
+// Implement support for WebSub, either with the built-in hub or an external one
+// https://www.w3.org/TR/websub/
+
+const (
+	webSubPath = "/websub"
+
+	webSubDefaultLease = 7 * 24 * time.Hour
+	webSubMinLease     = time.Hour
+	webSubMaxLease     = 30 * 24 * time.Hour
+	webSubMaxSecretLen = 200
+	webSubMaxTries     = 5
+
+	// Subscription requests per callback host and time window
+	webSubMaxHostRequests    = 10
+	webSubHostRequestsWindow = time.Hour
+
+	webSubVerifyQueue = "websubverify"
+)
+
+type webSubDelivery struct {
+	Topic, Callback string
+	ContentType     string
+	Body            []byte
+	Try             int
+}
+
+type webSubVerification struct {
+	Mode, Topic, Callback, Secret string
+	Lease                         time.Duration
+}
+
+func (a *goBlog) initWebSub() {
+	if !a.webSubEnabled() {
+		return
+	}
+	// Add hooks
+	hook := func(p *post) {
+		// Check if post is published
+		if !p.isPublicPublishedSectionPost() {
+			return
+		}
+		a.webSubPublish(a.webSubTopics(p))
+	}
+	a.pPostHooks = append(a.pPostHooks, hook)
+	a.pUpdateHooks = append(a.pUpdateHooks, hook)
+	a.pUndeleteHooks = append(a.pUndeleteHooks, hook)
+	a.pDeleteHooks = append(a.pDeleteHooks, func(p *post) {
+		// Deleted posts disappear from the feeds they were part of
+		if p.Visibility != visibilityPublic || p.Section == "" {
+			return
+		}
+		a.webSubPublish(a.webSubTopics(p))
+	})
+	if a.webSubBuiltinHub() {
+		// Distribute content to subscribers
+		a.listenOnQueue("websub", 30*time.Second, func(qi *queueItem, dequeue func(), reschedule func(time.Duration)) {
+			var d webSubDelivery
+			if err := gob.NewDecoder(bytes.NewReader(qi.content)).Decode(&d); err != nil {
+				log.Println("websub queue:", err.Error())
+				dequeue()
+				return
+			}
+			if retry := a.processWebSubDelivery(&d); retry && d.Try < webSubMaxTries {
+				// Try it again later
+				buf := bufferpool.Get()
+				_ = gob.NewEncoder(buf).Encode(&d)
+				qi.content = buf.Bytes()
+				reschedule(time.Duration(d.Try) * 10 * time.Minute)
+				bufferpool.Put(buf)
+				return
+			}
+			dequeue()
+		})
+		// Verify subscription requests
+		a.listenOnQueue(webSubVerifyQueue, 10*time.Second, func(qi *queueItem, dequeue func(), _ func(time.Duration)) {
+			defer dequeue()
+			a.processWebSubVerification(qi.content)
+		})
+		// Delete expired subscriptions
+		a.hourlyHooks = append(a.hourlyHooks, a.deleteExpiredWebSubSubscriptions)
+	}
+}
+
+func (a *goBlog) webSubEnabled() bool {
+	// Check if private mode is enabled
+	if a.isPrivate() {
+		return false
+	}
+	// Check if WebSub is disabled
+	if wsc := a.cfg.WebSub; wsc == nil || !wsc.Enabled {
+		return false
+	}
+	return true
+}
+
+func (a *goBlog) webSubBuiltinHub() bool {
+	return a.webSubEnabled() && a.cfg.WebSub.Hub == ""
+}
+
+func (a *goBlog) webSubHubURL() string {
+	if a.webSubBuiltinHub() {
+		return a.getFullAddress(webSubPath)
+	}
+	return a.cfg.WebSub.Hub
+}
+
+// Set the discovery links as HTTP headers
+func (a *goBlog) setWebSubLinkHeaders(w http.ResponseWriter, self string) {
+	w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", a.webSubHubURL(), "hub"))
+	w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", self, "self"))
+}
+
+// Get the URLs of the index pages and feeds a post appears in
+func (a *goBlog) webSubTopics(p *post) []string {
+	bc := a.getBlogFromPost(p)
+	paths := []string{bc.getRelativePath("")}
+	if p.Section != "" {
+		paths = append(paths, bc.getRelativePath(p.Section))
+	}
+	for _, tax := range bc.Taxonomies {
+		for _, value := range p.Parameters[tax.Name] {
+			if value = urlize(value); value != "" {
+				paths = append(paths, bc.getRelativePath(fmt.Sprintf("/%s/%s", tax.Name, value)))
+			}
+		}
+	}
+	var topics []string
+	for _, path := range lo.Uniq(paths) {
+		topics = append(topics, a.getFullAddress(path))
+		for _, f := range []feedType{rssFeed, atomFeed, jsonFeed, minRssFeed, minAtomFeed, minJsonFeed} {
+			topics = append(topics, a.getFullAddress(path+"."+string(f)))
+		}
+	}
+	return topics
+}
+
+// Notify the hub about updated topics
+func (a *goBlog) webSubPublish(topics []string) {
+	if !a.webSubEnabled() || len(topics) == 0 {
+		return
+	}
+	if a.webSubBuiltinHub() {
+		for _, topic := range topics {
+			if err := a.distributeWebSubTopic(topic); err != nil {
+				log.Println("Failed to distribute WebSub topic:", err.Error())
+			}
+		}
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := requests.URL(a.cfg.WebSub.Hub).Client(a.httpClient).Method(http.MethodPost).
+		BodyForm(url.Values{
+			"hub.mode": []string{"publish"},
+			"hub.url":  topics,
+		}).
+		Fetch(ctx)
+	if err != nil {
+		log.Println("Sending WebSub publish request failed:", err.Error())
+		return
+	}
+	log.Println("WebSub publish request sent for", len(topics), "topics")
+}
+
+// Queue the current content of a topic for all active subscribers
+func (a *goBlog) distributeWebSubTopic(topic string) error {
+	callbacks, err := a.db.webSubCallbacks(topic)
+	if err != nil || len(callbacks) == 0 {
+		return err
+	}
+	// Get current content
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, topic, nil)
+	if err != nil {
+		return err
+	}
+	res, err := doHandlerRequest(req, a.getAppRouter())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("topic %s returned HTTP %d", topic, res.StatusCode)
+	}
+	for _, callback := range callbacks {
+		buf := bufferpool.Get()
+		err = gob.NewEncoder(buf).Encode(&webSubDelivery{
+			Topic: topic, Callback: callback, ContentType: res.Header.Get(contentType), Body: body,
+		})
+		if err == nil {
+			err = a.enqueue("websub", buf.Bytes(), time.Now())
+		}
+		bufferpool.Put(buf)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Send the content to a subscriber, returns if it should be retried
+func (a *goBlog) processWebSubDelivery(d *webSubDelivery) (retry bool) {
+	d.Try++
+	secret, err := a.db.webSubSecret(d.Topic, d.Callback)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Unsubscribed or expired in the meantime
+		return false
+	} else if err != nil {
+		log.Println("Failed to get WebSub subscription:", err.Error())
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// The host could resolve to another address by now
+	if err = checkWebSubCallback(ctx, d.Callback); err != nil {
+		log.Println("WebSub delivery to "+d.Callback+" refused:", err.Error())
+		return false
+	}
+	status := 0
+	rb := requests.URL(d.Callback).Client(a.httpClient).Method(http.MethodPost).
+		BodyBytes(d.Body).
+		ContentType(d.ContentType).
+		Header("Link", fmt.Sprintf("<%s>; rel=%q, <%s>; rel=%q", a.webSubHubURL(), "hub", d.Topic, "self")).
+		AddValidator(func(r *http.Response) error {
+			status = r.StatusCode
+			if r.StatusCode < 200 || 300 <= r.StatusCode {
+				return fmt.Errorf("HTTP %d", r.StatusCode)
+			}
+			return nil
+		})
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write(d.Body)
+		rb.Header("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	if err = rb.Fetch(ctx); err != nil {
+		if status == http.StatusGone {
+			// The subscriber doesn't want any more content
+			_ = a.db.deleteWebSubSubscription(d.Topic, d.Callback)
+			return false
+		}
+		log.Println("WebSub delivery to "+d.Callback+" failed:", err.Error())
+		return true
+	}
+	return false
+}
+
+// The built-in hub endpoint for subscription requests
+func (a *goBlog) serveWebSubHub(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mode := r.Form.Get("hub.mode")
+	if mode != "subscribe" && mode != "unsubscribe" {
+		a.serveError(w, r, "unsupported hub.mode", http.StatusBadRequest)
+		return
+	}
+	topic, callback := r.Form.Get("hub.topic"), r.Form.Get("hub.callback")
+	if !strings.HasPrefix(topic, a.cfg.Server.PublicAddress) {
+		a.serveError(w, r, "hub.topic is not served by this hub", http.StatusBadRequest)
+		return
+	}
+	cu, err := url.Parse(callback)
+	if err != nil || (cu.Scheme != "http" && cu.Scheme != "https") || cu.Host == "" {
+		a.serveError(w, r, "invalid hub.callback", http.StatusBadRequest)
+		return
+	}
+	if err = checkWebSubCallback(r.Context(), callback); err != nil {
+		a.serveError(w, r, "invalid hub.callback: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	secret := r.Form.Get("hub.secret")
+	if len(secret) > webSubMaxSecretLen {
+		a.serveError(w, r, "hub.secret is too long", http.StatusBadRequest)
+		return
+	}
+	lease := webSubDefaultLease
+	if ls, err := strconv.Atoi(r.Form.Get("hub.lease_seconds")); err == nil {
+		lease = min(max(time.Duration(ls)*time.Second, webSubMinLease), webSubMaxLease)
+	}
+	if !a.allowWebSubHostRequest(strings.ToLower(cu.Hostname())) {
+		a.serveError(w, r, "too many requests for this hub.callback host", http.StatusTooManyRequests)
+		return
+	}
+	// Verify the intent of the subscriber asynchronously
+	buf := bufferpool.Get()
+	defer bufferpool.Put(buf)
+	if err = gob.NewEncoder(buf).Encode(&webSubVerification{
+		Mode: mode, Topic: topic, Callback: callback, Secret: secret, Lease: lease,
+	}); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = a.enqueue(webSubVerifyQueue, buf.Bytes(), time.Now()); err != nil {
+		a.serveError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Limit the subscription requests per callback host, so the hub can't be used to flood a host with verification requests
+func (a *goBlog) allowWebSubHostRequest(host string) bool {
+	a.webSubMutex.Lock()
+	defer a.webSubMutex.Unlock()
+	if a.webSubHostRequests == nil {
+		a.webSubHostRequests = map[string][]time.Time{}
+	}
+	windowStart := time.Now().Add(-webSubHostRequestsWindow)
+	for h, times := range a.webSubHostRequests {
+		if recent := lo.Filter(times, func(t time.Time, _ int) bool { return t.After(windowStart) }); len(recent) > 0 {
+			a.webSubHostRequests[h] = recent
+		} else {
+			delete(a.webSubHostRequests, h)
+		}
+	}
+	if len(a.webSubHostRequests[host]) >= webSubMaxHostRequests {
+		return false
+	}
+	a.webSubHostRequests[host] = append(a.webSubHostRequests[host], time.Now())
+	return true
+}
+
+// Refuse callbacks on loopback, private and other non-public addresses
+func checkWebSubCallback(ctx context.Context, callback string) error {
+	cu, err := url.Parse(callback)
+	if err != nil {
+		return err
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(cu.Hostname()); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, cu.Hostname())
+		if err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return errors.New("host has no address")
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+			return errors.New("host has no public address")
+		}
+	}
+	return nil
+}
+
+func (a *goBlog) processWebSubVerification(content []byte) {
+	var v webSubVerification
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&v); err != nil {
+		log.Println("websub verification queue:", err.Error())
+		return
+	}
+	if err := a.verifyWebSubIntent(v.Mode, v.Topic, v.Callback, v.Secret, v.Lease); err != nil {
+		log.Println("WebSub verification failed:", err.Error())
+	}
+}
+
+// Check if the subscriber really requested the (un)subscription and save it
+func (a *goBlog) verifyWebSubIntent(mode, topic, callback, secret string, lease time.Duration) error {
+	challenge := uuid.NewString()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := checkWebSubCallback(ctx, callback); err != nil {
+		return err
+	}
+	rb := requests.URL(callback).Client(a.httpClient).
+		Param("hub.mode", mode).
+		Param("hub.topic", topic).
+		Param("hub.challenge", challenge)
+	if mode == "subscribe" {
+		rb.Param("hub.lease_seconds", strconv.Itoa(int(lease.Seconds())))
+	}
+	var response string
+	if err := rb.ToString(&response).Fetch(ctx); err != nil {
+		return err
+	}
+	if strings.TrimSpace(response) != challenge {
+		return errors.New("subscriber didn't echo the challenge")
+	}
+	if mode == "unsubscribe" {
+		return a.db.deleteWebSubSubscription(topic, callback)
+	}
+	return a.db.saveWebSubSubscription(topic, callback, secret, time.Now().Add(lease))
+}
+
+func (db *database) saveWebSubSubscription(topic, callback, secret string, expires time.Time) error {
+	_, err := db.Exec(
+		`insert into websubsubscriptions (topic, callback, secret, expires) values (@topic, @callback, @secret, @expires)
+		on conflict (topic, callback) do update set secret = @secret, expires = @expires`,
+		sql.Named("topic", topic), sql.Named("callback", callback), sql.Named("secret", secret), sql.Named("expires", expires.Unix()),
+	)
+	return err
+}
+
+func (db *database) deleteWebSubSubscription(topic, callback string) error {
+	_, err := db.Exec(
+		"delete from websubsubscriptions where topic = @topic and callback = @callback",
+		sql.Named("topic", topic), sql.Named("callback", callback),
+	)
+	return err
+}
+
+func (db *database) webSubCallbacks(topic string) ([]string, error) {
+	rows, err := db.Query(
+		"select callback from websubsubscriptions where topic = @topic and expires > @now",
+		sql.Named("topic", topic), sql.Named("now", time.Now().Unix()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var callbacks []string
+	for rows.Next() {
+		var callback string
+		if err = rows.Scan(&callback); err != nil {
+			return nil, err
+		}
+		callbacks = append(callbacks, callback)
+	}
+	return callbacks, rows.Err()
+}
+
+func (db *database) webSubSecret(topic, callback string) (secret string, err error) {
+	row, err := db.QueryRow(
+		"select secret from websubsubscriptions where topic = @topic and callback = @callback and expires > @now",
+		sql.Named("topic", topic), sql.Named("callback", callback), sql.Named("now", time.Now().Unix()),
+	)
+	if err != nil {
+		return "", err
+	}
+	err = row.Scan(&secret)
+	return
+}
+
+func (a *goBlog) deleteExpiredWebSubSubscriptions() {
+	if _, err := a.db.Exec("delete from websubsubscriptions where expires <= ?", time.Now().Unix()); err != nil {
+		log.Println("Failed to delete expired WebSub subscriptions:", err.Error())
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_webSub(t *testing.T) {
+	fc := newFakeHttpClient()
+
+	app := &goBlog{
+		cfg:        createDefaultTestConfig(t),
+		httpClient: fc.Client,
+	}
+	app.cfg.WebSub = &configWebSub{Enabled: true}
+
+	require.NoError(t, app.initConfig(false))
+	require.NoError(t, app.initTemplateStrings())
+	require.NoError(t, app.initCache())
+	app.initMarkdown()
+	app.initSessions()
+
+	app.d = app.buildRouter()
+
+	hub := "http://localhost:8080/websub"
+
+	t.Run("Discovery", func(t *testing.T) {
+		for _, f := range []string{"rss", "atom", "json"} {
+			rec := httptest.NewRecorder()
+			app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/."+f, nil))
+			res := rec.Result()
+			body, _ := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Contains(t, res.Header.Values("Link"), `<`+hub+`>; rel="hub"`)
+			assert.Contains(t, res.Header.Values("Link"), `<http://localhost:8080/.`+f+`>; rel="self"`)
+			switch f {
+			case "rss":
+				assert.Contains(t, string(body), `xmlns:atom="http://www.w3.org/2005/Atom"`)
+				assert.Contains(t, string(body), `<atom:link href="`+hub+`" rel="hub"`)
+			case "atom":
+				assert.Contains(t, string(body), `<link href="`+hub+`" rel="hub"`)
+				assert.Contains(t, string(body), `<link href="http://localhost:8080/.atom" rel="self"`)
+			case "json":
+				assert.Contains(t, string(body), `"feed_url":"http://localhost:8080/.json"`)
+				assert.Contains(t, string(body), `"hubs":[{"type":"WebSub","url":"`+hub+`"}]`)
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		body := rec.Body.String()
+		assert.Contains(t, body, `<link rel=hub href=`+hub+`>`)
+		assert.Contains(t, body, `<link rel=self href=http://localhost:8080>`)
+	})
+
+	t.Run("Topics", func(t *testing.T) {
+		topics := app.webSubTopics(&post{
+			Blog:       "default",
+			Section:    "posts",
+			Parameters: map[string][]string{"tags": {"Foo Bar"}},
+		})
+		assert.Len(t, topics, 21)
+		assert.Contains(t, topics, "http://localhost:8080")
+		assert.Contains(t, topics, "http://localhost:8080/.rss")
+		assert.Contains(t, topics, "http://localhost:8080/posts.min.atom")
+		assert.Contains(t, topics, "http://localhost:8080/tags/foo-bar.json")
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		var verification url.Values
+		fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			verification = r.URL.Query()
+			_, _ = io.WriteString(rw, r.URL.Query().Get("hub.challenge"))
+		}))
+
+		// Invalid topic
+		data := url.Values{
+			"hub.mode":     {"subscribe"},
+			"hub.topic":    {"https://example.org/.rss"},
+			"hub.callback": {"https://203.0.113.10/callback"},
+		}
+		req := httptest.NewRequest(http.MethodPost, webSubPath, strings.NewReader(data.Encode()))
+		req.Header.Set(contentType, "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		app.d.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		// Valid subscription
+		data.Set("hub.topic", "http://localhost:8080/.rss")
+		data.Set("hub.secret", "mysecret")
+		data.Set("hub.lease_seconds", "60")
+		req = httptest.NewRequest(http.MethodPost, webSubPath, strings.NewReader(data.Encode()))
+		req.Header.Set(contentType, "application/x-www-form-urlencoded")
+		rec = httptest.NewRecorder()
+		app.d.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		// Verification is queued
+		qi, err := app.peekQueue(context.Background(), webSubVerifyQueue)
+		require.NoError(t, err)
+		require.NotNil(t, qi)
+		require.NoError(t, app.dequeue(qi))
+		app.processWebSubVerification(qi.content)
+
+		callbacks, err := app.db.webSubCallbacks("http://localhost:8080/.rss")
+		require.NoError(t, err)
+		assert.Len(t, callbacks, 1)
+
+		fc.mu.Lock()
+		assert.Equal(t, "subscribe", verification.Get("hub.mode"))
+		assert.Equal(t, "http://localhost:8080/.rss", verification.Get("hub.topic"))
+		// Lease is raised to the minimum
+		assert.Equal(t, "3600", verification.Get("hub.lease_seconds"))
+		fc.mu.Unlock()
+
+		// Subscriber doesn't confirm
+		fc.setFakeResponse(http.StatusOK, "wrong")
+		err = app.verifyWebSubIntent("subscribe", "http://localhost:8080/.atom", "https://203.0.113.10/callback", "", time.Hour)
+		assert.Error(t, err)
+		callbacks, _ = app.db.webSubCallbacks("http://localhost:8080/.atom")
+		assert.Empty(t, callbacks)
+	})
+
+	t.Run("Callback checks", func(t *testing.T) {
+		subscribe := func(callback string) int {
+			data := url.Values{
+				"hub.mode":     {"subscribe"},
+				"hub.topic":    {"http://localhost:8080/.atom"},
+				"hub.callback": {callback},
+			}
+			req := httptest.NewRequest(http.MethodPost, webSubPath, strings.NewReader(data.Encode()))
+			req.Header.Set(contentType, "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			app.d.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		// Loopback and private addresses are refused
+		for _, callback := range []string{"http://127.0.0.1/callback", "http://[::1]:8080/callback", "http://192.168.1.1/callback", "http://10.0.0.1/callback", "http://169.254.169.254/callback", "http://localhost/callback"} {
+			assert.Equal(t, http.StatusBadRequest, subscribe(callback), callback)
+		}
+		assert.Error(t, app.verifyWebSubIntent("subscribe", "http://localhost:8080/.atom", "http://127.0.0.1/callback", "", time.Hour))
+
+		// Requests are limited per callback host
+		for i := 1; i < webSubMaxHostRequests; i++ {
+			assert.Equal(t, http.StatusAccepted, subscribe(fmt.Sprintf("https://203.0.113.10/callback%d", i)))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, subscribe("https://203.0.113.10/other"))
+		assert.Equal(t, http.StatusAccepted, subscribe("https://203.0.113.11/callback"))
+
+		_, err := app.db.Exec("delete from queue where name = ?", webSubVerifyQueue)
+		require.NoError(t, err)
+	})
+
+	t.Run("Distribute", func(t *testing.T) {
+		var received *http.Request
+		var receivedBody []byte
+		fc.setHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+		}))
+
+		d := &webSubDelivery{
+			Topic:       "http://localhost:8080/.rss",
+			Callback:    "https://203.0.113.10/callback",
+			ContentType: "application/rss+xml",
+			Body:        []byte("<rss></rss>"),
+		}
+		assert.False(t, app.processWebSubDelivery(d))
+
+		fc.mu.Lock()
+		require.NotNil(t, received)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "<rss></rss>", string(receivedBody))
+		assert.Equal(t, "application/rss+xml", received.Header.Get(contentType))
+		assert.Equal(t, `<`+hub+`>; rel="hub", <http://localhost:8080/.rss>; rel="self"`, received.Header.Get("Link"))
+		mac := hmac.New(sha256.New, []byte("mysecret"))
+		_, _ = mac.Write([]byte("<rss></rss>"))
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), received.Header.Get("X-Hub-Signature"))
+		fc.mu.Unlock()
+
+		// Server errors are retried
+		fc.setFakeResponse(http.StatusInternalServerError, "")
+		assert.True(t, app.processWebSubDelivery(d))
+
+		// Gone removes the subscription
+		fc.setFakeResponse(http.StatusGone, "")
+		assert.False(t, app.processWebSubDelivery(d))
+		callbacks, _ := app.db.webSubCallbacks("http://localhost:8080/.rss")
+		assert.Empty(t, callbacks)
+	})
+
+	t.Run("External hub", func(t *testing.T) {
+		app.cfg.WebSub.Hub = "https://hub.example/"
+		defer func() { app.cfg.WebSub.Hub = "" }()
+
+		fc.setFakeResponse(http.StatusNoContent, "")
+		app.webSubPublish([]string{"http://localhost:8080/.rss", "http://localhost:8080/.atom"})
+
+		fc.mu.Lock()
+		require.NotNil(t, fc.req)
+		assert.Equal(t, "https://hub.example/", fc.req.URL.String())
+		_ = fc.req.ParseForm()
+		assert.Equal(t, "publish", fc.req.PostForm.Get("hub.mode"))
+		assert.Equal(t, []string{"http://localhost:8080/.rss", "http://localhost:8080/.atom"}, fc.req.PostForm["hub.url"])
+		fc.mu.Unlock()
+	})
+}